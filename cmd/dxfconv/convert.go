@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+// job converts one input file to one output file
+type job struct {
+	input, output string
+}
+
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dxfconv", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "output `file`, \"-\" for standard output, or the output directory of several inputs")
+	workers := fs.Int("j", runtime.NumCPU(), "number of parallel conversions")
+	verbose := fs.Bool("v", false, "print each converted file")
+	options := optionFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage:\n  dxfconv [flags] [input ...]\n  dxfconv info [-no-vars] [input ...]\n  dxfconv serve [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	opts, err := options()
+	if err != nil {
+		errorf(stderr, "%v", err)
+		return exitUsage
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 || len(inputs) == 1 && inputs[0] == "-" {
+		return convertStream(stdin, stdout, stderr, *output, opts)
+	}
+
+	files, err := expandInputs(inputs)
+	if err != nil {
+		errorf(stderr, "%v", err)
+		return exitFailure
+	}
+	if len(files) == 0 {
+		errorf(stderr, "no DXF files found")
+		return exitFailure
+	}
+
+	// A single input may be written to a named file or standard output
+	single := len(inputs) == 1 && len(files) == 1 && !isDir(inputs[0])
+	if single && *output != "" && !isDir(*output) && !strings.HasSuffix(*output, string(os.PathSeparator)) {
+		if opts.Format == "" {
+			opts.Format = formatOf(*output)
+		}
+		if *output == "-" {
+			return convertFile(files[0], stdout, stderr, opts)
+		}
+		return runJobs([]job{{files[0], *output}}, 1, opts, stderr, *verbose)
+	}
+
+	if opts.Format == "" {
+		opts.Format = converter.FormatPDF
+	}
+	jobs := make([]job, len(files))
+	for i, f := range files {
+		dir := *output
+		if dir == "" {
+			dir = filepath.Dir(f)
+		}
+		name := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f)) + opts.Format.Extension()
+		jobs[i] = job{f, filepath.Join(dir, name)}
+	}
+	if err := checkOutputs(jobs); err != nil {
+		errorf(stderr, "%v", err)
+		return exitFailure
+	}
+	if *output != "" {
+		if err := os.MkdirAll(*output, 0o755); err != nil {
+			errorf(stderr, "%v", err)
+			return exitFailure
+		}
+	}
+	return runJobs(jobs, *workers, opts, stderr, *verbose)
+}
+
+// checkOutputs fails if two jobs would write the same file, such as a/x.dxf
+// and b/x.dxf converted into one directory. Names are compared ignoring case
+// to catch collisions on case-insensitive file systems too.
+func checkOutputs(jobs []job) error {
+	byOutput := make(map[string]string, len(jobs))
+	for _, j := range jobs {
+		key := strings.ToLower(filepath.Clean(j.output))
+		if other, ok := byOutput[key]; ok {
+			return fmt.Errorf("%s and %s would both be written to %s", other, j.input, j.output)
+		}
+		byOutput[key] = j.input
+	}
+	return nil
+}
+
+// formatOf returns the format of an output file name, PDF if the extension is unknown
+func formatOf(name string) converter.Format {
+	if f, ok := formatFromExtension(name); ok {
+		return f
+	}
+	return converter.FormatPDF
+}
+
+// convertStream converts standard input to the output file, or to standard output
+func convertStream(stdin io.Reader, stdout, stderr io.Writer, output string, opts *converter.Options) int {
+	if opts.Format == "" {
+		opts.Format = formatOf(output)
+	}
+	var warnings []dxfconverror.Diagnostic
+	write := func(w io.Writer) (err error) {
+		warnings, err = convert(stdin, w, opts)
+		return err
+	}
+	var err error
+	if output == "" || output == "-" {
+		err = write(stdout)
+	} else {
+		err = writeFile(output, write)
+	}
+	if err != nil {
+		errorf(stderr, "%v", err)
+		printExcerpt(stderr, err)
+		return exitFailure
+	}
+	warn(stderr, "<stdin>", warnings)
+	return exitOK
+}
+
+func convertFile(input string, w io.Writer, stderr io.Writer, opts *converter.Options) int {
+	f, err := os.Open(input)
+	if err != nil {
+		errorf(stderr, "%v", err)
+		return exitFailure
+	}
+	defer f.Close()
+	warnings, err := convert(f, w, withFilename(opts, input))
+	if err != nil {
+		errorf(stderr, "%s: %v", input, err)
+		printExcerpt(stderr, err)
+		return exitFailure
+	}
+	warn(stderr, input, warnings)
+	return exitOK
+}
+
+// runJobs converts the files with the given number of workers. Failures are
+// reported and do not stop the other conversions.
+func runJobs(jobs []job, workers int, opts *converter.Options, stderr io.Writer, verbose bool) int {
+	ch := make(chan job)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex // guards stderr and failed
+		failed int
+	)
+	for range max(1, min(workers, len(jobs))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range ch {
+				warnings, err := convertJob(j, opts)
+				mu.Lock()
+				if err != nil {
+					errorf(stderr, "%s: %v", j.input, err)
+					printExcerpt(stderr, err)
+					failed++
+				} else {
+					warn(stderr, j.input, warnings)
+					if verbose {
+						fmt.Fprintf(stderr, "%s -> %s\n", j.input, j.output)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, j := range jobs {
+		ch <- j
+	}
+	close(ch)
+	wg.Wait()
+
+	if failed > 0 {
+		if len(jobs) > 1 {
+			errorf(stderr, "%d of %d conversions failed", failed, len(jobs))
+		}
+		return exitFailure
+	}
+	return exitOK
+}
+
+func convertJob(j job, opts *converter.Options) (warnings []dxfconverror.Diagnostic, err error) {
+	f, err := os.Open(j.input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	err = writeFile(j.output, func(w io.Writer) (err error) {
+		warnings, err = convert(f, w, withFilename(opts, j.input))
+		return err
+	})
+	return warnings, err
+}
+
+// withFilename returns a copy of opts for the named input file
+func withFilename(opts *converter.Options, name string) *converter.Options {
+	o := *opts
+	o.Filename = name
+	return &o
+}
+
+// convert converts r to w and returns the warnings of lenient parsing
+func convert(r io.Reader, w io.Writer, opts *converter.Options) ([]dxfconverror.Diagnostic, error) {
+	res, err := converter.ConvertContext(context.Background(), r, w, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Warnings, nil
+}
+
+// warn reports the warnings of the named input
+func warn(stderr io.Writer, name string, warnings []dxfconverror.Diagnostic) {
+	for _, d := range warnings {
+		errorf(stderr, "%s: %s", name, d)
+	}
+}
+
+// writeFile creates the named file with the output of write, removing it
+// again if write fails
+func writeFile(name string, write func(io.Writer) error) error {
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(out)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+	}
+	return err
+}
+
+// expandInputs resolves directories and glob patterns to the DXF files they
+// contain, without duplicates
+func expandInputs(inputs []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	for _, in := range inputs {
+		switch {
+		case isDir(in):
+			entries, err := os.ReadDir(in)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".dxf") {
+					add(filepath.Join(in, e.Name()))
+				}
+			}
+		case strings.ContainsAny(in, "*?["):
+			matches, err := filepath.Glob(in)
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %w", in, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", in)
+			}
+			sort.Strings(matches)
+			for _, m := range matches {
+				if !isDir(m) {
+					add(m)
+				}
+			}
+		default:
+			add(in)
+		}
+	}
+	return files, nil
+}
+
+func isDir(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
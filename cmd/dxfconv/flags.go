@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+)
+
+// Formats written by the CLI, in the order tried by formatFromExtension
+var formats = []converter.Format{
+	converter.FormatPDF,
+	converter.FormatSVG,
+	converter.FormatPNG,
+	converter.FormatEPS,
+	converter.FormatHPGL,
+}
+
+// formatFromExtension returns the format written to files with the extension of name
+func formatFromExtension(name string) (converter.Format, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".hpgl" {
+		return converter.FormatHPGL, true
+	}
+	for _, f := range formats {
+		if ext == f.Extension() {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// parseWindow parses "minx,miny,maxx,maxy"
+func parseWindow(s string) (*converter.Window, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid window %q: want minx,miny,maxx,maxy", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", s, err)
+		}
+		v[i] = f
+	}
+	return &converter.Window{MinX: v[0], MinY: v[1], MaxX: v[2], MaxY: v[3]}, nil
+}
+
+// parsePenTable parses "aci=pen,aci=pen,..."
+func parsePenTable(s string) (map[int]int, error) {
+	table := make(map[int]int)
+	for _, entry := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(entry, "=")
+		aci, err1 := strconv.Atoi(strings.TrimSpace(k))
+		pen, err2 := strconv.Atoi(strings.TrimSpace(v))
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid pen table entry %q: want ACI=PEN", entry)
+		}
+		table[aci] = pen
+	}
+	return table, nil
+}
+
+// parseLayerStyle parses "PATTERN:color=N,weight=MM,linetype=NAME"
+func parseLayerStyle(s string) (converter.LayerStyle, error) {
+	pattern, props, ok := strings.Cut(s, ":")
+	if !ok || pattern == "" {
+		return converter.LayerStyle{}, fmt.Errorf("invalid layer style %q: want LAYER:color=N,weight=MM,linetype=NAME", s)
+	}
+	ls := converter.LayerStyle{Layer: pattern}
+	for _, prop := range strings.Split(props, ",") {
+		k, v, _ := strings.Cut(prop, "=")
+		var err error
+		switch strings.TrimSpace(k) {
+		case "color":
+			ls.Color, err = strconv.Atoi(v)
+		case "weight":
+			ls.LineWeight, err = strconv.ParseFloat(v, 64)
+		case "linetype":
+			ls.LineType = v
+		default:
+			err = fmt.Errorf("unknown property %q", k)
+		}
+		if err != nil {
+			return converter.LayerStyle{}, fmt.Errorf("invalid layer style %q: %w", s, err)
+		}
+	}
+	return ls, nil
+}
+
+// listFlag collects comma-separated values of a repeatable flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// optionFlags registers the flags of converter.Options on fs. The returned
+// function builds the options once fs has been parsed.
+func optionFlags(fs *flag.FlagSet) func() (*converter.Options, error) {
+	def := converter.DefaultOptions()
+	var (
+		format      = fs.String("format", "", "output `format`: pdf, svg, png, eps or hpgl (default: from the output file extension, else pdf)")
+		page        = fs.String("page", "A4", "page `size`: A0-A5, letter, legal, tabloid or WIDTHxHEIGHT in mm")
+		orientation = fs.String("orientation", "portrait", "page orientation: portrait or landscape")
+		scale       = fs.Float64("scale", def.Scale, "manual `scale` from drawing units to mm (0 fits the page)")
+		plotScale   = fs.String("plot-scale", "", "true plot `scale`, e.g. 1:50 or 1mm=1m")
+		units       = fs.String("units", "", "drawing `units` for -plot-scale, e.g. mm, in, m (default: $INSUNITS)")
+		overflow    = fs.Bool("allow-overflow", false, "cut off what does not fit the page at -plot-scale instead of failing")
+		margin      = fs.Float64("margin", def.Margin, "page margin in `mm`")
+		extents     = fs.Bool("header-extents", false, "fit $EXTMIN/$EXTMAX from the header instead of the entity bounds")
+		window      = fs.String("window", "", "plot only the region `minx,miny,maxx,maxy` of model space")
+		view        = fs.String("view", "", "plot the region of the named `view`")
+		limits      = fs.Bool("limits", false, "plot the $LIMMIN/$LIMMAX region")
+		showHidden  = fs.Bool("show-hidden", false, "plot frozen, off and non-plottable layers")
+		ocg         = fs.Bool("optional-content", false, "map layers to toggleable PDF layers")
+		compress    = fs.Bool("compress", false, "compress PDF output (PDF 1.5)")
+		pdfa        = fs.Bool("pdfa", false, "write PDF/A-2b archival output (fails on any drawing with text)")
+		title       = fs.String("title", "", "PDF document `title` (default: from the header, else the file name)")
+		author      = fs.String("author", "", "PDF document `author`")
+		subject     = fs.String("subject", "", "PDF document `subject`")
+		keywords    = fs.String("keywords", "", "PDF document `keywords`")
+		fitWindow   = fs.Bool("fit-window", false, "open PDF output zoomed to fit the page in a window sized to it")
+		svgClasses  = fs.Bool("svg-classes", false, "style SVG output with CSS classes")
+		svgPrec     = fs.Int("svg-precision", def.SVGPrecision, "`decimals` of SVG coordinates")
+		svgMinify   = fs.Bool("svg-minify", false, "write SVG without indentation")
+		dpi         = fs.Float64("dpi", 0, "PNG resolution in `dots per inch` (default 96)")
+		background  = fs.String("background", "", "PNG background `colour`: #rrggbb, #rrggbbaa, white, black or transparent (default white)")
+		noAA        = fs.Bool("no-antialias", false, "draw PNG output without anti-aliasing")
+		minWidth    = fs.Float64("min-line-width", 0, "minimum PNG line width in `pixels` (default 1)")
+		penTable    = fs.String("pen-table", "", "HPGL pens by colour as `ACI=PEN,...`")
+		lenient     = fs.Bool("lenient", false, "skip malformed entities with a warning instead of failing")
+		workers     = fs.Int("workers", 0, "parse and draw each large drawing with `n` goroutines")
+		maxBytes    = fs.Int64("max-input-bytes", 0, "fail on DXF input larger than `n` bytes (0: no limit)")
+		maxEntities = fs.Int("max-entities", 0, "fail on drawings with more than `n` entities (0: no limit)")
+		maxDepth    = fs.Int("max-block-depth", 0, "fail on block references nested deeper than `n` (0: no limit)")
+		maxVertices = fs.Int("max-vertices", 0, "fail on drawings with more than `n` vertices (0: no limit)")
+		maxPixels   = fs.Int64("max-pixels", 0, "fail on PNG output larger than `n` pixels (0: 268435456)")
+		layers      listFlag
+		exclude     listFlag
+		styles      []converter.LayerStyle
+	)
+	fs.Var(&layers, "layers", "comma-separated layer `patterns` to plot (repeatable)")
+	fs.Var(&exclude, "exclude-layers", "comma-separated layer `patterns` not to plot (repeatable)")
+	fs.Func("layer-style", "override a layer's pen as `LAYER:color=N,weight=MM,linetype=NAME` (repeatable)", func(s string) error {
+		ls, err := parseLayerStyle(s)
+		if err != nil {
+			return err
+		}
+		styles = append(styles, ls)
+		return nil
+	})
+
+	return func() (*converter.Options, error) {
+		// An empty Format is resolved from the output file name by the caller
+		opts := converter.DefaultOptions()
+		opts.Format = ""
+		var err error
+		if *format != "" {
+			if opts.Format, err = converter.ParseFormat(*format); err != nil {
+				return nil, err
+			}
+		}
+		if opts.PageSize, err = converter.ParsePageSize(*page); err != nil {
+			return nil, err
+		}
+		if opts.Orientation, err = converter.ParseOrientation(*orientation); err != nil {
+			return nil, err
+		}
+		opts.Scale = *scale
+		opts.PlotScale = *plotScale
+		if *units != "" {
+			u, ok := dxf.ParseUnits(*units)
+			if !ok {
+				return nil, fmt.Errorf("unknown units %q", *units)
+			}
+			opts.Units = u
+		}
+		opts.AllowOverflow = *overflow
+		opts.Margin = *margin
+		opts.UseHeaderExtents = *extents
+		if *window != "" {
+			if opts.Window, err = parseWindow(*window); err != nil {
+				return nil, err
+			}
+		}
+		opts.View = *view
+		opts.PlotLimits = *limits
+		opts.Layers = layers
+		opts.ExcludeLayers = exclude
+		opts.ShowHiddenLayers = *showHidden
+		opts.OptionalContent = *ocg
+		opts.Compress = *compress
+		opts.PDFA = *pdfa
+		opts.Info.Title = *title
+		opts.Info.Author = *author
+		opts.Info.Subject = *subject
+		opts.Info.Keywords = *keywords
+		opts.ViewerPreferences.FitPage = *fitWindow
+		opts.ViewerPreferences.FitWindow = *fitWindow
+		opts.SVGClasses = *svgClasses
+		opts.SVGPrecision = *svgPrec
+		opts.SVGMinify = *svgMinify
+		opts.DPI = *dpi
+		if *background != "" {
+			if opts.Background, err = converter.ParseColor(*background); err != nil {
+				return nil, err
+			}
+		}
+		opts.NoAntialias = *noAA
+		opts.MinLineWidth = *minWidth
+		if *penTable != "" {
+			if opts.PenTable, err = parsePenTable(*penTable); err != nil {
+				return nil, err
+			}
+		}
+		opts.LayerStyles = styles
+		opts.Lenient = *lenient
+		opts.Workers = *workers
+		opts.MaxInputBytes = *maxBytes
+		opts.MaxEntities = *maxEntities
+		opts.MaxBlockDepth = *maxDepth
+		opts.MaxVertices = *maxVertices
+		opts.MaxPixels = *maxPixels
+		return opts, nil
+	}
+}
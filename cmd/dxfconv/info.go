@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/daidai-ok/dxfconv/pkg/boundingbox"
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+)
+
+func runInfo(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dxfconv info", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	noVars := fs.Bool("no-vars", false, "do not list the header variables")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage:\n  dxfconv info [flags] [input ...]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 || len(inputs) == 1 && inputs[0] == "-" {
+		d, stats, err := readInfo(stdin)
+		if err != nil {
+			errorf(stderr, "%v", err)
+			printExcerpt(stderr, err)
+			return exitFailure
+		}
+		printInfo(stdout, "-", d, stats, !*noVars)
+		return exitOK
+	}
+
+	files, err := expandInputs(inputs)
+	if err != nil {
+		errorf(stderr, "%v", err)
+		return exitFailure
+	}
+	code := exitOK
+	for i, name := range files {
+		d, stats, err := readFile(name)
+		if err != nil {
+			errorf(stderr, "%s: %v", name, err)
+			printExcerpt(stderr, err)
+			code = exitFailure
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		printInfo(stdout, name, d, stats, !*noVars)
+	}
+	return code
+}
+
+// entityStats are the entity counts and extents of a drawing. They are
+// gathered while decoding, so that large drawings are not held in memory.
+type entityStats struct {
+	total   int
+	extents *boundingbox.BoundingBox
+	// perLayer counts the entities by lower-case layer name; names holds the
+	// spelling of the first entity on each layer
+	perLayer map[string]int
+	names    map[string]string
+	perType  map[dxf.EntityType]int
+}
+
+func (st *entityStats) add(e dxf.Entity) {
+	st.total++
+	if bb := converter.Extents([]dxf.Entity{e}); !bb.IsEmpty() {
+		st.extents.Update(bb.MinX, bb.MinY)
+		st.extents.Update(bb.MaxX, bb.MaxY)
+	}
+	key := strings.ToLower(e.Layer())
+	if _, ok := st.names[key]; !ok {
+		st.names[key] = e.Layer()
+	}
+	st.perLayer[key]++
+	st.perType[e.Type()]++
+}
+
+// readInfo decodes a drawing, returning it without entities and their statistics
+func readInfo(r io.Reader) (*dxf.Drawing, *entityStats, error) {
+	stats := &entityStats{
+		extents:  boundingbox.NewBoundingBox(),
+		perLayer: make(map[string]int),
+		names:    make(map[string]string),
+		perType:  make(map[dxf.EntityType]int),
+	}
+	dec := dxf.NewDecoder(r)
+	for e, err := range dec.Entities() {
+		if err != nil {
+			return nil, nil, err
+		}
+		stats.add(e)
+	}
+	return dec.Drawing(), stats, nil
+}
+
+func readFile(name string) (*dxf.Drawing, *entityStats, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return readInfo(f)
+}
+
+// printInfo prints the version, units, extents, layers, entity counts and
+// header variables of the drawing
+func printInfo(w io.Writer, name string, d *dxf.Drawing, stats *entityStats, vars bool) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "File:\t%s\n", name)
+	if v := d.Header.Version(); v != "" {
+		fmt.Fprintf(tw, "Version:\t%s\n", v)
+	}
+	fmt.Fprintf(tw, "Units:\t%s\n", d.Header.Units())
+	if bb := stats.extents; !bb.IsEmpty() {
+		fmt.Fprintf(tw, "Extents:\t%s,%s - %s,%s (%s x %s)\n", num(bb.MinX), num(bb.MinY), num(bb.MaxX), num(bb.MaxY),
+			num(bb.Width()), num(bb.Height()))
+	}
+	if min, max, ok := d.Header.Extents(); ok {
+		fmt.Fprintf(tw, "Header extents:\t%s,%s - %s,%s\n", num(min[0]), num(min[1]), num(max[0]), num(max[1]))
+	}
+
+	fmt.Fprintf(tw, "\nLayers:\n")
+	fmt.Fprintf(tw, "  NAME\tCOLOR\tLINETYPE\tSTATE\tENTITIES\n")
+	listed := make(map[string]bool)
+	for _, l := range d.Layers {
+		key := strings.ToLower(l.Name)
+		listed[key] = true
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\t%d\n", l.Name, abs(l.Color), l.LineType, layerState(&l), stats.perLayer[key])
+	}
+	// Layers of entities that are missing from the layer table
+	var missing []string
+	for key, name := range stats.names {
+		if !listed[key] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		fmt.Fprintf(tw, "  %s\t-\t-\tnot in layer table\t%d\n", name, stats.perLayer[strings.ToLower(name)])
+	}
+
+	fmt.Fprintf(tw, "\nEntities:\t%d\n", stats.total)
+	types := make([]string, 0, len(stats.perType))
+	for t := range stats.perType {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(tw, "  %s\t%d\n", t, stats.perType[dxf.EntityType(t)])
+	}
+
+	if !vars || len(d.Header.Variables) == 0 {
+		return
+	}
+	fmt.Fprintf(tw, "\nHeader variables:\n")
+	names := make([]string, 0, len(d.Header.Variables))
+	for name := range d.Header.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := make([]string, len(d.Header.Variables[name]))
+		for i, t := range d.Header.Variables[name] {
+			values[i] = t.Value
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, strings.Join(values, ", "))
+	}
+}
+
+func layerState(l *dxf.Layer) string {
+	var s []string
+	if l.Off() {
+		s = append(s, "off")
+	}
+	if l.Frozen() {
+		s = append(s, "frozen")
+	}
+	if !l.Plot {
+		s = append(s, "no plot")
+	}
+	if len(s) == 0 {
+		return "on"
+	}
+	return strings.Join(s, ", ")
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
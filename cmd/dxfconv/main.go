@@ -0,0 +1,63 @@
+// Command dxfconv converts DXF drawings to PDF, SVG, PNG, EPS and HPGL and
+// prints information about them.
+//
+// Usage:
+//
+//	dxfconv [flags] [input ...]
+//	dxfconv info [-no-vars] [input ...]
+//	dxfconv serve [-addr :8080] [-max-upload bytes] [-timeout 60s] [flags]
+//
+// Inputs are files, directories (every .dxf file in them) or glob patterns.
+// Without inputs, or with "-", the drawing is read from standard input. A
+// single input is written to the file given by -o, or to standard output for
+// "-o -". Several inputs are converted in parallel next to the inputs, or into
+// the directory given by -o. Run "dxfconv -h" for the list of flags.
+//
+// The serve command runs the HTTP conversion service of package server; the
+// conversion flags set the defaults of its requests.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "info":
+			return runInfo(args[1:], stdin, stdout, stderr)
+		case "serve":
+			return runServe(args[1:], stderr)
+		case "convert":
+			args = args[1:]
+		}
+	}
+	return runConvert(args, stdin, stdout, stderr)
+}
+
+func errorf(stderr io.Writer, format string, args ...any) {
+	fmt.Fprintf(stderr, "dxfconv: "+format+"\n", args...)
+}
+
+// printExcerpt shows the input around the position of a parse error
+func printExcerpt(stderr io.Writer, err error) {
+	if pe, ok := dxfconverror.AsParseError(err); ok && pe.Excerpt != "" {
+		fmt.Fprintln(stderr, pe.Excerpt)
+	}
+}
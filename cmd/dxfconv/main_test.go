@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+)
+
+const fixture = "../../fixtures/line_simple.dxf"
+
+func runArgs(t *testing.T, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun_StdinToStdout(t *testing.T) {
+	in, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, out, stderr := runArgs(t, string(in), "-format", "svg")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(out, "<?xml") || !strings.Contains(out, "<svg") {
+		t.Errorf("expected SVG on stdout, got %.100q", out)
+	}
+}
+
+func TestRun_SingleFile(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "drawing.eps")
+	// The format follows from the output extension
+	code, _, stderr := runArgs(t, "", "-page", "A3", "-orientation", "landscape", "-o", out, fixture)
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("%!PS-Adobe-3.0 EPSF-3.0")) {
+		t.Errorf("expected EPS, got %.40q", data)
+	}
+}
+
+func TestRun_Batch(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{"a.dxf", "b.DXF", "notes.txt"} {
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(src, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "out")
+
+	code, _, stderr := runArgs(t, "", "-j", "2", "-format", "png", "-o", out, src)
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	for _, name := range []string{"a.png", "b.png"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("missing output: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "notes.png")); err == nil {
+		t.Errorf("non-DXF file converted")
+	}
+
+	// Without -o the outputs are written next to the inputs
+	code, _, stderr = runArgs(t, "", filepath.Join(src, "*.dxf"))
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a.pdf")); err != nil {
+		t.Errorf("missing output: %v", err)
+	}
+}
+
+func TestRun_BatchFailure(t *testing.T) {
+	out := t.TempDir()
+	code, _, stderr := runArgs(t, "", "-o", out, fixture, "../../fixtures/broken.dxf", "missing.dxf")
+	if code != exitFailure {
+		t.Errorf("exit code %d, want %d", code, exitFailure)
+	}
+	if !strings.Contains(stderr, "2 of 3 conversions failed") {
+		t.Errorf("unexpected stderr: %s", stderr)
+	}
+	// The good file is still converted; nothing is left of the failed ones
+	if _, err := os.Stat(filepath.Join(out, "line_simple.pdf")); err != nil {
+		t.Errorf("missing output: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "broken.pdf")); err == nil {
+		t.Errorf("output of failed conversion left behind")
+	}
+}
+
+func TestRun_BatchDuplicateOutputs(t *testing.T) {
+	src := t.TempDir()
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/plan.dxf", "b/plan.dxf"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(src, name)), 0o755)
+		if err := os.WriteFile(filepath.Join(src, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	code, _, stderr := runArgs(t, "", "-o", out, filepath.Join(src, "a"), filepath.Join(src, "b"))
+	if code != exitFailure {
+		t.Errorf("exit code %d, want %d", code, exitFailure)
+	}
+	if !strings.Contains(stderr, "would both be written to "+filepath.Join(out, "plan.pdf")) {
+		t.Errorf("unexpected stderr: %s", stderr)
+	}
+	// Nothing is converted
+	if _, err := os.Stat(out); err == nil {
+		t.Errorf("output directory created")
+	}
+}
+
+func TestRun_Lenient(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "broken.svg")
+	code, _, stderr := runArgs(t, "", "-lenient", "-o", out, "../../fixtures/broken.dxf")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "broken.dxf: line 29: error: LINE") {
+		t.Errorf("unexpected stderr: %s", stderr)
+	}
+}
+
+func TestRun_PDFA(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "plan.pdf")
+	code, _, stderr := runArgs(t, "", "-pdfa", "-author", "Jane Doe", "-fit-window", "-o", out, fixture)
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The title defaults to the input file name
+	for _, want := range []string{"/Title (line_simple) /Author (Jane Doe)", "<pdfaid:part>2</pdfaid:part>", "/FitWindow true"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+
+	// Text cannot be written without embedded fonts
+	code, _, stderr = runArgs(t, "", "-pdfa", "-o", out, "../../fixtures/text.dxf")
+	if code != exitFailure || !strings.Contains(stderr, "embedded fonts") {
+		t.Errorf("exit code %d: %s", code, stderr)
+	}
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-page", "B7"},
+		{"-orientation", "sideways"},
+		{"-format", "dwg"},
+		{"-background", "#12345"},
+		{"-layer-style", "DIM:colour=1"},
+		{"-no-such-flag"},
+	} {
+		if code, _, _ := runArgs(t, "", args...); code != exitUsage {
+			t.Errorf("%v: exit code %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+func TestOptionFlags(t *testing.T) {
+	code, _, stderr := runArgs(t, "", "-h")
+	if code != exitOK || !strings.Contains(stderr, "-plot-scale") {
+		t.Errorf("help: exit code %d: %s", code, stderr)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	build := optionFlags(fs)
+	err := fs.Parse([]string{
+		"-page", "300x200", "-orientation", "L", "-margin", "5",
+		"-layers", "A,B", "-layers", "C", "-exclude-layers", "DIM*",
+		"-layer-style", "DIM*:color=8,weight=0.18,linetype=DASHED",
+		"-background", "#ff000080", "-pen-table", "7=1,1=2",
+		"-units", "in", "-window", "0,0,100,50",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.PageSize != (converter.PageSize{Width: 300, Height: 200}) || opts.Orientation != converter.OrientationLandscape || opts.Margin != 5 {
+		t.Errorf("page options = %+v %s %v", opts.PageSize, opts.Orientation, opts.Margin)
+	}
+	if strings.Join(opts.Layers, "|") != "A|B|C" || strings.Join(opts.ExcludeLayers, "|") != "DIM*" {
+		t.Errorf("layers = %v, exclude = %v", opts.Layers, opts.ExcludeLayers)
+	}
+	want := converter.LayerStyle{Layer: "DIM*", Color: 8, LineWeight: 0.18, LineType: "DASHED"}
+	if len(opts.LayerStyles) != 1 || opts.LayerStyles[0] != want {
+		t.Errorf("layer styles = %+v", opts.LayerStyles)
+	}
+	if c := color.RGBAModel.Convert(opts.Background).(color.RGBA); c != (color.RGBA{R: 0x80, A: 0x80}) {
+		t.Errorf("background = %+v", c)
+	}
+	if opts.PenTable[1] != 2 || opts.Units.String() != "in" || opts.Window.MaxX != 100 {
+		t.Errorf("options = %+v", opts)
+	}
+}
+
+func TestRun_Info(t *testing.T) {
+	code, out, stderr := runArgs(t, "", "info", "../../fixtures/layers.dxf")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	for _, want := range []string{"Extents:", "0,0 - 100,50", "FROZEN", "frozen", "no plot", "LINE", "$LTSCALE"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("info output missing %q:\n%s", want, out)
+		}
+	}
+
+	_, out, _ = runArgs(t, "", "info", "-no-vars", "../../fixtures/layers.dxf")
+	if strings.Contains(out, "$LTSCALE") {
+		t.Errorf("header variables listed with -no-vars:\n%s", out)
+	}
+}
+
+func TestRun_ServeUsage(t *testing.T) {
+	if code, _, _ := runArgs(t, "", "serve", "extra"); code != exitUsage {
+		t.Errorf("exit code %d, want %d", code, exitUsage)
+	}
+	if code, _, stderr := runArgs(t, "", "serve", "-h"); code != exitOK || !strings.Contains(stderr, "-max-upload") {
+		t.Errorf("help: exit code %d: %s", code, stderr)
+	}
+}
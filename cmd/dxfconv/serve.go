@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/server"
+)
+
+func runServe(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dxfconv serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", ":8080", "listen `address`")
+	maxUpload := fs.Int64("max-upload", server.DefaultMaxUploadSize, "maximum upload size in `bytes`")
+	timeout := fs.Duration("timeout", server.DefaultTimeout, "maximum duration of a conversion")
+	options := optionFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage:\n  dxfconv serve [flags]\n\nServes POST /convert. The conversion flags set the defaults of requests.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		errorf(stderr, "serve takes no arguments")
+		return exitUsage
+	}
+	opts, err := options()
+	if err != nil {
+		errorf(stderr, "%v", err)
+		return exitUsage
+	}
+	if opts.Format == "" {
+		opts.Format = converter.FormatPDF
+	}
+
+	srv := &http.Server{
+		Addr: *addr,
+		Handler: server.NewHandler(server.Config{
+			MaxUploadSize: *maxUpload,
+			Timeout:       *timeout,
+			Defaults:      opts,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Let running conversions finish
+		shutdown, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(stderr, "dxfconv: listening on %s\n", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		errorf(stderr, "%v", err)
+		return exitFailure
+	}
+	return exitOK
+}
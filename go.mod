@@ -2,4 +2,6 @@ module github.com/daidai-ok/dxfconv
 
 go 1.25.5
 
-require github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b
+require golang.org/x/image v0.25.0
+
+require golang.org/x/text v0.23.0 // indirect
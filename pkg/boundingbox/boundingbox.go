@@ -44,3 +44,8 @@ func (bb *BoundingBox) Width() float64 {
 func (bb *BoundingBox) Height() float64 {
 	return bb.MaxY - bb.MinY
 }
+
+// IsEmpty reports whether no point has been added to the bounding box
+func (bb *BoundingBox) IsEmpty() bool {
+	return bb.MinX > bb.MaxX || bb.MinY > bb.MaxY
+}
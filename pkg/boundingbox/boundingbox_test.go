@@ -108,3 +108,15 @@ func TestBoundingBox_Dimensions(t *testing.T) {
 		t.Errorf("Height() = %v, want %v", h, 50)
 	}
 }
+
+func TestBoundingBox_IsEmpty(t *testing.T) {
+	bb := NewBoundingBox()
+	if !bb.IsEmpty() {
+		t.Error("IsEmpty() = false for a new bounding box, want true")
+	}
+
+	bb.Update(5, 5)
+	if bb.IsEmpty() {
+		t.Error("IsEmpty() = true after Update, want false")
+	}
+}
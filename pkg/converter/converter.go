@@ -1,53 +1,176 @@
 package converter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 
 	"github.com/daidai-ok/dxfconv/pkg/boundingbox"
 	"github.com/daidai-ok/dxfconv/pkg/dxf"
 	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+	"github.com/daidai-ok/dxfconv/pkg/pdf"
 	"github.com/daidai-ok/dxfconv/pkg/renderers"
 )
 
 // Convert reads DXF data from r and writes PDF data to w
 func Convert(r io.Reader, w io.Writer, opts *Options) error {
+	_, err := ConvertContext(context.Background(), r, w, opts)
+	return err
+}
+
+// Result describes a completed conversion
+type Result struct {
+	// Warnings lists the malformed input skipped with Options.Lenient
+	Warnings []dxfconverror.Diagnostic
+}
+
+// How many entities are drawn between checks of the context
+const cancelCheckInterval = 256
+
+// ConvertContext is like Convert but stops with the context's error when ctx
+// is done, while parsing or drawing. Output already written to w is then
+// incomplete. Drawings exceeding the limits of opts fail with a
+// *dxfconverror.LimitError. The Result lists the warnings of lenient parsing.
+func ConvertContext(ctx context.Context, r io.Reader, w io.Writer, opts *Options) (*Result, error) {
 	if opts == nil {
 		opts = DefaultOptions()
 	}
+	if err := checkLimits(opts); err != nil {
+		return nil, err
+	}
+	if opts.Format == FormatPNG {
+		if err := checkPixels(opts); err != nil {
+			return nil, err
+		}
+	}
+	var plotRatio float64
+	if opts.PlotScale != "" {
+		ratio, err := ParsePlotScale(opts.PlotScale)
+		if err != nil {
+			return nil, &dxfconverror.OptionError{Option: "PlotScale", Err: err}
+		}
+		plotRatio = ratio
+	}
 
-	dxfDrawing, err := dxf.Parse(r)
+	d, err := parse(ctx, r, opts)
 	if err != nil {
-		return &dxfconverror.ParseError{Err: fmt.Errorf("failed to parse DXF: %w", err)}
+		return nil, err
+	}
+	if err := render(ctx, d, w, opts, plotRatio); err != nil {
+		return nil, err
+	}
+	return &Result{Warnings: d.Diagnostics}, nil
+}
+
+// Parse reads a DXF drawing with the parse options and input limits of opts:
+// Lenient, Workers, MaxInputBytes, MaxEntities and MaxBlockDepth. It fails
+// like ConvertContext; the warnings of lenient parsing are in the drawing's
+// Diagnostics. A nil opts selects DefaultOptions.
+func Parse(ctx context.Context, r io.Reader, opts *Options) (*dxf.Drawing, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := checkLimits(opts); err != nil {
+		return nil, err
+	}
+	return parse(ctx, r, opts)
+}
+
+// parse reads the drawing with the parse options and limits of opts
+func parse(ctx context.Context, r io.Reader, opts *Options) (*dxf.Drawing, error) {
+	if opts.MaxInputBytes > 0 {
+		r = newLimitReader(r, opts.MaxInputBytes)
+	}
+	d, err := dxf.ParseContext(ctx, r, dxf.ParseOptions{
+		MaxEntities:   opts.MaxEntities,
+		MaxBlockDepth: opts.MaxBlockDepth,
+		Lenient:       opts.Lenient,
+		Workers:       opts.Workers,
+	})
+	if err != nil {
+		var limitErr *dxfconverror.LimitError
+		if errors.As(err, &limitErr) {
+			return nil, limitErr
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if parseErr, ok := dxfconverror.AsParseError(err); ok {
+			return nil, parseErr
+		}
+		return nil, &dxfconverror.ParseError{Err: fmt.Errorf("failed to parse DXF: %w", err)}
+	}
+	return d, nil
+}
+
+// render draws the parsed drawing to w. plotRatio is the parsed
+// Options.PlotScale, or zero.
+func render(ctx context.Context, dxfDrawing *dxf.Drawing, w io.Writer, opts *Options, plotRatio float64) error {
+	// Filter Layers
+	layers, err := newLayerSet(dxfDrawing, opts)
+	if err != nil {
+		return err
+	}
+	entities := make([]dxf.Entity, 0, len(dxfDrawing.Entities))
+	for _, e := range dxfDrawing.Entities {
+		if layers.Visible(e.Layer()) {
+			entities = append(entities, e)
+		}
 	}
 
 	// Calculate Bounding Box
-	bb := calculateBoundingBox(dxfDrawing)
+	bb := extents(entities, opts.Workers)
+	if bb.IsEmpty() || opts.UseHeaderExtents {
+		// Fall back to the extents stored by the CAD application
+		if min, max, ok := dxfDrawing.Header.Extents(); ok {
+			bb = boundingbox.NewBoundingBox()
+			bb.Update(min[0], min[1])
+			bb.Update(max[0], max[1])
+		}
+	}
 
-	// Setup Renderer
-	var renderer renderers.Renderer
+	window, err := plotWindow(dxfDrawing, opts)
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		bb = boundingbox.NewBoundingBox()
+		bb.Update(window.MinX, window.MinY)
+		bb.Update(window.MaxX, window.MaxY)
+	}
+
+	// Page Size
 	pageW, pageH := opts.PageSize.Width, opts.PageSize.Height
 	if opts.Orientation == OrientationLandscape {
 		pageW, pageH = pageH, pageW
 	}
 
-	switch opts.Format {
-	case FormatSVG:
-		renderer = renderers.NewSVGRenderer(w, pageW, pageH)
-	case FormatPDF:
-		fallthrough
-	default:
-		renderer = renderers.NewPDFRenderer(w, string(opts.Orientation), pageW, pageH)
-	}
-
-	renderer.Init(pageW, pageH)
-
 	// Calculate Scale
 	availW := pageW - (2 * opts.Margin)
 	availH := pageH - (2 * opts.Margin)
 
 	scale := opts.Scale
-	if scale == 0 {
+	if plotRatio > 0 {
+		// True scale: drawing units -> millimeters on paper
+		units := opts.Units
+		if units == dxf.UnitsUnitless {
+			units = dxfDrawing.Header.Units()
+		}
+		scale = units.Millimeters() * plotRatio
+
+		const tolerance = 1e-6
+		if !opts.AllowOverflow && (bb.Width()*scale > availW+tolerance || bb.Height()*scale > availH+tolerance) {
+			return &dxfconverror.FitError{
+				Width:           bb.Width() * scale,
+				Height:          bb.Height() * scale,
+				AvailableWidth:  availW,
+				AvailableHeight: availH,
+			}
+		}
+	} else if scale == 0 {
 		var scaleX, scaleY float64
 		if bb.Width() > 0 {
 			scaleX = availW / bb.Width()
@@ -70,52 +193,229 @@ func Convert(r io.Reader, w io.Writer, opts *Options) error {
 	// Centering Logic
 	realOffsetX := -bb.MinX*scale + opts.Margin + (availW-bb.Width()*scale)/2
 	realOffsetY := -bb.MinY*scale + opts.Margin + (availH-bb.Height()*scale)/2
+	dc := &renderers.DrawContext{
+		Scale:      scale,
+		OffsetX:    realOffsetX,
+		OffsetY:    realOffsetY,
+		Height:     pageH,
+		PointStyle: pointStyle(&dxfDrawing.Header, scale, availH),
+	}
+
+	// Vertices are counted at the page scale, which sets how finely curves
+	// are flattened
+	if err := checkVertices(ctx, dc, entities, opts.MaxVertices); err != nil {
+		return err
+	}
+
+	// Setup Renderer
+	var renderer renderers.Renderer
+	switch opts.Format {
+	case FormatSVG:
+		svgRenderer := renderers.NewSVGRenderer(w, pageW, pageH)
+		svgRenderer.UseClasses = opts.SVGClasses
+		svgRenderer.Precision = opts.SVGPrecision
+		svgRenderer.Minify = opts.SVGMinify
+		renderer = svgRenderer
+	case FormatPNG:
+		pngRenderer := renderers.NewPNGRenderer(w, pageW, pageH)
+		if opts.DPI > 0 {
+			pngRenderer.DPI = opts.DPI
+		}
+		if opts.Background != nil {
+			pngRenderer.Background = opts.Background
+		}
+		pngRenderer.Antialias = !opts.NoAntialias
+		if opts.MinLineWidth > 0 {
+			pngRenderer.MinLineWidth = opts.MinLineWidth
+		}
+		pngRenderer.MaxPixels = opts.MaxPixels
+		renderer = pngRenderer
+	case FormatEPS:
+		renderer = renderers.NewEPSRenderer(w, pageW, pageH)
+	case FormatHPGL:
+		hpglRenderer := renderers.NewHPGLRenderer(w, pageW, pageH)
+		hpglRenderer.PenTable = opts.PenTable
+		renderer = hpglRenderer
+	case FormatPDF:
+		fallthrough
+	default:
+		pdfRenderer := renderers.NewPDFRenderer(w, string(opts.Orientation), pageW, pageH)
+		pdfRenderer.OptionalContent = opts.OptionalContent
+		pdfRenderer.Compress = opts.Compress
+		pdfRenderer.LineJoin = opts.LineJoin
+		pdfRenderer.LineCap = opts.LineCap
+		pdfRenderer.Info = documentInfo(&dxfDrawing.Header, opts)
+		pdfRenderer.PDFA = opts.PDFA
+		pdfRenderer.ViewerPreferences = opts.ViewerPreferences
+		renderer = pdfRenderer
+	}
+
+	renderer.Init(pageW, pageH)
+
+	if window != nil {
+		renderer.Clip(
+			window.MinX*scale+realOffsetX,
+			pageH-(window.MaxY*scale+realOffsetY),
+			bb.Width()*scale,
+			bb.Height()*scale,
+		)
+	}
 
 	// Draw Entities
-	for _, e := range dxfDrawing.Entities {
-		renderers.DrawEntity(renderer, e, scale, realOffsetX, realOffsetY, pageH)
+	drawn, err := drawChunks(ctx, renderer, dc, entities, layers, opts.Workers)
+	if err != nil {
+		return err
+	}
+	if !drawn {
+		entityRenderer, grouped := renderer.(renderers.EntityRenderer)
+		for i, e := range entities {
+			if i%cancelCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if grouped {
+				entityRenderer.BeginEntity(layers.Info(e))
+			}
+			renderer.SetStyle(layers.Style(e, scale))
+			dc.Draw(renderer, e)
+			if grouped {
+				entityRenderer.EndEntity()
+			}
+		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if err := renderer.Finish(); err != nil {
 		return &dxfconverror.RenderingError{Err: err}
 	}
 	return nil
 }
 
-func calculateBoundingBox(dxfDrawing *dxf.Drawing) *boundingbox.BoundingBox {
-	bb := boundingbox.NewBoundingBox()
-	for _, e := range dxfDrawing.Entities {
-		switch e := e.(type) {
-		case *dxf.Line:
-			bb.Update(e.Start[0], e.Start[1])
-			bb.Update(e.End[0], e.End[1])
-		case *dxf.Circle:
-			bb.Update(e.Center[0]-e.Radius, e.Center[1]-e.Radius)
-			bb.Update(e.Center[0]+e.Radius, e.Center[1]+e.Radius)
-		case *dxf.Arc:
-			// Arc bounding box is tricky, approximate with full circle for now or centers/endpoints
-			// Better to just update center +/- radius
-			bb.Update(e.Center[0]-e.Radius, e.Center[1]-e.Radius)
-			bb.Update(e.Center[0]+e.Radius, e.Center[1]+e.Radius)
-		case *dxf.LwPolyline:
-			for _, v := range e.Vertices {
-				bb.Update(v.X, v.Y)
-			}
-		case *dxf.Polyline:
-			for _, v := range e.Vertices {
-				bb.Update(v.X, v.Y)
-			}
-		case *dxf.Spline:
-			for _, v := range e.ControlPoints {
-				bb.Update(v[0], v[1])
-			}
-		case *dxf.Point:
-			bb.Update(e.Coord[0], e.Coord[1])
-		case *dxf.Text:
-			bb.Update(e.Point[0], e.Point[1])
-		case *dxf.MText:
-			bb.Update(e.Point[0], e.Point[1])
+// plotWindow resolves the region of model space selected by the options.
+// It returns nil when the whole drawing is plotted.
+func plotWindow(d *dxf.Drawing, opts *Options) (*Window, error) {
+	var w Window
+	switch {
+	case opts.Window != nil:
+		w = *opts.Window
+	case opts.View != "":
+		v, ok := d.View(opts.View)
+		if !ok {
+			return nil, &dxfconverror.OptionError{Option: "View", Err: fmt.Errorf("view %q not found", opts.View)}
+		}
+		w.MinX, w.MinY, w.MaxX, w.MaxY = v.Bounds()
+	case opts.PlotLimits:
+		min, max, ok := d.Header.Limits()
+		if !ok {
+			return nil, &dxfconverror.OptionError{Option: "PlotLimits", Err: fmt.Errorf("drawing has no valid $LIMMIN/$LIMMAX")}
 		}
+		w = Window{MinX: min[0], MinY: min[1], MaxX: max[0], MaxY: max[1]}
+	default:
+		return nil, nil
+	}
+	if w.MaxX <= w.MinX || w.MaxY <= w.MinY {
+		return nil, &dxfconverror.OptionError{Option: "Window", Err: fmt.Errorf("empty window %+v", w)}
+	}
+	return &w, nil
+}
+
+// documentInfo returns opts.Info completed from the header and file name.
+// It stays empty, writing no metadata, unless Info is set or PDFA is on.
+func documentInfo(h *dxf.Header, opts *Options) pdf.Info {
+	info := opts.Info
+	if info == (pdf.Info{}) && !opts.PDFA {
+		return info
+	}
+	for _, name := range []string{"$TITLE", "$PROJECTNAME"} {
+		if info.Title != "" {
+			break
+		}
+		info.Title, _ = h.String(name)
+	}
+	if info.Title == "" && opts.Filename != "" {
+		info.Title = strings.TrimSuffix(filepath.Base(opts.Filename), filepath.Ext(opts.Filename))
+	}
+	if info.CreationDate.IsZero() {
+		info.CreationDate, _ = h.Created()
+	}
+	if info.ModDate.IsZero() {
+		info.ModDate, _ = h.Updated()
+	}
+	if info.Producer == "" {
+		info.Producer = "dxfconv"
+	}
+	return info
+}
+
+// pointStyle converts $PDMODE/$PDSIZE into a page-space point style.
+// It returns nil when the drawing does not define $PDMODE.
+func pointStyle(h *dxf.Header, scale, availH float64) *renderers.PointStyle {
+	if !h.Has("$PDMODE") {
+		return nil
+	}
+	size := h.PDSize()
+	switch {
+	case size > 0:
+		// Absolute size in drawing units
+		size *= scale
+	case size < 0:
+		// Percentage of the viewport
+		size = -size / 100 * availH
+	default:
+		// 5% of the drawing area height
+		size = 0.05 * availH
+	}
+	return &renderers.PointStyle{Mode: h.PDMode(), Size: size}
+}
+
+// Extents returns the bounding box of the entities as used to fit the page.
+// The box is empty if no entity has a position.
+func Extents(entities []dxf.Entity) *boundingbox.BoundingBox {
+	return calculateBoundingBox(entities)
+}
+
+func calculateBoundingBox(entities []dxf.Entity) *boundingbox.BoundingBox {
+	bb := boundingbox.NewBoundingBox()
+	for _, e := range entities {
+		addToBoundingBox(bb, e)
 	}
 	return bb
 }
+
+// addToBoundingBox expands bb to include the entity
+func addToBoundingBox(bb *boundingbox.BoundingBox, e dxf.Entity) {
+	switch e := e.(type) {
+	case *dxf.Line:
+		bb.Update(e.Start[0], e.Start[1])
+		bb.Update(e.End[0], e.End[1])
+	case *dxf.Circle:
+		bb.Update(e.Center[0]-e.Radius, e.Center[1]-e.Radius)
+		bb.Update(e.Center[0]+e.Radius, e.Center[1]+e.Radius)
+	case *dxf.Arc:
+		// Arc bounding box is tricky, approximate with full circle for now or centers/endpoints
+		// Better to just update center +/- radius
+		bb.Update(e.Center[0]-e.Radius, e.Center[1]-e.Radius)
+		bb.Update(e.Center[0]+e.Radius, e.Center[1]+e.Radius)
+	case *dxf.LwPolyline:
+		for _, v := range e.Vertices {
+			bb.Update(v.X, v.Y)
+		}
+	case *dxf.Polyline:
+		for _, v := range e.Vertices {
+			bb.Update(v.X, v.Y)
+		}
+	case *dxf.Spline:
+		for _, v := range e.ControlPoints {
+			bb.Update(v[0], v[1])
+		}
+	case *dxf.Point:
+		bb.Update(e.Coord[0], e.Coord[1])
+	case *dxf.Text:
+		bb.Update(e.Point[0], e.Point[1])
+	case *dxf.MText:
+		bb.Update(e.Point[0], e.Point[1])
+	}
+}
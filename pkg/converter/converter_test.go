@@ -2,14 +2,19 @@ package converter
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"image/color"
+	"image/png"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/daidai-ok/dxfconv/pkg/dxf"
 	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+	"github.com/daidai-ok/dxfconv/pkg/pdf"
 )
 
 func TestConvert_PDF(t *testing.T) {
@@ -35,6 +40,10 @@ func TestConvert_PDF(t *testing.T) {
 	if !strings.HasPrefix(w.String(), "%PDF") {
 		t.Error("Expected PDF output to start with %PDF")
 	}
+	// Metadata is only written when asked for
+	if strings.Contains(w.String(), "/Info") || strings.Contains(w.String(), "/Metadata") {
+		t.Error("Expected PDF output without metadata")
+	}
 }
 
 func TestConvert_SVG(t *testing.T) {
@@ -62,6 +71,91 @@ func TestConvert_SVG(t *testing.T) {
 	}
 }
 
+func TestConvert_PNG(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/overall.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read encoded DXF: %v", err)
+	}
+	r := bytes.NewReader(dxfData)
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.Format = FormatPNG
+	opts.DPI = 50.8 // 2 pixels per millimeter
+
+	err = Convert(r, &w, opts)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	img, err := png.Decode(&w)
+	if err != nil {
+		t.Fatalf("Expected PNG output: %v", err)
+	}
+	// A4 portrait: 210 x 297 mm
+	if b := img.Bounds(); b.Dx() != 420 || b.Dy() != 594 {
+		t.Errorf("Image size = %dx%d, want 420x594", b.Dx(), b.Dy())
+	}
+	if c := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("Margin pixel = %v, want white", c)
+	}
+}
+
+func TestConvert_EPS(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/arc.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read encoded DXF: %v", err)
+	}
+	r := bytes.NewReader(dxfData)
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.Format = FormatEPS
+
+	err = Convert(r, &w, opts)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	out := w.String()
+	if !strings.HasPrefix(out, "%!PS-Adobe-3.0 EPSF-3.0") {
+		t.Error("Expected EPS output to start with %!PS-Adobe-3.0 EPSF-3.0")
+	}
+	if !strings.Contains(out, "%%BoundingBox:") {
+		t.Error("Expected EPS output to contain a %%BoundingBox comment")
+	}
+	if !strings.Contains(out, " A\n") {
+		t.Error("Expected the arc to be drawn with the arc operator")
+	}
+}
+
+func TestConvert_HPGL(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read encoded DXF: %v", err)
+	}
+	r := bytes.NewReader(dxfData)
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.Format = FormatHPGL
+	opts.PenTable = map[int]int{1: 4}
+
+	err = Convert(r, &w, opts)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	out := w.String()
+	if !strings.HasPrefix(out, "IN;") {
+		t.Error("Expected HPGL output to start with IN;")
+	}
+	// Layer DIM is red (ACI 1) and dashed
+	if !strings.Contains(out, "SP4;") {
+		t.Errorf("Expected the red layer on pen 4:\n%s", out)
+	}
+	if !strings.Contains(out, "LT1,") {
+		t.Errorf("Expected a user-defined line type for the dashed layer:\n%s", out)
+	}
+}
+
 func TestConvert_Arc(t *testing.T) {
 	dxfData, err := os.ReadFile("../../fixtures/arc.dxf")
 	if err != nil {
@@ -86,7 +180,7 @@ func TestConvert_Arc(t *testing.T) {
 	if !strings.Contains(output, "<svg") {
 		t.Error("Expected SVG output to contain <svg tag")
 	}
-	// svgo Arc uses path
+	// Arcs are written as path data
 	if !strings.Contains(output, "<path") {
 		t.Error("Expected SVG output to contain <path tag for Arc")
 	}
@@ -150,6 +244,26 @@ func TestConvert_BrokenDXF(t *testing.T) {
 	}
 }
 
+func TestConvertContext_Lenient(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/broken.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read broken DXF: %v", err)
+	}
+	opts := DefaultOptions()
+	opts.Lenient = true
+	var w bytes.Buffer
+	res, err := ConvertContext(context.Background(), bytes.NewReader(dxfData), &w, opts)
+	if err != nil {
+		t.Fatalf("ConvertContext() error = %v", err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].EntityType != "LINE" || res.Warnings[0].Severity != dxfconverror.SeverityError {
+		t.Errorf("Warnings = %v", res.Warnings)
+	}
+	if !bytes.HasPrefix(w.Bytes(), []byte("%PDF-")) {
+		t.Error("Output is not a PDF")
+	}
+}
+
 func TestDrawing_Entities(t *testing.T) {
 	dxfPath := "../../fixtures/overall.dxf"
 	f, err := os.Open(dxfPath)
@@ -278,3 +392,219 @@ func TestDrawing_NewEntities(t *testing.T) {
 		})
 	}
 }
+
+func TestConvert_HeaderPointMode(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/header.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.Format = FormatSVG
+	opts.UseHeaderExtents = true
+
+	if err := Convert(bytes.NewReader(dxfData), &w, opts); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	output := w.String()
+	// $PDMODE 35 = cross (3) + circle (32)
+	if n := strings.Count(output, "<line"); n != 2 {
+		t.Errorf("Expected 2 <line elements for a cross point, got %d", n)
+	}
+	if n := strings.Count(output, "<circle"); n != 1 {
+		t.Errorf("Expected 1 <circle element for a circled point, got %d", n)
+	}
+}
+
+func TestPointStyle(t *testing.T) {
+	h := &dxf.Header{Variables: map[string][]dxf.Tag{
+		"$PDMODE": {{Code: 70, Value: "3"}},
+		"$PDSIZE": {{Code: 40, Value: "-10"}},
+	}}
+	ps := pointStyle(h, 2, 200)
+	if ps == nil {
+		t.Fatal("Expected a point style when $PDMODE is set")
+	}
+	if ps.Mode != 3 || ps.Size != 20 {
+		t.Errorf("Expected mode 3 size 20, got mode %d size %v", ps.Mode, ps.Size)
+	}
+
+	if ps := pointStyle(&dxf.Header{}, 2, 200); ps != nil {
+		t.Errorf("Expected nil point style without $PDMODE, got %+v", ps)
+	}
+}
+
+func TestConvert_PlotScale(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/header.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		plotScale string
+		units     dxf.Units
+		wantFit   bool
+	}{
+		// Header extents are 100 x 100 mm, the A4 printable area 190 x 277 mm
+		{name: "1:1 fits", plotScale: "1:1", wantFit: true},
+		{name: "2:1 overflows", plotScale: "2:1", wantFit: false},
+		{name: "meters override overflows", plotScale: "1:100", units: dxf.UnitsMeters, wantFit: false},
+		{name: "meters at 1:1000 fits", plotScale: "1:1000", units: dxf.UnitsMeters, wantFit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			opts := DefaultOptions()
+			opts.UseHeaderExtents = true
+			opts.PlotScale = tt.plotScale
+			opts.Units = tt.units
+
+			err := Convert(bytes.NewReader(dxfData), &w, opts)
+			if tt.wantFit {
+				if err != nil {
+					t.Fatalf("Convert failed: %v", err)
+				}
+				return
+			}
+			var fitErr *dxfconverror.FitError
+			if !errors.As(err, &fitErr) {
+				t.Fatalf("Expected FitError, got %T: %v", err, err)
+			}
+			if w.Len() != 0 {
+				t.Errorf("Expected no output on FitError, got %d bytes", w.Len())
+			}
+
+			opts.AllowOverflow = true
+			if err := Convert(bytes.NewReader(dxfData), &w, opts); err != nil {
+				t.Errorf("Convert with AllowOverflow failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvert_InvalidPlotScale(t *testing.T) {
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.PlotScale = "1:0"
+
+	err := Convert(strings.NewReader(""), &w, opts)
+	var optErr *dxfconverror.OptionError
+	if !errors.As(err, &optErr) {
+		t.Fatalf("Expected OptionError, got %T: %v", err, err)
+	}
+	if optErr.Option != "PlotScale" {
+		t.Errorf("Expected option PlotScale, got %s", optErr.Option)
+	}
+}
+
+func TestConvert_Window(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/view.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		setup  func(*Options)
+		want   string
+	}{
+		{"Window PDF", FormatPDF, func(o *Options) { o.Window = &Window{MinX: 0, MinY: 0, MaxX: 50, MaxY: 50} }, "re W n"},
+		{"Window SVG", FormatSVG, func(o *Options) { o.Window = &Window{MinX: 0, MinY: 0, MaxX: 50, MaxY: 50} }, "<clipPath"},
+		{"View SVG", FormatSVG, func(o *Options) { o.View = "detail" }, "clip-path=\"url(#clip1)\""},
+		{"Limits PDF", FormatPDF, func(o *Options) { o.PlotLimits = true }, "re W n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			opts := DefaultOptions()
+			opts.Format = tt.format
+			tt.setup(opts)
+
+			if err := Convert(bytes.NewReader(dxfData), &w, opts); err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			if !strings.Contains(w.String(), tt.want) {
+				t.Errorf("Expected output to contain %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert_UnknownView(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/view.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.View = "missing"
+
+	err = Convert(bytes.NewReader(dxfData), &w, opts)
+	var optErr *dxfconverror.OptionError
+	if !errors.As(err, &optErr) || optErr.Option != "View" {
+		t.Fatalf("Expected View OptionError, got %T: %v", err, err)
+	}
+}
+
+func TestConvert_PDFSize(t *testing.T) {
+	// Upper bounds on the output size, to catch regressions. Lower them when
+	// the output shrinks.
+	input := polylinesDXF(200, 50)
+	tests := []struct {
+		compress bool
+		max      int
+	}{
+		{false, 340000},
+		{true, 40000},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.Compress = tt.compress
+		var w bytes.Buffer
+		if err := Convert(strings.NewReader(input), &w, opts); err != nil {
+			t.Fatal(err)
+		}
+		if w.Len() > tt.max {
+			t.Errorf("Compress %v: output is %d bytes, want at most %d", tt.compress, w.Len(), tt.max)
+		}
+	}
+}
+
+func TestDocumentInfo(t *testing.T) {
+	header := func(vars map[string]string) *dxf.Header {
+		h := &dxf.Header{Variables: map[string][]dxf.Tag{}}
+		for name, v := range vars {
+			h.Variables[name] = []dxf.Tag{{Code: 1, Value: v}}
+		}
+		return h
+	}
+	tests := []struct {
+		name     string
+		header   map[string]string
+		opts     Options
+		title    string
+		producer string
+	}{
+		{"empty", map[string]string{"$TITLE": "Plan"}, Options{Filename: "a.dxf"}, "", ""},
+		{"PDF/A", nil, Options{PDFA: true}, "", "dxfconv"},
+		{"file name", nil, Options{Filename: "/plans/Ground floor.dxf", PDFA: true}, "Ground floor", "dxfconv"},
+		{"project name", map[string]string{"$PROJECTNAME": "Tower"}, Options{Filename: "a.dxf", PDFA: true}, "Tower", "dxfconv"},
+		{"title", map[string]string{"$TITLE": "Plan", "$PROJECTNAME": "Tower"}, Options{Info: pdf.Info{Author: "Jane"}}, "Plan", "dxfconv"},
+		{"option", map[string]string{"$TITLE": "Plan"}, Options{Info: pdf.Info{Title: "Mine", Producer: "me"}}, "Mine", "me"},
+	}
+	for _, tt := range tests {
+		info := documentInfo(header(tt.header), &tt.opts)
+		if info.Title != tt.title || info.Producer != tt.producer {
+			t.Errorf("%s: title %q, producer %q; want %q, %q", tt.name, info.Title, info.Producer, tt.title, tt.producer)
+		}
+	}
+
+	h := header(map[string]string{"$TDUCREATE": "2460000.5"})
+	if info := documentInfo(h, &Options{PDFA: true}); !info.CreationDate.Equal(time.Date(2023, 2, 25, 0, 0, 0, 0, time.UTC)) || !info.ModDate.IsZero() {
+		t.Errorf("dates = %v, %v", info.CreationDate, info.ModDate)
+	}
+}
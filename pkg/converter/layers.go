@@ -0,0 +1,212 @@
+package converter
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
+// Length in page units of a zero-length (dot) linetype element
+const dotLength = 0.1
+
+// layerSet resolves layer visibility and styles for the entities of a drawing.
+type layerSet struct {
+	drawing *dxf.Drawing
+	opts    *Options
+	// layers indexes the LAYER table by lowercase name
+	layers map[string]*dxf.Layer
+	// linetypes indexes the LTYPE table by lowercase name
+	linetypes map[string]*dxf.Linetype
+	visible   map[string]bool
+}
+
+func newLayerSet(d *dxf.Drawing, opts *Options) (*layerSet, error) {
+	for _, p := range opts.Layers {
+		if err := checkPattern(p); err != nil {
+			return nil, &dxfconverror.OptionError{Option: "Layers", Err: err}
+		}
+	}
+	for _, p := range opts.ExcludeLayers {
+		if err := checkPattern(p); err != nil {
+			return nil, &dxfconverror.OptionError{Option: "ExcludeLayers", Err: err}
+		}
+	}
+	for _, ls := range opts.LayerStyles {
+		if err := checkPattern(ls.Layer); err != nil {
+			return nil, &dxfconverror.OptionError{Option: "LayerStyles", Err: err}
+		}
+	}
+
+	ls := &layerSet{
+		drawing:   d,
+		opts:      opts,
+		layers:    make(map[string]*dxf.Layer, len(d.Layers)),
+		linetypes: make(map[string]*dxf.Linetype, len(d.Linetypes)),
+		visible:   make(map[string]bool),
+	}
+	for i := range d.Layers {
+		ls.layers[strings.ToLower(d.Layers[i].Name)] = &d.Layers[i]
+	}
+	for i := range d.Linetypes {
+		ls.linetypes[strings.ToLower(d.Linetypes[i].Name)] = &d.Linetypes[i]
+	}
+	return ls, nil
+}
+
+func checkPattern(p string) error {
+	if _, err := dxf.MatchName(p, ""); err != nil {
+		return fmt.Errorf("bad layer pattern %q: %w", p, err)
+	}
+	return nil
+}
+
+// matchLayer reports whether the layer name matches the wildcard pattern, ignoring case.
+func matchLayer(pattern, name string) bool {
+	ok, _ := dxf.MatchName(pattern, name)
+	return ok
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchLayer(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Visible reports whether entities on the named layer are plotted.
+func (ls *layerSet) Visible(name string) bool {
+	if v, ok := ls.visible[name]; ok {
+		return v
+	}
+	v := ls.isVisible(name)
+	ls.visible[name] = v
+	return v
+}
+
+func (ls *layerSet) isVisible(name string) bool {
+	if len(ls.opts.Layers) > 0 && !matchAny(ls.opts.Layers, name) {
+		return false
+	}
+	if matchAny(ls.opts.ExcludeLayers, name) {
+		return false
+	}
+	if ls.hidden(name) && !ls.opts.ShowHiddenLayers && !ls.opts.OptionalContent {
+		return false
+	}
+	return true
+}
+
+// hidden reports whether the layer table hides the layer: frozen, off or not plottable.
+func (ls *layerSet) hidden(name string) bool {
+	l, ok := ls.layers[strings.ToLower(name)]
+	return ok && (l.Frozen() || l.Off() || !l.Plot)
+}
+
+// Info describes the entity for renderers that group output by entity or layer.
+func (ls *layerSet) Info(e dxf.Entity) renderers.EntityInfo {
+	base := e.Common()
+	return renderers.EntityInfo{
+		Type:         string(base.EntityType),
+		Handle:       base.Handle,
+		Layer:        base.LayerName,
+		LayerVisible: ls.opts.ShowHiddenLayers || !ls.hidden(base.LayerName),
+	}
+}
+
+// override merges the LayerStyles matching the layer, later entries taking precedence.
+func (ls *layerSet) override(name string) LayerStyle {
+	var o LayerStyle
+	for _, s := range ls.opts.LayerStyles {
+		if !matchLayer(s.Layer, name) {
+			continue
+		}
+		if s.Color != 0 {
+			o.Color = s.Color
+		}
+		if s.LineWeight != 0 {
+			o.LineWeight = s.LineWeight
+		}
+		if s.LineType != "" {
+			o.LineType = s.LineType
+		}
+	}
+	return o
+}
+
+// Style resolves the pen of an entity from its own properties, its layer and the
+// LayerStyles overrides. scale converts drawing units to page units.
+func (ls *layerSet) Style(e dxf.Entity, scale float64) renderers.Style {
+	base := e.Common()
+	layer := ls.layers[strings.ToLower(base.LayerName)]
+	o := ls.override(base.LayerName)
+
+	// Colour
+	aci := base.Color
+	if aci == dxf.ColorByLayer {
+		aci = 7
+		if layer != nil {
+			aci = int(math.Abs(float64(layer.Color)))
+		}
+	}
+	if aci == dxf.ColorByBlock {
+		aci = 7
+	}
+	if o.Color != 0 {
+		aci = o.Color
+	}
+	var c color.RGBA
+	if aci == 7 {
+		// Colour 7 is the foreground colour: black on white paper
+		c = color.RGBA{A: 0xff}
+	} else {
+		c = dxf.ACIColor(aci)
+	}
+
+	// Lineweight
+	lw := base.LineWeight
+	if lw == dxf.LineWeightByLayer {
+		lw = dxf.LineWeightDefault
+		if layer != nil {
+			lw = layer.LineWeight
+		}
+	}
+	var width float64
+	if lw > 0 {
+		width = float64(lw) / 100
+	}
+	if o.LineWeight != 0 {
+		width = o.LineWeight
+	}
+
+	// Linetype
+	lt := base.LineType
+	if lt == "" || strings.EqualFold(lt, "BYLAYER") {
+		lt = ""
+		if layer != nil {
+			lt = layer.LineType
+		}
+	}
+	if o.LineType != "" {
+		lt = o.LineType
+	}
+	var dash []float64
+	if t, ok := ls.linetypes[strings.ToLower(lt)]; ok && len(t.Pattern) > 0 {
+		ltScale := ls.drawing.Header.LTScale() * base.LineTypeScale * scale
+		dash = make([]float64, len(t.Pattern))
+		for i, v := range t.Pattern {
+			dash[i] = math.Abs(v) * ltScale
+			if dash[i] == 0 {
+				dash[i] = dotLength
+			}
+		}
+	}
+
+	return renderers.Style{Color: c, ColorIndex: aci, LineWidth: width, Dash: dash}
+}
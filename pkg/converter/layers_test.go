@@ -0,0 +1,241 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+func TestConvert_LayerFiltering(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(*Options)
+		wantLines int
+	}{
+		{"hidden layers skipped by default", func(o *Options) {}, 3},
+		{"show hidden layers", func(o *Options) { o.ShowHiddenLayers = true }, 6},
+		{"include wildcard", func(o *Options) { o.Layers = []string{"d*"} }, 1},
+		{"exclude", func(o *Options) { o.ExcludeLayers = []string{"DIM"} }, 2},
+		{"include hidden layer explicitly", func(o *Options) {
+			o.Layers = []string{"hidden", "frozen"}
+			o.ShowHiddenLayers = true
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			opts := DefaultOptions()
+			opts.Format = FormatSVG
+			tt.setup(opts)
+
+			if err := Convert(bytes.NewReader(dxfData), &w, opts); err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			if n := strings.Count(w.String(), "<line"); n != tt.wantLines {
+				t.Errorf("Expected %d lines, got %d", tt.wantLines, n)
+			}
+		})
+	}
+}
+
+func TestConvert_BadLayerPattern(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.Layers = []string{"[dim"}
+
+	err = Convert(bytes.NewReader(dxfData), &w, opts)
+	var optErr *dxfconverror.OptionError
+	if !errors.As(err, &optErr) || optErr.Option != "Layers" {
+		t.Fatalf("Expected Layers OptionError, got %T: %v", err, err)
+	}
+}
+
+func TestLayerSet_XrefLayers(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Layers = []string{"*|WALLS"}
+	ls, err := newLayerSet(&dxf.Drawing{}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"site|walls":              true,
+		"plans/site.dwg|WALLS":    true,
+		"plans/site.dwg|WALLS-HA": false,
+		"WALLS":                   false,
+	} {
+		if got := ls.Visible(name); got != want {
+			t.Errorf("Visible(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestLayerSet_Style(t *testing.T) {
+	f, err := os.Open("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to open DXF: %v", err)
+	}
+	defer f.Close()
+	d, err := dxf.Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	opts := DefaultOptions()
+	ls, err := newLayerSet(d, opts)
+	if err != nil {
+		t.Fatalf("newLayerSet failed: %v", err)
+	}
+
+	// BYLAYER on layer 0: foreground colour, default width, solid
+	s := ls.Style(d.Entities[0], 1)
+	if s.Color != (color.RGBA{A: 0xff}) || s.LineWidth != 0 || len(s.Dash) != 0 {
+		t.Errorf("Unexpected style on layer 0: %+v", s)
+	}
+
+	// Explicit colour 5
+	if s := ls.Style(d.Entities[1], 1); s.Color != dxf.ACIColor(5) {
+		t.Errorf("Expected blue, got %v", s.Color)
+	}
+
+	// DIM: red, 0.5 mm, DASHED scaled by $LTSCALE 2 and page scale 3
+	s = ls.Style(d.Entities[2], 3)
+	if s.Color != dxf.ACIColor(1) || s.LineWidth != 0.5 {
+		t.Errorf("Unexpected style on layer DIM: %+v", s)
+	}
+	if len(s.Dash) != 2 || s.Dash[0] != 3 || s.Dash[1] != 1.5 {
+		t.Errorf("Unexpected dash on layer DIM: %v", s.Dash)
+	}
+
+	// Overrides: the later entry wins for the fields it sets
+	opts.LayerStyles = []LayerStyle{
+		{Layer: "*", Color: 3, LineWeight: 0.35},
+		{Layer: "DIM", Color: 8, LineType: "CONTINUOUS"},
+	}
+	ls, err = newLayerSet(d, opts)
+	if err != nil {
+		t.Fatalf("newLayerSet failed: %v", err)
+	}
+	s = ls.Style(d.Entities[2], 3)
+	if s.Color != dxf.ACIColor(8) || s.LineWidth != 0.35 || len(s.Dash) != 0 {
+		t.Errorf("Unexpected overridden style on layer DIM: %+v", s)
+	}
+}
+
+func TestConvert_TinyLineTypeScale(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+	// A dashed line on layer DIM, far longer than its dash pattern
+	data := strings.Replace(string(dxfData), "$LTSCALE\n 40\n2.0", "$LTSCALE\n 40\n1e-9", 1)
+	if data == string(dxfData) {
+		t.Fatal("$LTSCALE not found")
+	}
+	opts := DefaultOptions()
+	opts.Format = FormatPNG
+	opts.Layers = []string{"DIM"}
+	var w bytes.Buffer
+	if err := Convert(strings.NewReader(data), &w, opts); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+}
+
+func TestConvert_OptionalContent(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+	var w bytes.Buffer
+	opts := DefaultOptions()
+	opts.OptionalContent = true
+
+	if err := Convert(bytes.NewReader(dxfData), &w, opts); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	output := w.String()
+	if n := strings.Count(output, "/Type /OCG"); n != 5 {
+		t.Errorf("Expected 5 optional content groups, got %d", n)
+	}
+	// HIDDEN, FROZEN and Construction start hidden
+	if !strings.Contains(output, "/OFF [ 8 0 R 9 0 R 10 0 R ]") {
+		t.Error("Expected hidden layers to be listed in /OFF")
+	}
+	if n := strings.Count(output, " BDC\n"); n != strings.Count(output, "EMC\n") || n != 5 {
+		t.Errorf("Expected 5 balanced marked-content sections, got %d", n)
+	}
+}
+
+func TestConvert_SVGLayerGroups(t *testing.T) {
+	dxfData, err := os.ReadFile("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to read DXF: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(*Options)
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "groups and metadata",
+			setup: func(o *Options) {},
+			want: []string{
+				`<g id="layer-0" data-layer="0"`,
+				`<g id="layer-DIM" data-layer="DIM"`,
+				`data-type="LINE" data-layer="DIM" data-handle="1C"`,
+				`style="fill:none;stroke:#ff0000`,
+			},
+			notWant: []string{"<style", "display:none"},
+		},
+		{
+			name:    "classes",
+			setup:   func(o *Options) { o.SVGClasses = true },
+			want:    []string{`<style type="text/css">`, ".s0 { fill:none;stroke:#000000", `class="s2"`},
+			notWant: []string{`style="fill:none;stroke:#ff0000`},
+		},
+		{
+			name:  "hidden layers",
+			setup: func(o *Options) { o.OptionalContent = true },
+			want:  []string{`<g id="layer-HIDDEN" data-layer="HIDDEN" style="display:none"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			opts := DefaultOptions()
+			opts.Format = FormatSVG
+			tt.setup(opts)
+
+			if err := Convert(bytes.NewReader(dxfData), &w, opts); err != nil {
+				t.Fatalf("Convert failed: %v", err)
+			}
+			output := w.String()
+			for _, s := range tt.want {
+				if !strings.Contains(output, s) {
+					t.Errorf("Expected output to contain %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(output, s) {
+					t.Errorf("Expected output not to contain %q", s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
+// checkLimits rejects negative limits
+func checkLimits(opts *Options) error {
+	for _, l := range []struct {
+		name  string
+		value int64
+	}{
+		{"MaxEntities", int64(opts.MaxEntities)},
+		{"MaxBlockDepth", int64(opts.MaxBlockDepth)},
+		{"MaxInputBytes", opts.MaxInputBytes},
+		{"MaxVertices", int64(opts.MaxVertices)},
+		{"MaxPixels", opts.MaxPixels},
+	} {
+		if l.value < 0 {
+			return &dxfconverror.OptionError{Option: l.name, Err: errors.New("must not be negative")}
+		}
+	}
+	return nil
+}
+
+// checkPixels returns a LimitError if the page at the resolution of the
+// options has more pixels than MaxPixels, computed like the PNG renderer
+func checkPixels(opts *Options) error {
+	max := opts.MaxPixels
+	if max == 0 {
+		max = renderers.DefaultPNGMaxPixels
+	}
+	dpi := opts.DPI
+	if dpi <= 0 {
+		dpi = renderers.DefaultPNGDPI
+	}
+	scale := dpi / 25.4
+	w, h := math.Ceil(opts.PageSize.Width*scale), math.Ceil(opts.PageSize.Height*scale)
+	// Written so that NaN sizes fail too
+	if m := float64(max); !(w*h <= m && w <= m && h <= m) {
+		return &dxfconverror.LimitError{Limit: "MaxPixels", Max: max}
+	}
+	return nil
+}
+
+// limitReader reads from r until more than remaining bytes have been read,
+// then fails with a LimitError for MaxInputBytes
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func newLimitReader(r io.Reader, max int64) *limitReader {
+	return &limitReader{r: r, remaining: max, max: max}
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, &dxfconverror.LimitError{Limit: "MaxInputBytes", Max: l.max}
+	}
+	// Read one byte beyond the limit to tell a file of exactly max bytes
+	// from a longer one. Written so that remaining+1 cannot overflow.
+	if int64(len(p))-1 > l.remaining {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, &dxfconverror.LimitError{Limit: "MaxInputBytes", Max: l.max}
+	}
+	return n, err
+}
+
+// checkVertices returns a LimitError if the entities, drawn with dc, have
+// more than max vertices in total. Zero means no limit.
+func checkVertices(ctx context.Context, dc *renderers.DrawContext, entities []dxf.Entity, max int) error {
+	if max <= 0 {
+		return nil
+	}
+	c := &vertexCounter{}
+	for i, e := range entities {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if dc.Draw(c, e); c.n > max {
+			return &dxfconverror.LimitError{Limit: "MaxVertices", Max: int64(max)}
+		}
+	}
+	return nil
+}
+
+// vertexCounter is a renderer that counts the vertices it is handed, with
+// curves flattened to polylines as for renderers without native curves.
+// Text counts as one vertex.
+type vertexCounter struct {
+	n int
+}
+
+func (c *vertexCounter) Init(width, height float64)               {}
+func (c *vertexCounter) Clip(x, y, width, height float64)         {}
+func (c *vertexCounter) SetStyle(style renderers.Style)           {}
+func (c *vertexCounter) Line(x1, y1, x2, y2 float64)              { c.n += 2 }
+func (c *vertexCounter) Circle(x, y, radius float64)              { c.arc(x, y, radius, 0, 360) }
+func (c *vertexCounter) Polyline(points [][]float64, closed bool) { c.n += len(points) }
+func (c *vertexCounter) Text(x, y, height float64, text string)   { c.n++ }
+func (c *vertexCounter) Finish() error                            { return nil }
+func (c *vertexCounter) Arc(x, y, radius, startAngle, endAngle float64) {
+	sweep := math.Mod(endAngle-startAngle, 360)
+	if sweep <= 0 {
+		sweep += 360
+	}
+	c.arc(x, y, radius, startAngle, sweep)
+}
+
+func (c *vertexCounter) arc(x, y, radius, startAngle, sweep float64) {
+	var p renderers.Path
+	p.ArcTo(x, y, radius, startAngle, sweep)
+	polylines, _ := p.Polylines()
+	for _, pts := range polylines {
+		c.n += len(pts)
+	}
+}
@@ -0,0 +1,146 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+// polylinesDXF returns a drawing of n polylines with the given number of vertices
+func polylinesDXF(n, vertices int) string {
+	var b strings.Builder
+	b.WriteString("0\nSECTION\n2\nENTITIES\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "0\nLWPOLYLINE\n8\n0\n90\n%d\n", vertices)
+		for v := 0; v < vertices; v++ {
+			fmt.Fprintf(&b, "10\n%d\n20\n%d\n", v, i)
+		}
+	}
+	b.WriteString("0\nENDSEC\n0\nEOF\n")
+	return b.String()
+}
+
+// A drawing inserting block OUTER, which inserts block INNER
+const nestedBlocksDXF = "0\nSECTION\n2\nBLOCKS\n" +
+	"0\nBLOCK\n2\nINNER\n0\nLINE\n10\n0\n20\n0\n11\n1\n21\n1\n0\nENDBLK\n" +
+	"0\nBLOCK\n2\nOUTER\n0\nINSERT\n2\nINNER\n0\nENDBLK\n" +
+	"0\nENDSEC\n" +
+	"0\nSECTION\n2\nENTITIES\n" +
+	"0\nINSERT\n2\nOUTER\n" +
+	"0\nLINE\n10\n0\n20\n0\n11\n10\n21\n10\n" +
+	"0\nENDSEC\n0\nEOF\n"
+
+const circleDXF = "0\nSECTION\n2\nENTITIES\n0\nCIRCLE\n10\n0\n20\n0\n40\n10\n0\nENDSEC\n0\nEOF\n"
+
+func TestConvertContext_Limits(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		modify func(o *Options)
+		limit  string
+	}{
+		{"entities", polylinesDXF(5, 2), func(o *Options) { o.MaxEntities = 4 }, "MaxEntities"},
+		{"vertices", polylinesDXF(3, 10), func(o *Options) { o.MaxVertices = 25 }, "MaxVertices"},
+		// Curves count the points they are flattened to
+		{"circle", circleDXF, func(o *Options) { o.MaxVertices = 100 }, "MaxVertices"},
+		{"input bytes", polylinesDXF(3, 10), func(o *Options) { o.MaxInputBytes = 100 }, "MaxInputBytes"},
+		{"block depth", nestedBlocksDXF, func(o *Options) { o.MaxBlockDepth = 1 }, "MaxBlockDepth"},
+		// An A4 page at 10 DPI is 83 x 117 pixels
+		{"pixels", polylinesDXF(1, 2), func(o *Options) { o.Format, o.DPI, o.MaxPixels = FormatPNG, 10, 83*117-1 }, "MaxPixels"},
+		{"default pixels", polylinesDXF(1, 2), func(o *Options) { o.Format, o.DPI = FormatPNG, 1e9 }, "MaxPixels"},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		tt.modify(opts)
+		_, err := ConvertContext(context.Background(), strings.NewReader(tt.input), &bytes.Buffer{}, opts)
+		var limitErr *dxfconverror.LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit {
+			t.Errorf("%s: error = %v, want LimitError for %s", tt.name, err, tt.limit)
+		}
+	}
+
+	// Drawings within the limits convert
+	input := polylinesDXF(5, 2)
+	opts := DefaultOptions()
+	opts.MaxEntities = 5
+	opts.MaxVertices = 10
+	opts.MaxInputBytes = int64(len(input))
+	if err := Convert(strings.NewReader(input), &bytes.Buffer{}, opts); err != nil {
+		t.Errorf("Convert() error = %v", err)
+	}
+	opts = DefaultOptions()
+	opts.MaxInputBytes = math.MaxInt64
+	if err := Convert(strings.NewReader(input), &bytes.Buffer{}, opts); err != nil {
+		t.Errorf("Convert() error = %v with MaxInputBytes math.MaxInt64", err)
+	}
+	opts = DefaultOptions()
+	opts.MaxBlockDepth = 2
+	if err := Convert(strings.NewReader(nestedBlocksDXF), &bytes.Buffer{}, opts); err != nil {
+		t.Errorf("Convert() error = %v", err)
+	}
+	opts = DefaultOptions()
+	opts.Format, opts.DPI, opts.MaxPixels = FormatPNG, 10, 83*117
+	if err := Convert(strings.NewReader(input), &bytes.Buffer{}, opts); err != nil {
+		t.Errorf("Convert() error = %v", err)
+	}
+
+	opts = DefaultOptions()
+	opts.MaxEntities = -1
+	var optErr *dxfconverror.OptionError
+	if err := Convert(strings.NewReader(input), &bytes.Buffer{}, opts); !errors.As(err, &optErr) {
+		t.Errorf("Convert() error = %v, want OptionError", err)
+	}
+}
+
+func TestConvertContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ConvertContext(ctx, strings.NewReader(polylinesDXF(2000, 2)), &bytes.Buffer{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ConvertContext() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestThumbnailContext_Limits(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		modify func(o *ThumbnailOptions)
+		limit  string
+	}{
+		{"entities", polylinesDXF(5, 2), func(o *ThumbnailOptions) { o.Input.MaxEntities = 4 }, "MaxEntities"},
+		{"vertices", polylinesDXF(3, 10), func(o *ThumbnailOptions) { o.Input.MaxVertices = 25 }, "MaxVertices"},
+		{"input bytes", polylinesDXF(3, 10), func(o *ThumbnailOptions) { o.Input.MaxInputBytes = 100 }, "MaxInputBytes"},
+		{"block depth", nestedBlocksDXF, func(o *ThumbnailOptions) { o.Input.MaxBlockDepth = 1 }, "MaxBlockDepth"},
+		{"pixels", polylinesDXF(1, 2), func(o *ThumbnailOptions) { o.Input.MaxPixels = 64*64 - 1 }, "MaxPixels"},
+		{"default pixels", polylinesDXF(1, 2), func(o *ThumbnailOptions) { o.Input, o.Width, o.Height = nil, 1<<20, 1<<20 }, "MaxPixels"},
+	}
+	for _, tt := range tests {
+		opts := ThumbnailOptions{Width: 64, Height: 64, Input: DefaultOptions()}
+		tt.modify(&opts)
+		_, err := ThumbnailContext(context.Background(), strings.NewReader(tt.input), &bytes.Buffer{}, opts)
+		var limitErr *dxfconverror.LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != tt.limit {
+			t.Errorf("%s: error = %v, want LimitError for %s", tt.name, err, tt.limit)
+		}
+	}
+
+	opts := ThumbnailOptions{Width: 64, Height: 64, Input: DefaultOptions()}
+	opts.Input.MaxEntities, opts.Input.MaxVertices, opts.Input.MaxPixels = 5, 10, 64*64
+	if _, err := ThumbnailContext(context.Background(), strings.NewReader(polylinesDXF(5, 2)), &bytes.Buffer{}, opts); err != nil {
+		t.Errorf("ThumbnailContext() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts.Input = nil
+	if _, err := ThumbnailContext(ctx, strings.NewReader(polylinesDXF(2000, 2)), &bytes.Buffer{}, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("ThumbnailContext() error = %v, want context.Canceled", err)
+	}
+}
@@ -1,5 +1,13 @@
 package converter
 
+import (
+	"image/color"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/pdf"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
 // PageSize represents the dimensions of the PDF page
 type PageSize struct {
 	Width  float64
@@ -24,29 +32,159 @@ const (
 type Format string
 
 const (
-	FormatPDF Format = "pdf"
-	FormatSVG Format = "svg"
+	FormatPDF  Format = "pdf"
+	FormatSVG  Format = "svg"
+	FormatPNG  Format = "png"
+	FormatEPS  Format = "eps"
+	FormatHPGL Format = "hpgl"
 )
 
+// Window is a rectangle of model space in drawing coordinates
+type Window struct {
+	MinX, MinY float64
+	MaxX, MaxY float64
+}
+
+// LayerStyle overrides the appearance of the layers matching Layer
+type LayerStyle struct {
+	// Layer is a layer name or a wildcard pattern (see Options.Layers)
+	Layer string
+	// Color is an ACI colour number (1-255). Zero keeps the entity colour.
+	Color int
+	// LineWeight in mm. Zero keeps the entity lineweight.
+	LineWeight float64
+	// LineType is a linetype name from the LTYPE table, or "CONTINUOUS" for solid lines.
+	// Empty keeps the entity linetype.
+	LineType string
+}
+
 // Options configuration for the conversion
 type Options struct {
 	PageSize    PageSize
 	Orientation Orientation
-	// Format specifies the output format (pdf or svg)
+	// Format specifies the output format (pdf, svg, png, eps or hpgl)
 	Format Format
 	// Scale allows manual scaling. If 0, auto-scaling is used.
 	Scale float64
+	// PlotScale plots at true scale, given as "paper:model" (e.g. "1:50") or as
+	// "paper=model" lengths (e.g. `1/4"=1'-0"`, "1mm=1m"). Drawing units are
+	// converted to millimeters on paper. It takes precedence over Scale.
+	PlotScale string
+	// Units overrides the drawing units used by PlotScale.
+	// If zero (unitless), $INSUNITS from the DXF header is used.
+	Units dxf.Units
+	// AllowOverflow disables the FitError returned when the drawing does not fit
+	// the page at PlotScale. Geometry outside the page is cut off instead.
+	AllowOverflow bool
 	// Margin in mm
 	Margin float64
+	// UseHeaderExtents fits $EXTMIN/$EXTMAX from the DXF header instead of the
+	// bounds computed from the entities. The header extents are also used when
+	// no entity contributes to the bounds.
+	UseHeaderExtents bool
+	// Window plots only this region of model space; geometry is clipped at its boundary.
+	Window *Window
+	// View plots the region of the named VIEW table entry. Ignored if Window is set.
+	View string
+	// PlotLimits plots the $LIMMIN/$LIMMAX region. Ignored if Window or View is set.
+	PlotLimits bool
+	// Layers lists the layers to plot. Names may contain the wildcards of
+	// dxf.MatchName, where * also matches the / of external reference names, and
+	// are compared case-insensitively. If empty, all layers are plotted.
+	Layers []string
+	// ExcludeLayers lists layers not to plot, with the same syntax as Layers.
+	ExcludeLayers []string
+	// ShowHiddenLayers plots layers that are frozen, off or not plottable in the layer table.
+	ShowHiddenLayers bool
+	// OptionalContent maps DXF layers to PDF optional content groups that can be
+	// toggled in the viewer. Layers hidden in the layer table are then included
+	// but initially turned off, unless ShowHiddenLayers is set. In SVG output
+	// the groups of such layers are given display:none.
+	OptionalContent bool
+	// Compress writes PDF output with compressed content, object and
+	// cross-reference streams (PDF 1.5).
+	Compress bool
+	// LineJoin and LineCap shape the corners and ends of PDF strokes. The
+	// zero values are mitered joins and butt caps.
+	LineJoin pdf.LineJoin
+	LineCap  pdf.LineCap
+	// Info fills the document information and XMP metadata of PDF output,
+	// which is only written if a field is set or PDFA is on. An empty Title
+	// then defaults to the $TITLE or $PROJECTNAME header variable, else to
+	// Filename without its extension. Zero dates default to the $TDUCREATE
+	// and $TDUUPDATE header dates, an empty Producer to "dxfconv".
+	Info pdf.Info
+	// Filename is the name of the DXF file, the default title of PDF output
+	Filename string
+	// PDFA writes PDF/A-2b archival PDF output with XMP metadata and an sRGB
+	// output intent. Fonts are not embedded, so any drawing with text, e.g.
+	// TEXT, MTEXT or dimensions, fails with a *dxfconverror.RenderingError
+	// wrapping pdf.ErrFontNotEmbedded.
+	PDFA bool
+	// ViewerPreferences control how viewers open PDF output, e.g. zoomed to
+	// fit the page
+	ViewerPreferences pdf.ViewerPreferences
+	// SVGClasses styles SVG elements through CSS classes of an embedded stylesheet
+	// instead of inline styles, so that an external stylesheet can override them.
+	SVGClasses bool
+	// SVGPrecision is the number of decimals of SVG coordinates, zero for
+	// integers. Negative values select renderers.DefaultSVGPrecision.
+	SVGPrecision int
+	// SVGMinify writes SVG output without indentation and line breaks.
+	SVGMinify bool
+	// DPI is the resolution of PNG output in dots per inch. Zero selects
+	// renderers.DefaultPNGDPI.
+	DPI float64
+	// Background is the colour of PNG output behind the drawing.
+	// Nil selects white; color.Transparent gives a transparent image.
+	Background color.Color
+	// NoAntialias draws PNG output with hard pixel edges.
+	NoAntialias bool
+	// MinLineWidth is the minimum width of lines in PNG output, in pixels.
+	// Zero selects one pixel.
+	MinLineWidth float64
+	// PenTable maps ACI colour numbers to the pens of HPGL output.
+	// Nil selects renderers.DefaultPenTable; other colours use pen 1.
+	PenTable map[int]int
+	// LayerStyles overrides colour, lineweight and linetype per layer.
+	// When several entries match a layer, later entries take precedence.
+	LayerStyles []LayerStyle
+	// Lenient skips malformed entities and table records instead of failing
+	// with a ParseError, like CAD applications do. The skipped input is listed
+	// in Result.Warnings.
+	Lenient bool
+	// Workers is the number of goroutines parsing, measuring and drawing
+	// large drawings. Values below 2 convert sequentially. The output does
+	// not depend on it.
+	Workers int
+
+	// Resource limits for untrusted input. Drawings exceeding them fail with a
+	// *dxfconverror.LimitError. Zero means no limit.
+
+	// MaxInputBytes is the maximum size of the DXF data.
+	MaxInputBytes int64
+	// MaxEntities is the maximum number of entities in the drawing.
+	MaxEntities int
+	// MaxBlockDepth is the maximum nesting depth of block references.
+	// Recursive block definitions always exceed it.
+	MaxBlockDepth int
+	// MaxVertices is the maximum number of vertices drawn: two per line, one
+	// per polyline vertex, and for circles, arcs, bulges and splines the points
+	// of their flattening to polylines at the page scale. Text counts as one.
+	MaxVertices int
+	// MaxPixels is the maximum size of PNG output in pixels. Unlike the other
+	// limits, zero selects renderers.DefaultPNGMaxPixels.
+	MaxPixels int64
 }
 
 // DefaultOptions returns the default configuration
 func DefaultOptions() *Options {
 	return &Options{
-		PageSize:    PageSizeA4,
-		Orientation: OrientationPortrait,
-		Format:      FormatPDF,
-		Scale:       0.0,
-		Margin:      10.0,
+		PageSize:     PageSizeA4,
+		Orientation:  OrientationPortrait,
+		Format:       FormatPDF,
+		Scale:        0.0,
+		Margin:       10.0,
+		SVGPrecision: renderers.DefaultSVGPrecision,
 	}
 }
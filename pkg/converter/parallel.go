@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/daidai-ok/dxfconv/pkg/boundingbox"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
+// Number of entities measured or drawn by one goroutine at a time. Smaller
+// drawings are processed sequentially.
+const chunkSize = 4096
+
+// chunks splits n entities into runs of chunkSize, returning their bounds
+func chunks(n int) [][2]int {
+	var c [][2]int
+	for start := 0; start < n; start += chunkSize {
+		c = append(c, [2]int{start, min(start+chunkSize, n)})
+	}
+	return c
+}
+
+// forEachChunk calls f for each chunk of n entities on up to workers goroutines
+func forEachChunk(n, workers int, f func(i, start, end int)) {
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, c := range chunks(n) {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(i, c[0], c[1])
+			<-sem
+		}()
+	}
+	wg.Wait()
+}
+
+// extents returns the bounding box of the entities, measuring chunks of a
+// large drawing on workers goroutines
+func extents(entities []dxf.Entity, workers int) *boundingbox.BoundingBox {
+	if workers < 2 || len(entities) <= chunkSize {
+		return calculateBoundingBox(entities)
+	}
+	boxes := make([]*boundingbox.BoundingBox, len(chunks(len(entities))))
+	forEachChunk(len(entities), workers, func(i, start, end int) {
+		boxes[i] = calculateBoundingBox(entities[start:end])
+	})
+	bb := boundingbox.NewBoundingBox()
+	for _, b := range boxes {
+		if !b.IsEmpty() {
+			bb.Update(b.MinX, b.MinY)
+			bb.Update(b.MaxX, b.MaxY)
+		}
+	}
+	return bb
+}
+
+// drawChunks draws the entities of a large drawing on workers goroutines if
+// the renderer is a renderers.ChunkRenderer. Each chunk starts with the style
+// of the entity before it, so the output is that of sequential drawing. It
+// reports false if the entities are left to be drawn sequentially.
+func drawChunks(ctx context.Context, renderer renderers.Renderer, dc *renderers.DrawContext, entities []dxf.Entity, layers *layerSet, workers int) (bool, error) {
+	cr, ok := renderer.(renderers.ChunkRenderer)
+	if !ok || workers < 2 || len(entities) <= chunkSize {
+		return false, nil
+	}
+	c := chunks(len(entities))
+	rs := make([]renderers.Renderer, len(c))
+	for i, bounds := range c {
+		var prev *renderers.Style
+		if start := bounds[0]; start > 0 {
+			s := layers.Style(entities[start-1], dc.Scale)
+			prev = &s
+		}
+		if rs[i], ok = cr.NewChunk(prev); !ok {
+			return false, nil
+		}
+	}
+
+	forEachChunk(len(entities), workers, func(i, start, end int) {
+		r := rs[i]
+		for j, e := range entities[start:end] {
+			if j%cancelCheckInterval == 0 && ctx.Err() != nil {
+				return
+			}
+			r.SetStyle(layers.Style(e, dc.Scale))
+			dc.Draw(r, e)
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return true, err
+	}
+	for _, r := range rs {
+		cr.AppendChunk(r)
+	}
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// colorfulDXF returns a drawing of n lines and circles whose colour and
+// linetype change, so that chunks start in different styles
+func colorfulDXF(n int) string {
+	var b strings.Builder
+	b.WriteString("0\nSECTION\n2\nENTITIES\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "0\nLINE\n8\nL%d\n62\n%d\n10\n%d\n20\n0\n11\n%d\n21\n%d\n", i%3, i/7%5+1, i, i+1, i%11)
+		if i%5 == 0 {
+			fmt.Fprintf(&b, "0\nCIRCLE\n8\n0\n6\nDASHED\n10\n%d\n20\n-5\n40\n2\n", i)
+		}
+	}
+	b.WriteString("0\nENDSEC\n0\nEOF\n")
+	return b.String()
+}
+
+func TestConvertContext_Workers(t *testing.T) {
+	inputs := map[string]string{
+		"colorful":  colorfulDXF(3 * chunkSize),
+		"polylines": polylinesDXF(2*chunkSize+10, 3),
+	}
+	files, err := filepath.Glob("../../fixtures/*.dxf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[filepath.Base(f)] = string(data)
+	}
+
+	for name, input := range inputs {
+		for _, format := range []Format{FormatPDF, FormatSVG} {
+			convert := func(workers int) (string, error) {
+				opts := DefaultOptions()
+				opts.Format = format
+				opts.Workers = workers
+				var out bytes.Buffer
+				_, err := ConvertContext(context.Background(), strings.NewReader(input), &out, opts)
+				return out.String(), err
+			}
+			want, wantErr := convert(0)
+			got, gotErr := convert(4)
+			if fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+				t.Errorf("%s %s: error = %v, want %v", name, format, gotErr, wantErr)
+			} else if got != want {
+				t.Errorf("%s %s: parallel output differs from sequential", name, format)
+			}
+		}
+	}
+}
+
+func BenchmarkConvert(b *testing.B) {
+	input := colorfulDXF(100000)
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := DefaultOptions()
+			opts.Workers = workers
+			b.SetBytes(int64(len(input)))
+			for b.Loop() {
+				if err := Convert(strings.NewReader(input), &bytes.Buffer{}, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Paper sizes accepted by ParsePageSize, in mm (portrait)
+var pageSizes = map[string]PageSize{
+	"a0":      {Width: 841, Height: 1189},
+	"a1":      {Width: 594, Height: 841},
+	"a2":      {Width: 420, Height: 594},
+	"a3":      PageSizeA3,
+	"a4":      PageSizeA4,
+	"a5":      {Width: 148, Height: 210},
+	"letter":  {Width: 215.9, Height: 279.4},
+	"legal":   {Width: 215.9, Height: 355.6},
+	"tabloid": {Width: 279.4, Height: 431.8},
+}
+
+// ParsePageSize parses a paper name (A0-A5, letter, legal or tabloid, in any
+// case) or a size in millimeters given as WIDTHxHEIGHT, e.g. "300x200".
+func ParsePageSize(s string) (PageSize, error) {
+	if ps, ok := pageSizes[strings.ToLower(s)]; ok {
+		return ps, nil
+	}
+	if w, h, ok := strings.Cut(strings.ToLower(s), "x"); ok {
+		width, err1 := strconv.ParseFloat(w, 64)
+		height, err2 := strconv.ParseFloat(h, 64)
+		if err1 == nil && err2 == nil && width > 0 && height > 0 {
+			return PageSize{Width: width, Height: height}, nil
+		}
+	}
+	return PageSize{}, fmt.Errorf("unknown page size %q: expected a paper name (A0-A5, letter, legal, tabloid) or WIDTHxHEIGHT in mm", s)
+}
+
+// ParseOrientation parses "portrait" or "landscape", or their initials
+func ParseOrientation(s string) (Orientation, error) {
+	switch strings.ToLower(s) {
+	case "p", "portrait":
+		return OrientationPortrait, nil
+	case "l", "landscape":
+		return OrientationLandscape, nil
+	}
+	return "", fmt.Errorf("unknown orientation %q: expected portrait or landscape", s)
+}
+
+// ParseFormat parses an output format name such as "pdf" or "SVG"
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(s))
+	if f.Extension() == "" {
+		return "", fmt.Errorf("unknown format %q: expected pdf, svg, png, eps or hpgl", s)
+	}
+	return f, nil
+}
+
+// Extension returns the usual file name extension of the format, including
+// the dot, or "" for unknown formats.
+func (f Format) Extension() string {
+	switch f {
+	case FormatPDF:
+		return ".pdf"
+	case FormatSVG:
+		return ".svg"
+	case FormatPNG:
+		return ".png"
+	case FormatEPS:
+		return ".eps"
+	case FormatHPGL:
+		return ".plt"
+	}
+	return ""
+}
+
+// MediaType returns the MIME type of the format, or "" for unknown formats.
+func (f Format) MediaType() string {
+	switch f {
+	case FormatPDF:
+		return "application/pdf"
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPNG:
+		return "image/png"
+	case FormatEPS:
+		return "application/postscript"
+	case FormatHPGL:
+		return "application/vnd.hp-hpgl"
+	}
+	return ""
+}
+
+// ParseColor parses a colour given as "#rgb", "#rrggbb" or "#rrggbbaa" (the
+// leading # is optional), or by the names white, black and transparent.
+func ParseColor(s string) (color.Color, error) {
+	switch strings.ToLower(s) {
+	case "white":
+		return color.White, nil
+	case "black":
+		return color.Black, nil
+	case "transparent", "none":
+		return color.Transparent, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 {
+		return nil, fmt.Errorf("invalid colour %q: expected #rrggbb, #rrggbbaa, white, black or transparent", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParsePageSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want PageSize
+	}{
+		{"A4", PageSizeA4},
+		{"a3", PageSizeA3},
+		{"Letter", PageSize{Width: 215.9, Height: 279.4}},
+		{"300x200", PageSize{Width: 300, Height: 200}},
+		{"420.5X297", PageSize{Width: 420.5, Height: 297}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePageSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePageSize(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "B7", "300x", "0x100", "-1x5"} {
+		if _, err := ParsePageSize(in); err == nil {
+			t.Errorf("ParsePageSize(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("SVG")
+	if err != nil || f != FormatSVG || f.Extension() != ".svg" || f.MediaType() != "image/svg+xml" {
+		t.Errorf("ParseFormat(SVG) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("dwg"); err == nil {
+		t.Error("ParseFormat(dwg) succeeded, want error")
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.RGBA
+	}{
+		{"#ff0000", color.RGBA{R: 0xff, A: 0xff}},
+		{"0f0", color.RGBA{G: 0xff, A: 0xff}},
+		{"#0000ff80", color.RGBA{B: 0x80, A: 0x80}},
+		{"transparent", color.RGBA{}},
+		{"White", color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+	}
+	for _, tt := range tests {
+		c, err := ParseColor(tt.in)
+		if err != nil {
+			t.Errorf("ParseColor(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got := color.RGBAModel.Convert(c).(color.RGBA); got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"#12345", "red", "#gg0000"} {
+		if _, err := ParseColor(in); err == nil {
+			t.Errorf("ParseColor(%q) succeeded, want error", in)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePlotScale parses a plot scale and returns the ratio of paper length to model length.
+//
+// Two notations are accepted:
+//   - a ratio "paper:model", e.g. "1:50" or "2:1"
+//   - two lengths "paper=model", e.g. `1/4"=1'-0"`, `1"=20'` or "1mm=1m"
+//
+// Lengths may use feet ('), inches ("), mm, cm or m. Either both or neither side carries a unit.
+func ParsePlotScale(s string) (float64, error) {
+	var paper, model string
+	var ok bool
+	if paper, model, ok = strings.Cut(s, ":"); !ok {
+		if paper, model, ok = strings.Cut(s, "="); !ok {
+			return 0, fmt.Errorf("invalid plot scale %q: expected paper:model or paper=model", s)
+		}
+	}
+
+	p, pUnit, err := parseLength(paper)
+	if err != nil {
+		return 0, fmt.Errorf("invalid plot scale %q: %w", s, err)
+	}
+	m, mUnit, err := parseLength(model)
+	if err != nil {
+		return 0, fmt.Errorf("invalid plot scale %q: %w", s, err)
+	}
+	if pUnit != mUnit {
+		return 0, fmt.Errorf("invalid plot scale %q: units must be given on both sides or neither", s)
+	}
+	if p <= 0 || m <= 0 {
+		return 0, fmt.Errorf("invalid plot scale %q: lengths must be positive", s)
+	}
+	return p / m, nil
+}
+
+// Metric suffixes, longest first so that "mm" is not mistaken for "m"
+var metricUnits = []struct {
+	suffix string
+	mm     float64
+}{
+	{"mm", 1},
+	{"cm", 10},
+	{"m", 1000},
+}
+
+// parseLength parses a length and returns it in millimeters.
+// hasUnit is false for a bare number, which is returned unchanged.
+func parseLength(s string) (mm float64, hasUnit bool, err error) {
+	s = strings.TrimSpace(s)
+	for _, u := range metricUnits {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			n, err := parseNumber(v)
+			return n * u.mm, true, err
+		}
+	}
+
+	if !strings.ContainsAny(s, `'"`) {
+		n, err := parseNumber(s)
+		return n, false, err
+	}
+
+	// Feet and inches, e.g. 1'-6", 1' 6", 3', 1/4"
+	if feet, rest, ok := strings.Cut(s, "'"); ok {
+		n, err := parseNumber(feet)
+		if err != nil {
+			return 0, true, err
+		}
+		mm = n * 304.8
+		s = strings.TrimLeft(rest, " -")
+	}
+	if s != "" {
+		inches, ok := strings.CutSuffix(s, `"`)
+		if !ok {
+			return 0, true, fmt.Errorf("invalid length %q", s)
+		}
+		n, err := parseNumber(inches)
+		if err != nil {
+			return 0, true, err
+		}
+		mm += n * 25.4
+	}
+	return mm, true, nil
+}
+
+// parseNumber parses a decimal, a fraction ("1/4") or a mixed number ("1-1/2", "1 1/2").
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	whole := 0.0
+	if i := strings.IndexAny(s, " -"); i > 0 && strings.Contains(s[i:], "/") {
+		w, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		whole = w
+		s = strings.TrimSpace(s[i+1:])
+	}
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		d, err := strconv.ParseFloat(strings.TrimSpace(den), 64)
+		if err != nil || d == 0 {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		return whole + n/d, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return whole + v, nil
+}
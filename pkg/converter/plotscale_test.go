@@ -0,0 +1,43 @@
+package converter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePlotScale(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"1:50", 1.0 / 50},
+		{"1:100", 1.0 / 100},
+		{"2:1", 2},
+		{"1=100", 1.0 / 100},
+		{`1/4"=1'-0"`, 1.0 / 48},
+		{`1/8" = 1'-0"`, 1.0 / 96},
+		{`1"=20'`, 1.0 / 240},
+		{`1-1/2"=1'`, 1.5 / 12},
+		{`3"=1' 6"`, 3.0 / 18},
+		{"1mm=1m", 1.0 / 1000},
+		{"1cm=5m", 10.0 / 5000},
+	}
+	for _, tt := range tests {
+		got, err := ParsePlotScale(tt.in)
+		if err != nil {
+			t.Errorf("ParsePlotScale(%q) error = %v", tt.in, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("ParsePlotScale(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsePlotScale_Invalid(t *testing.T) {
+	for _, in := range []string{"", "50", "1:0", "a:b", `1"=100`, "-1:50", `1'6=1'`, "1/0:1"} {
+		if _, err := ParsePlotScale(in); err == nil {
+			t.Errorf("ParsePlotScale(%q) expected error, got nil", in)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/daidai-ok/dxfconv/pkg/boundingbox"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
+// ThumbnailMode selects how the drawing is fitted into the thumbnail box
+type ThumbnailMode string
+
+const (
+	// ThumbnailFit shows the whole drawing centred in the box, padding the
+	// remaining space with the background.
+	ThumbnailFit ThumbnailMode = "fit"
+	// ThumbnailFill covers the whole box with the drawing, cutting off the
+	// parts that overflow it.
+	ThumbnailFill ThumbnailMode = "fill"
+	// ThumbnailCrop shows the whole drawing like ThumbnailFit, but crops the
+	// image to the drawing so that one side may be shorter than the box.
+	ThumbnailCrop ThumbnailMode = "crop"
+)
+
+// DefaultMinTextHeight is the height in pixels below which thumbnails omit text
+const DefaultMinTextHeight = 4
+
+// ThumbnailOptions configures Thumbnail
+type ThumbnailOptions struct {
+	// Width and Height of the thumbnail box in pixels
+	Width, Height int
+	// Mode defaults to ThumbnailFit
+	Mode ThumbnailMode
+	// Padding in pixels kept free around the drawing
+	Padding int
+	// Background of the image. Nil selects white.
+	Background color.Color
+	// MinTextHeight omits text smaller than this many pixels.
+	// Zero selects DefaultMinTextHeight; a negative value draws all text.
+	MinTextHeight float64
+	// Input holds the parse options and the limits of the DXF data and of
+	// the drawing: Lenient, Workers, MaxInputBytes, MaxEntities,
+	// MaxBlockDepth, MaxVertices and MaxPixels. Nil parses strictly with only
+	// the default MaxPixels.
+	Input *Options
+}
+
+// Thumbnail reads DXF data from r and writes a PNG preview of at most
+// opts.Width x opts.Height pixels to w. Lines are at least one pixel wide and
+// entities smaller than a pixel are skipped.
+func Thumbnail(r io.Reader, w io.Writer, opts ThumbnailOptions) error {
+	_, err := ThumbnailContext(context.Background(), r, w, opts)
+	return err
+}
+
+// ThumbnailContext is like Thumbnail but stops with the context's error when
+// ctx is done, while parsing or drawing. Drawings exceeding the limits of
+// opts.Input fail with a *dxfconverror.LimitError. The Result lists the
+// warnings of lenient parsing.
+func ThumbnailContext(ctx context.Context, r io.Reader, w io.Writer, opts ThumbnailOptions) (*Result, error) {
+	if opts.Width <= 0 {
+		return nil, &dxfconverror.OptionError{Option: "Width", Err: fmt.Errorf("thumbnail width must be positive, got %d", opts.Width)}
+	}
+	if opts.Height <= 0 {
+		return nil, &dxfconverror.OptionError{Option: "Height", Err: fmt.Errorf("thumbnail height must be positive, got %d", opts.Height)}
+	}
+	if 2*opts.Padding >= min(opts.Width, opts.Height) {
+		return nil, &dxfconverror.OptionError{Option: "Padding", Err: fmt.Errorf("padding %d leaves no room in %dx%d", opts.Padding, opts.Width, opts.Height)}
+	}
+	mode := opts.Mode
+	switch mode {
+	case "":
+		mode = ThumbnailFit
+	case ThumbnailFit, ThumbnailFill, ThumbnailCrop:
+	default:
+		return nil, &dxfconverror.OptionError{Option: "Mode", Err: fmt.Errorf("unknown thumbnail mode %q", mode)}
+	}
+	input := opts.Input
+	if input == nil {
+		input = DefaultOptions()
+	}
+	if err := checkLimits(input); err != nil {
+		return nil, err
+	}
+	maxPixels := input.MaxPixels
+	if maxPixels == 0 {
+		maxPixels = renderers.DefaultPNGMaxPixels
+	}
+	if int64(opts.Width)*int64(opts.Height) > maxPixels {
+		return nil, &dxfconverror.LimitError{Limit: "MaxPixels", Max: maxPixels}
+	}
+	minText := opts.MinTextHeight
+	if minText == 0 {
+		minText = DefaultMinTextHeight
+	}
+
+	dxfDrawing, err := parse(ctx, r, input)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := newLayerSet(dxfDrawing, DefaultOptions())
+	if err != nil {
+		return nil, err
+	}
+	entities := make([]dxf.Entity, 0, len(dxfDrawing.Entities))
+	for _, e := range dxfDrawing.Entities {
+		if layers.Visible(e.Layer()) {
+			entities = append(entities, e)
+		}
+	}
+	bb := calculateBoundingBox(entities)
+	if bb.IsEmpty() {
+		if min, max, ok := dxfDrawing.Header.Extents(); ok {
+			bb.Update(min[0], min[1])
+			bb.Update(max[0], max[1])
+		}
+	}
+
+	// The renderer works at one pixel per page unit
+	pageW, pageH := float64(opts.Width), float64(opts.Height)
+	pad := float64(opts.Padding)
+	availW, availH := pageW-2*pad, pageH-2*pad
+
+	scale := 1.0
+	if !bb.IsEmpty() {
+		scaleX, scaleY := scale, scale
+		if bb.Width() > 0 {
+			scaleX = availW / bb.Width()
+		}
+		if bb.Height() > 0 {
+			scaleY = availH / bb.Height()
+		}
+		switch {
+		case bb.Width() == 0:
+			scale = scaleY
+		case bb.Height() == 0:
+			scale = scaleX
+		case mode == ThumbnailFill:
+			scale = math.Max(scaleX, scaleY)
+		default:
+			scale = math.Min(scaleX, scaleY)
+		}
+		if mode == ThumbnailCrop {
+			pageW = math.Min(pageW, math.Ceil(bb.Width()*scale)+2*pad)
+			pageH = math.Min(pageH, math.Ceil(bb.Height()*scale)+2*pad)
+			availW, availH = pageW-2*pad, pageH-2*pad
+		}
+	}
+
+	offsetX := -bb.MinX*scale + pad + (availW-bb.Width()*scale)/2
+	offsetY := -bb.MinY*scale + pad + (availH-bb.Height()*scale)/2
+	dc := &renderers.DrawContext{
+		Scale:      scale,
+		OffsetX:    offsetX,
+		OffsetY:    offsetY,
+		Height:     pageH,
+		PointStyle: pointStyle(&dxfDrawing.Header, scale, availH),
+	}
+
+	var drawn []dxf.Entity
+	if !bb.IsEmpty() {
+		for i, e := range entities {
+			if i%cancelCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			extent := boundingbox.NewBoundingBox()
+			addToBoundingBox(extent, e)
+			if thumbnailVisible(e, extent, dc, pageW, pageH, minText) {
+				drawn = append(drawn, e)
+			}
+		}
+	}
+	if err := checkVertices(ctx, dc, drawn, input.MaxVertices); err != nil {
+		return nil, err
+	}
+
+	renderer := renderers.NewPNGRenderer(w, pageW, pageH)
+	renderer.DPI = 25.4
+	renderer.MinLineWidth = 1
+	renderer.MaxPixels = input.MaxPixels
+	if opts.Background != nil {
+		renderer.Background = opts.Background
+	}
+	renderer.Init(pageW, pageH)
+	for i, e := range drawn {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		renderer.SetStyle(layers.Style(e, scale))
+		dc.Draw(renderer, e)
+	}
+
+	if err := renderer.Finish(); err != nil {
+		return nil, &dxfconverror.RenderingError{Err: err}
+	}
+	return &Result{Warnings: dxfDrawing.Diagnostics}, nil
+}
+
+// thumbnailVisible reports whether the entity with the given extent in drawing
+// units shows up on a pageW x pageH pixel thumbnail.
+func thumbnailVisible(e dxf.Entity, extent *boundingbox.BoundingBox, ctx *renderers.DrawContext, pageW, pageH, minText float64) bool {
+	switch e := e.(type) {
+	case *dxf.Text:
+		// The extent of text is only its insertion point, judge it by its height
+		return e.Height*ctx.Scale >= minText
+	case *dxf.MText:
+		return e.Height*ctx.Scale >= minText
+	case *dxf.Point:
+		// Points are drawn as symbols of the point style size
+		return true
+	}
+	if extent.IsEmpty() {
+		return false
+	}
+
+	// Cull entities smaller than a pixel
+	w, h := extent.Width()*ctx.Scale, extent.Height()*ctx.Scale
+	if w < 1 && h < 1 {
+		return false
+	}
+
+	// Cull entities outside the image, e.g. cut off by ThumbnailFill
+	x0 := extent.MinX*ctx.Scale + ctx.OffsetX
+	y0 := ctx.Height - (extent.MaxY*ctx.Scale + ctx.OffsetY)
+	return x0 < pageW+1 && x0+w > -1 && y0 < pageH+1 && y0+h > -1
+}
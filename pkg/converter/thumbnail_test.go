@@ -0,0 +1,182 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+// thumbnailDXF holds a 10 x 5 rectangle, a text of height 2 and a tiny line
+const thumbnailDXF = `0
+SECTION
+2
+ENTITIES
+0
+LWPOLYLINE
+8
+0
+90
+4
+70
+1
+10
+0
+20
+0
+10
+10
+20
+0
+10
+10
+20
+5
+10
+0
+20
+5
+0
+TEXT
+8
+0
+10
+1
+20
+1
+40
+2
+1
+Label
+0
+LINE
+8
+0
+10
+8
+20
+3
+11
+8.01
+21
+3.01
+0
+ENDSEC
+0
+EOF
+`
+
+func thumbnail(t *testing.T, opts ThumbnailOptions) image.Image {
+	t.Helper()
+	var w bytes.Buffer
+	if err := Thumbnail(strings.NewReader(thumbnailDXF), &w, opts); err != nil {
+		t.Fatalf("Thumbnail failed: %v", err)
+	}
+	img, err := png.Decode(&w)
+	if err != nil {
+		t.Fatalf("Expected PNG output: %v", err)
+	}
+	return img
+}
+
+// inked counts the pixels of r that are not white
+func inked(img image.Image, r image.Rectangle) int {
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if c := color.GrayModel.Convert(img.At(x, y)).(color.Gray); c.Y < 0xf0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestThumbnail_Modes(t *testing.T) {
+	tests := []struct {
+		mode          ThumbnailMode
+		width, height int
+	}{
+		{ThumbnailFit, 100, 100},
+		{ThumbnailFill, 100, 100},
+		// 10 x 5 drawing scaled by 10
+		{ThumbnailCrop, 100, 50},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			img := thumbnail(t, ThumbnailOptions{Width: 100, Height: 100, Mode: tt.mode})
+			if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+				t.Errorf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.width, tt.height)
+			}
+		})
+	}
+}
+
+func TestThumbnail_Fit(t *testing.T) {
+	img := thumbnail(t, ThumbnailOptions{Width: 100, Height: 100})
+	// The rectangle spans rows 25 to 75, the rest is background
+	if n := inked(img, image.Rect(0, 0, 100, 20)); n != 0 {
+		t.Errorf("%d inked pixels above the drawing", n)
+	}
+	if n := inked(img, image.Rect(0, 24, 100, 27)); n == 0 {
+		t.Error("top edge of the rectangle not drawn")
+	}
+}
+
+func TestThumbnail_Fill(t *testing.T) {
+	img := thumbnail(t, ThumbnailOptions{Width: 100, Height: 100, Mode: ThumbnailFill})
+	// Scaled by 20, the rectangle's top and bottom edges are the first and
+	// last rows while its left and right edges fall outside the image.
+	if n := inked(img, image.Rect(0, 0, 100, 1)); n < 90 {
+		t.Errorf("top row has %d inked pixels, want the full edge", n)
+	}
+	if n := inked(img, image.Rect(0, 10, 100, 11)); n > 5 {
+		t.Errorf("row 10 has %d inked pixels, want the side edges cut off", n)
+	}
+}
+
+func TestThumbnail_TextSize(t *testing.T) {
+	// At scale 10 the text is 20 pixels high, with its baseline on row 65
+	area := image.Rect(10, 40, 60, 70)
+	img := thumbnail(t, ThumbnailOptions{Width: 100, Height: 100})
+	if n := inked(img, area); n == 0 {
+		t.Error("readable text not drawn")
+	}
+	img = thumbnail(t, ThumbnailOptions{Width: 100, Height: 100, MinTextHeight: 30})
+	if n := inked(img, area); n != 0 {
+		t.Errorf("text below MinTextHeight drawn with %d pixels", n)
+	}
+}
+
+func TestThumbnail_Cull(t *testing.T) {
+	img := thumbnail(t, ThumbnailOptions{Width: 100, Height: 100})
+	// The LINE at (8, 3) is a tenth of a pixel long
+	if n := inked(img, image.Rect(75, 40, 85, 50)); n != 0 {
+		t.Errorf("sub-pixel line drawn with %d pixels", n)
+	}
+}
+
+func TestThumbnail_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		opts   ThumbnailOptions
+		option string
+	}{
+		{ThumbnailOptions{Height: 10}, "Width"},
+		{ThumbnailOptions{Width: 10}, "Height"},
+		{ThumbnailOptions{Width: 10, Height: 10, Padding: 5}, "Padding"},
+		{ThumbnailOptions{Width: 10, Height: 10, Mode: "stretch"}, "Mode"},
+	}
+	for _, tt := range tests {
+		err := Thumbnail(strings.NewReader(thumbnailDXF), io.Discard, tt.opts)
+		var optErr *dxfconverror.OptionError
+		if !errors.As(err, &optErr) || optErr.Option != tt.option {
+			t.Errorf("Thumbnail(%+v) error = %v, want OptionError for %s", tt.opts, err, tt.option)
+		}
+	}
+}
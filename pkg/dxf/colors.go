@@ -0,0 +1,77 @@
+package dxf
+
+import "image/color"
+
+// aciPalette holds the RGB values of the 256 AutoCAD Color Index entries.
+var aciPalette = buildACIPalette()
+
+// ACIColor returns the RGB value of an AutoCAD Color Index (0-255).
+// Negative indices (used by layers that are off) are treated as their absolute value,
+// out of range indices as 7 (white/foreground).
+func ACIColor(index int) color.RGBA {
+	if index < 0 {
+		index = -index
+	}
+	if index > 255 {
+		index = 7
+	}
+	return aciPalette[index]
+}
+
+func buildACIPalette() [256]color.RGBA {
+	var p [256]color.RGBA
+	standard := [...]uint32{
+		0x000000, 0xff0000, 0xffff00, 0x00ff00, 0x00ffff,
+		0x0000ff, 0xff00ff, 0xffffff, 0x414141, 0x808080,
+	}
+	for i, c := range standard {
+		p[i] = rgb(c)
+	}
+
+	// Indices 10-249 cycle through 24 hues in steps of 15 degrees. Within a hue,
+	// even indices are fully saturated and odd ones pastel, each pair darker than the last.
+	levels := [...]uint32{255, 189, 129, 104, 79}
+	for i := 10; i < 250; i++ {
+		r, g, b := hueRGB(float64(i/10-1) * 15)
+		if i%2 == 1 {
+			r, g, b = 170+r/3, 170+g/3, 170+b/3
+		}
+		level := levels[i%10/2]
+		p[i] = color.RGBA{
+			R: uint8(r * level / 255),
+			G: uint8(g * level / 255),
+			B: uint8(b * level / 255),
+			A: 0xff,
+		}
+	}
+
+	grays := [...]uint32{0x333333, 0x505050, 0x696969, 0x828282, 0xbebebe, 0xffffff}
+	for i, c := range grays {
+		p[250+i] = rgb(c)
+	}
+	return p
+}
+
+func rgb(c uint32) color.RGBA {
+	return color.RGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xff}
+}
+
+// hueRGB returns the fully saturated colour of a hue in degrees.
+func hueRGB(h float64) (r, g, b uint32) {
+	sector := int(h / 60)
+	f := uint32((h - float64(sector)*60) / 60 * 255)
+	switch sector {
+	case 0:
+		return 255, f, 0
+	case 1:
+		return 255 - f, 255, 0
+	case 2:
+		return 0, 255, f
+	case 3:
+		return 0, 255 - f, 255
+	case 4:
+		return f, 0, 255
+	default:
+		return 255, 0, 255 - f
+	}
+}
@@ -0,0 +1,30 @@
+package dxf
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestACIColor(t *testing.T) {
+	tests := []struct {
+		index int
+		want  color.RGBA
+	}{
+		{1, color.RGBA{255, 0, 0, 255}},
+		{5, color.RGBA{0, 0, 255, 255}},
+		{7, color.RGBA{255, 255, 255, 255}},
+		{-1, color.RGBA{255, 0, 0, 255}},
+		{10, color.RGBA{255, 0, 0, 255}},
+		{11, color.RGBA{255, 170, 170, 255}},
+		{12, color.RGBA{189, 0, 0, 255}},
+		{30, color.RGBA{255, 127, 0, 255}},
+		{90, color.RGBA{0, 255, 0, 255}},
+		{250, color.RGBA{51, 51, 51, 255}},
+		{300, color.RGBA{255, 255, 255, 255}},
+	}
+	for _, tt := range tests {
+		if got := ACIColor(tt.index); got != tt.want {
+			t.Errorf("ACIColor(%d) = %v, want %v", tt.index, got, tt.want)
+		}
+	}
+}
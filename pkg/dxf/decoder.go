@@ -0,0 +1,173 @@
+package dxf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+// Decoder reads the entities of a DXF file one at a time, so that large
+// drawings can be processed without holding all entities in memory.
+//
+// The header and tables, which precede the entities in DXF files, are read
+// as the decoder reaches the ENTITIES section and are available from Drawing.
+type Decoder struct {
+	s       *Scanner
+	st      *parseState
+	drawing *Drawing
+	// inEntities is set while reading the ENTITIES section
+	inEntities bool
+	// err is the sticky result of Next once it failed or reached the end
+	err error
+}
+
+// NewDecoder returns a Decoder reading r.
+func NewDecoder(r io.Reader) *Decoder {
+	return NewDecoderContext(context.Background(), r, ParseOptions{})
+}
+
+// NewDecoderContext returns a Decoder reading r with the given options, which
+// stops with the context's error when ctx is done.
+func NewDecoderContext(ctx context.Context, r io.Reader, opts ParseOptions) *Decoder {
+	s := NewScanner(r)
+	s.ctx = ctx
+	s.rawNumbers = true
+	s.lenient = opts.Lenient
+	return &Decoder{
+		s:       s,
+		st:      &parseState{opts: opts, blocks: make(map[string][]string)},
+		drawing: &Drawing{},
+	}
+}
+
+// Next returns the next entity. At the end of the file it returns io.EOF;
+// errors are those of ParseContext. Once Next failed, it keeps returning the
+// same error.
+func (dec *Decoder) Next() (Entity, error) {
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	e, err := dec.next()
+	if err != nil {
+		dec.err = err
+	}
+	return e, err
+}
+
+func (dec *Decoder) next() (Entity, error) {
+	for {
+		if !dec.inEntities {
+			if err := dec.advance(); err != nil {
+				return nil, err
+			}
+		}
+		e, err := nextEntity(dec.s, dec.st)
+		if err != nil {
+			return nil, dec.finish(err)
+		}
+		if e != nil {
+			return e, nil
+		}
+		dec.inEntities = false
+		dec.st.section = ""
+	}
+}
+
+// advance reads the sections up to the next ENTITIES section. At the end of
+// the file it returns the result of finish.
+func (dec *Decoder) advance() error {
+	s, st := dec.s, dec.st
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code == 0 && tag.Value == "SECTION" {
+			entities, err := parseSection(s, dec.drawing, st)
+			if err != nil {
+				return dec.finish(err)
+			}
+			if entities {
+				dec.inEntities = true
+				return nil
+			}
+			st.section = ""
+		} else if tag.Code == 0 && tag.Value == "EOF" {
+			break
+		}
+	}
+	return dec.finish(nil)
+}
+
+// readAll reads the whole drawing, parsing the entities with
+// ParseOptions.Workers goroutines
+func (dec *Decoder) readAll() (*Drawing, error) {
+	var entities []Entity
+	for {
+		err := dec.advance()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		more, err := parseEntitiesParallel(dec.s, dec.st)
+		entities = append(entities, more...)
+		if err != nil {
+			if err := dec.finish(err); err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		dec.inEntities = false
+		dec.st.section = ""
+	}
+	sortDiagnostics(dec.s.diagnostics)
+	d := dec.Drawing()
+	d.Entities = entities
+	return d, nil
+}
+
+// finish ends decoding with err, or at the end of the file. It returns the
+// error to report, io.EOF if there is none.
+func (dec *Decoder) finish(err error) error {
+	if err == nil {
+		err = dec.s.Err
+	}
+	if err != nil {
+		if !dec.st.opts.Lenient || !errors.Is(err, io.ErrUnexpectedEOF) {
+			return dec.s.annotate(err, dec.st.section, "", "")
+		}
+		// Keep what was read before the end of a truncated file
+		dec.s.report(dxfconverror.SeverityWarning, dec.s.Line, "", "", "unexpected end of file")
+	}
+	if err := dec.st.checkBlockDepth(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// Entities returns an iterator over the remaining entities. An error ends
+// the iteration after it is yielded; the end of the file is not an error.
+func (dec *Decoder) Entities() iter.Seq2[Entity, error] {
+	return func(yield func(Entity, error) bool) {
+		for {
+			e, err := dec.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(e, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Drawing returns the drawing read so far, without entities. The sections
+// following the ENTITIES section, if any, are only included once Next
+// returned io.EOF. Diagnostics holds the problems skipped so far in lenient
+// mode.
+func (dec *Decoder) Drawing() *Drawing {
+	dec.drawing.Diagnostics = dec.s.diagnostics
+	return dec.drawing
+}
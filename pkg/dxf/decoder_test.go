@@ -0,0 +1,104 @@
+package dxf
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+func TestDecoder_Next(t *testing.T) {
+	f, err := os.Open("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	dec := NewDecoder(f)
+	var n int
+	for {
+		e, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if n == 0 && len(dec.Drawing().Layers) == 0 {
+			t.Error("Expected the layer table to be read before the first entity")
+		}
+		if e == nil {
+			t.Fatal("Next returned a nil entity")
+		}
+		n++
+	}
+	if n != 6 {
+		t.Errorf("Expected 6 entities, got %d", n)
+	}
+	if d := dec.Drawing(); d.Entities != nil {
+		t.Errorf("Expected Drawing without entities, got %d", len(d.Entities))
+	}
+	if _, err := dec.Next(); err != io.EOF {
+		t.Errorf("Next after the end = %v, want io.EOF", err)
+	}
+}
+
+func TestDecoder_Entities(t *testing.T) {
+	f, err := os.Open("../../fixtures/layers.dxf")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	// Stop after two entities; the decoder continues where the loop stopped
+	dec := NewDecoder(f)
+	var before int
+	for _, err := range dec.Entities() {
+		if err != nil {
+			t.Fatalf("Entities failed: %v", err)
+		}
+		if before++; before == 2 {
+			break
+		}
+	}
+	var after int
+	for _, err := range dec.Entities() {
+		if err != nil {
+			t.Fatalf("Entities failed: %v", err)
+		}
+		after++
+	}
+
+	f.Seek(0, io.SeekStart)
+	d, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if before+after != len(d.Entities) || after == 0 {
+		t.Errorf("Iterated %d + %d entities, want %d in total", before, after, len(d.Entities))
+	}
+}
+
+func TestDecoder_Error(t *testing.T) {
+	f, err := os.Open("../../fixtures/broken.dxf")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	dec := NewDecoder(f)
+	var errs int
+	for _, err := range dec.Entities() {
+		if _, ok := dxfconverror.AsParseError(err); !ok {
+			t.Errorf("Expected ParseError, got %v", err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("Expected one error, got %d", errs)
+	}
+	if _, err := dec.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next after an error = %v, want the error", err)
+	}
+}
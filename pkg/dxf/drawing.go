@@ -1,6 +1,14 @@
 package dxf
 
+import "github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+
 // Drawing represents a parsed DXF drawing.
 type Drawing struct {
-	Entities []Entity
+	Header    Header
+	Layers    []Layer
+	Linetypes []Linetype
+	Views     []View
+	Entities  []Entity
+	// Diagnostics lists the problems skipped by lenient parsing
+	Diagnostics []dxfconverror.Diagnostic `json:"-"`
 }
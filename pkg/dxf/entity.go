@@ -15,16 +15,46 @@ const (
 	MTextType      EntityType = "MTEXT"
 )
 
+// Special values of the common entity properties.
+const (
+	ColorByBlock = 0
+	ColorByLayer = 256
+
+	LineWeightByLayer = -1
+	LineWeightByBlock = -2
+	LineWeightDefault = -3
+)
+
 // Entity is the interface that all DXF entities implement.
 type Entity interface {
 	Type() EntityType
 	Layer() string
+	// Common returns the properties shared by all entities.
+	Common() *BaseEntity
 }
 
 // BaseEntity contains common properties for all entities.
 type BaseEntity struct {
 	EntityType EntityType
 	LayerName  string
+	Handle     string
+	// Color is an ACI colour number, or ColorByLayer / ColorByBlock.
+	Color int
+	// LineType is the linetype name. Empty means BYLAYER.
+	LineType string
+	// LineWeight is in 1/100 mm, or one of the LineWeight constants.
+	LineWeight    int
+	LineTypeScale float64
+}
+
+// newBase returns a BaseEntity with the DXF defaults for omitted properties.
+func newBase(t EntityType) BaseEntity {
+	return BaseEntity{
+		EntityType:    t,
+		Color:         ColorByLayer,
+		LineWeight:    LineWeightByLayer,
+		LineTypeScale: 1,
+	}
 }
 
 func (e *BaseEntity) Type() EntityType {
@@ -35,6 +65,10 @@ func (e *BaseEntity) Layer() string {
 	return e.LayerName
 }
 
+func (e *BaseEntity) Common() *BaseEntity {
+	return e
+}
+
 // Line represents a LINE entity.
 type Line struct {
 	BaseEntity
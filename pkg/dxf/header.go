@@ -0,0 +1,178 @@
+package dxf
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Header holds the variables of the HEADER section.
+// Every variable is kept in Variables keyed by its name (e.g. "$INSUNITS");
+// well-known variables are additionally exposed through typed accessors.
+type Header struct {
+	Variables map[string][]Tag
+}
+
+// Uninitialized extents are written by AutoCAD as +/-1e20.
+const extentsSentinel = 1e20
+
+// Has reports whether the header defines the named variable.
+func (h *Header) Has(name string) bool {
+	_, ok := h.Variables[name]
+	return ok
+}
+
+// String returns the first value of the named variable.
+func (h *Header) String(name string) (string, bool) {
+	tags := h.Variables[name]
+	if len(tags) == 0 {
+		return "", false
+	}
+	return tags[0].Value, true
+}
+
+// Int returns the first value of the named variable as an int.
+func (h *Header) Int(name string) (int, bool) {
+	s, ok := h.String(name)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// Float returns the first value of the named variable as a float64.
+func (h *Header) Float(name string) (float64, bool) {
+	s, ok := h.String(name)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// Point returns the named variable as a point built from its 10/20/30 group codes.
+func (h *Header) Point(name string) ([3]float64, bool) {
+	var p [3]float64
+	tags := h.Variables[name]
+	found := false
+	for _, t := range tags {
+		if t.Code < 10 || t.Code > 39 || t.Code%10 != 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(t.Value, 64)
+		if err != nil {
+			return p, false
+		}
+		p[t.Code/10-1] = v
+		found = true
+	}
+	return p, found
+}
+
+// Version returns $ACADVER, e.g. "AC1018".
+func (h *Header) Version() string {
+	v, _ := h.String("$ACADVER")
+	return v
+}
+
+// InsUnits returns the $INSUNITS code (0 = unitless, 4 = millimeters, ...).
+func (h *Header) InsUnits() int {
+	v, _ := h.Int("$INSUNITS")
+	return v
+}
+
+// Measurement returns $MEASUREMENT (0 = imperial, 1 = metric).
+func (h *Header) Measurement() int {
+	v, _ := h.Int("$MEASUREMENT")
+	return v
+}
+
+// Extents returns $EXTMIN and $EXTMAX.
+// ok is false when they are missing or still hold the uninitialized values.
+func (h *Header) Extents() (min, max [3]float64, ok bool) {
+	return h.box("$EXTMIN", "$EXTMAX")
+}
+
+// Limits returns $LIMMIN and $LIMMAX.
+func (h *Header) Limits() (min, max [3]float64, ok bool) {
+	return h.box("$LIMMIN", "$LIMMAX")
+}
+
+func (h *Header) box(minName, maxName string) (min, max [3]float64, ok bool) {
+	min, okMin := h.Point(minName)
+	max, okMax := h.Point(maxName)
+	if !okMin || !okMax {
+		return min, max, false
+	}
+	if min[0] >= extentsSentinel || max[0] <= -extentsSentinel {
+		return min, max, false
+	}
+	if min[0] > max[0] || min[1] > max[1] {
+		return min, max, false
+	}
+	return min, max, true
+}
+
+// LTScale returns $LTSCALE, defaulting to 1.
+func (h *Header) LTScale() float64 {
+	v, ok := h.Float("$LTSCALE")
+	if !ok || v <= 0 {
+		return 1
+	}
+	return v
+}
+
+// PDMode returns $PDMODE, the point display mode.
+func (h *Header) PDMode() int {
+	v, _ := h.Int("$PDMODE")
+	return v
+}
+
+// PDSize returns $PDSIZE, the point display size.
+// 0 means 5% of the drawing area height, a negative value a percentage of the viewport.
+func (h *Header) PDSize() float64 {
+	v, _ := h.Float("$PDSIZE")
+	return v
+}
+
+// TextSize returns $TEXTSIZE, the default text height.
+func (h *Header) TextSize() float64 {
+	v, _ := h.Float("$TEXTSIZE")
+	return v
+}
+
+// Created returns $TDUCREATE, or the local $TDCREATE if missing, the time the
+// drawing was created.
+func (h *Header) Created() (time.Time, bool) {
+	return h.date("$TDUCREATE", "$TDCREATE")
+}
+
+// Updated returns $TDUUPDATE, or the local $TDUPDATE if missing, the time the
+// drawing was last saved.
+func (h *Header) Updated() (time.Time, bool) {
+	return h.date("$TDUUPDATE", "$TDUPDATE")
+}
+
+// Julian date of the Unix epoch
+const julianUnixEpoch = 2440587.5
+
+// date returns the first of the named Julian dates, to the second in UTC
+func (h *Header) date(names ...string) (time.Time, bool) {
+	for _, name := range names {
+		jd, ok := h.Float(name)
+		// Dates before the epoch are unset
+		if !ok || jd <= julianUnixEpoch {
+			continue
+		}
+		secs := math.Round((jd - julianUnixEpoch) * 86400)
+		return time.Unix(int64(secs), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
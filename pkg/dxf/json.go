@@ -0,0 +1,81 @@
+package dxf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteJSON writes the drawing as JSON. Every field of the entity model is
+// kept, so ReadJSON returns an identical drawing. JSON has no NaN or
+// infinities: the parser rejects them, and WriteJSON fails on a drawing
+// built with them.
+func WriteJSON(w io.Writer, d *Drawing) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// ReadJSON reads a drawing written by WriteJSON.
+func ReadJSON(r io.Reader) (*Drawing, error) {
+	d := &Drawing{}
+	if err := json.NewDecoder(r).Decode(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// UnmarshalJSON decodes a drawing, creating the entities from their EntityType.
+func (d *Drawing) UnmarshalJSON(data []byte) error {
+	// The alias drops this method; Entities is shadowed to decode it by hand
+	type drawing Drawing
+	var v struct {
+		drawing
+		Entities []json.RawMessage
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*d = Drawing(v.drawing)
+	d.Entities = nil
+	for i, raw := range v.Entities {
+		var base struct{ EntityType EntityType }
+		if err := json.Unmarshal(raw, &base); err != nil {
+			return fmt.Errorf("entity %d: %w", i, err)
+		}
+		e := newEntity(base.EntityType)
+		if e == nil {
+			return fmt.Errorf("entity %d: unknown entity type %q", i, base.EntityType)
+		}
+		if err := json.Unmarshal(raw, e); err != nil {
+			return fmt.Errorf("entity %d (%s): %w", i, base.EntityType, err)
+		}
+		d.Entities = append(d.Entities, e)
+	}
+	return nil
+}
+
+// newEntity returns an empty entity of the given type, or nil for unknown types.
+func newEntity(t EntityType) Entity {
+	switch t {
+	case LineType:
+		return &Line{}
+	case CircleType:
+		return &Circle{}
+	case ArcType:
+		return &Arc{}
+	case LwPolylineType:
+		return &LwPolyline{}
+	case PolylineType:
+		return &Polyline{}
+	case SplineType:
+		return &Spline{}
+	case PointType:
+		return &Point{}
+	case TextType:
+		return &Text{}
+	case MTextType:
+		return &MText{}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package dxf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSON_RoundTrip(t *testing.T) {
+	for _, name := range []string{"overall.dxf", "layers.dxf", "header.dxf", "polylines.dxf", "mtext.dxf"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open("../../fixtures/" + name)
+			if err != nil {
+				t.Fatalf("Failed to open fixture: %v", err)
+			}
+			defer f.Close()
+			d, err := Parse(f)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := WriteJSON(&buf, d); err != nil {
+				t.Fatalf("WriteJSON failed: %v", err)
+			}
+			got, err := ReadJSON(&buf)
+			if err != nil {
+				t.Fatalf("ReadJSON failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, d) {
+				t.Errorf("round trip changed the drawing:\ngot  %+v\nwant %+v", got, d)
+			}
+		})
+	}
+}
+
+func TestJSON_NonFinite(t *testing.T) {
+	for _, v := range []string{"NaN", "Inf", "-Infinity"} {
+		// A point with a non-finite coordinate, followed by a valid one
+		input := "0\nSECTION\n2\nENTITIES\n" +
+			"0\nPOINT\n5\n1A\n10\n" + v + "\n20\n0\n" +
+			"0\nPOINT\n5\n2B\n10\n1\n20\n2\n0\nENDSEC\n0\nEOF\n"
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse() accepted %s", v)
+		}
+		d, err := ParseContext(context.Background(), strings.NewReader(input), ParseOptions{Lenient: true})
+		if err != nil {
+			t.Fatalf("ParseContext() error = %v", err)
+		}
+		if len(d.Entities) != 1 || d.Entities[0].Common().Handle != "2B" {
+			t.Errorf("%s: expected only POINT 2B, got %v", v, d.Entities)
+		}
+		// Diagnostics are not part of the JSON
+		d.Diagnostics = nil
+
+		var buf bytes.Buffer
+		if err := WriteJSON(&buf, d); err != nil {
+			t.Fatalf("%s: WriteJSON failed: %v", v, err)
+		}
+		got, err := ReadJSON(&buf)
+		if err != nil {
+			t.Fatalf("%s: ReadJSON failed: %v", v, err)
+		}
+		if !reflect.DeepEqual(got, d) {
+			t.Errorf("%s: round trip changed the drawing:\ngot  %+v\nwant %+v", v, got, d)
+		}
+	}
+
+	// A drawing built with NaN is refused rather than written lossily
+	d := &Drawing{Entities: []Entity{&Point{BaseEntity: newBase(PointType), Coord: [3]float64{math.NaN()}}}}
+	if err := WriteJSON(io.Discard, d); err == nil {
+		t.Error("WriteJSON() accepted NaN")
+	}
+}
+
+func TestReadJSON_UnknownEntity(t *testing.T) {
+	_, err := ReadJSON(strings.NewReader(`{"Entities": [{"EntityType": "HATCH"}]}`))
+	if err == nil || !strings.Contains(err.Error(), "HATCH") {
+		t.Errorf("ReadJSON() error = %v, want unknown entity type", err)
+	}
+}
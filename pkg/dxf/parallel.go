@@ -0,0 +1,258 @@
+package dxf
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+// Number of entities handed to a parsing worker at a time
+const parseBatchSize = 256
+
+// entityRecord holds the tags of an entity, from its 0 group code up to the
+// next entity. The record of a POLYLINE includes its VERTEX and SEQEND.
+type entityRecord struct {
+	tags []Tag
+	// offsets holds the byte offsets of the tags
+	offsets []int64
+}
+
+// handle returns the handle of the entity, if it has been read
+func (r *entityRecord) handle() string {
+	for _, t := range r.tags {
+		if t.Code == 5 {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// line restores the numbered input line from the tags, for excerpts. Group
+// codes lose their padding, and the offsets of value lines are not known.
+func (r *entityRecord) line(n int) (int64, []byte, bool) {
+	i := sort.Search(len(r.tags), func(i int) bool { return r.tags[i].Line+1 >= n })
+	if i == len(r.tags) {
+		return 0, nil, false
+	}
+	switch t := r.tags[i]; n {
+	case t.Line:
+		return r.offsets[i], strconv.AppendInt(nil, int64(t.Code), 10), true
+	case t.Line + 1:
+		return 0, []byte(t.Text()), true
+	}
+	return 0, nil, false
+}
+
+// recordResult is the outcome of parsing an entityRecord
+type recordResult struct {
+	entity      Entity
+	diagnostics []dxfconverror.Diagnostic
+	err         error
+}
+
+// parseRecord parses an entity as nextEntity does, replaying its tags on s
+func parseRecord(s *Scanner, rec *entityRecord, lenient bool) recordResult {
+	last := rec.tags[len(rec.tags)-1]
+	*s = Scanner{Line: last.Line + 1, lenient: lenient, record: rec, replay: rec.tags[1:]}
+	typ := rec.tags[0].Value
+	entity, err := parseEntity(typ, s)
+	if err != nil {
+		var handle string
+		if entity != nil {
+			handle = entity.Common().Handle
+		}
+		if err := s.skipMalformed(err, typ, handle); err != nil {
+			return recordResult{err: s.annotate(err, "ENTITIES", typ, handle)}
+		}
+		return recordResult{diagnostics: s.diagnostics}
+	}
+	return recordResult{entity: entity, diagnostics: s.diagnostics}
+}
+
+// entityBatch is a run of records parsed by one worker
+type entityBatch struct {
+	records []*entityRecord
+	results chan []recordResult
+}
+
+// parseEntitiesParallel reads the ENTITIES section like repeated calls of
+// nextEntity. The scanner splits the section into entity records, whose
+// values are parsed by st.opts.Workers goroutines; the entities are returned
+// in the order of the file. A read error is returned with the entities
+// preceding it.
+func parseEntitiesParallel(s *Scanner, st *parseState) ([]Entity, error) {
+	workers := st.opts.Workers
+	jobs := make(chan *entityBatch)
+	defer close(jobs)
+	for range workers {
+		go func() {
+			var rs Scanner
+			for b := range jobs {
+				results := make([]recordResult, len(b.records))
+				for i, rec := range b.records {
+					results[i] = parseRecord(&rs, rec, s.lenient)
+				}
+				b.results <- results
+			}
+		}()
+	}
+
+	var (
+		entities []Entity
+		pending  []*entityBatch
+		records  []*entityRecord
+		// The tags of the records being collected, and of the record being
+		// read from index start, if start is not negative. values holds the
+		// numeric values of the tags.
+		tags    []Tag
+		offsets []int64
+		values  []byte
+		start   = -1
+	)
+	// collect adds the entities of the oldest pending batch
+	collect := func() error {
+		b := pending[0]
+		pending = pending[1:]
+		for _, res := range <-b.results {
+			s.diagnostics = append(s.diagnostics, res.diagnostics...)
+			if res.err != nil {
+				return res.err
+			}
+			if res.entity == nil {
+				continue
+			}
+			if limit := st.opts.MaxEntities; limit > 0 && st.entities >= limit {
+				return &dxfconverror.LimitError{Limit: "MaxEntities", Max: int64(limit)}
+			}
+			st.entities++
+			entities = append(entities, res.entity)
+		}
+		return nil
+	}
+	// send hands the collected records to a worker, keeping a bounded
+	// number of batches in flight
+	send := func() error {
+		if len(records) == 0 {
+			return nil
+		}
+		b := &entityBatch{records: records, results: make(chan []recordResult, 1)}
+		records = nil
+		tags, offsets = make([]Tag, 0, cap(tags)), make([]int64, 0, cap(offsets))
+		values = make([]byte, 0, cap(values))
+		pending = append(pending, b)
+		jobs <- b
+		if len(pending) > 2*workers {
+			return collect()
+		}
+		return nil
+	}
+	// add adds the tag just read to the record being read, if any
+	add := func() {
+		if start < 0 {
+			return
+		}
+		t := *s.NextTag
+		if t.raw != nil {
+			// The scanner reuses the buffer of the value
+			n := len(values)
+			values = append(values, t.raw...)
+			t.raw = values[n:len(values):len(values)]
+		}
+		tags = append(tags, t)
+		offsets = append(offsets, s.tagOffset)
+	}
+	// begin starts a record with the tag just read
+	begin := func() {
+		start = len(tags)
+		add()
+	}
+	// current returns the record being read
+	current := func() *entityRecord {
+		end := len(tags)
+		return &entityRecord{tags: tags[start:end:end], offsets: offsets[start:end:end]}
+	}
+	// complete finishes the record being read
+	complete := func() error {
+		if start < 0 {
+			return nil
+		}
+		rec := current()
+		start = -1
+		if rec.tags[0].Value == "INSERT" {
+			// Only the name of the inserted block is used, as by skipInsert
+			var name string
+			for _, t := range rec.tags {
+				if t.Code == 2 {
+					name = t.Value
+				}
+			}
+			st.inserts = append(st.inserts, name)
+			return nil
+		}
+		records = append(records, rec)
+		if len(records) == parseBatchSize {
+			return send()
+		}
+		return nil
+	}
+	// finish parses what is left and returns the entities. A read error err
+	// is returned, with the entities before it, unless an earlier entity is
+	// malformed.
+	finish := func(err error) ([]Entity, error) {
+		if serr := send(); serr != nil {
+			return nil, serr
+		}
+		for len(pending) > 0 {
+			if cerr := collect(); cerr != nil {
+				return nil, cerr
+			}
+		}
+		return entities, err
+	}
+
+	var inPolyline bool
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code != 0 {
+			add()
+			continue
+		}
+		if tag.Value == "ENDSEC" {
+			if err := complete(); err != nil {
+				return nil, err
+			}
+			return finish(nil)
+		}
+		if inPolyline && (tag.Value == "VERTEX" || tag.Value == "SEQEND") {
+			inPolyline = tag.Value == "VERTEX"
+			add()
+			continue
+		}
+		if err := complete(); err != nil {
+			return nil, err
+		}
+		begin()
+		inPolyline = tag.Value == "POLYLINE"
+	}
+	if s.Err != nil {
+		// The entity being read is incomplete
+		var typ, handle string
+		if start >= 0 {
+			rec := current()
+			typ, handle = rec.tags[0].Value, rec.handle()
+		}
+		return finish(s.annotate(s.Err, "ENTITIES", typ, handle))
+	}
+	if err := complete(); err != nil {
+		return nil, err
+	}
+	return finish(nil)
+}
+
+// sortDiagnostics orders diagnostics by line, as they are reported by
+// sequential parsing
+func sortDiagnostics(d []dxfconverror.Diagnostic) {
+	slices.SortStableFunc(d, func(a, b dxfconverror.Diagnostic) int { return a.Line - b.Line })
+}
@@ -0,0 +1,96 @@
+package dxf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// largeDXF returns a drawing of n groups of entities. Every bad-th LINE has a
+// malformed coordinate if bad is positive.
+func largeDXF(n, bad int) string {
+	var b strings.Builder
+	b.WriteString("0\nSECTION\n2\nBLOCKS\n0\nBLOCK\n2\nB\n0\nLINE\n10\n0\n20\n0\n11\n1\n21\n1\n0\nENDBLK\n0\nENDSEC\n")
+	b.WriteString("0\nSECTION\n2\nENTITIES\n")
+	for i := 0; i < n; i++ {
+		x := "1.5"
+		if bad > 0 && i%bad == bad-1 {
+			x = "oops"
+		}
+		fmt.Fprintf(&b, "0\nLINE\n5\nL%d\n8\nWALLS\n10\n%d\n20\n%s\n11\n%d.25\n21\n7\n", i, i, x, i)
+		fmt.Fprintf(&b, "0\nCIRCLE\n5\nC%d\n8\n0\n10\n%d\n20\n2\n40\n0.5\n", i, i)
+		fmt.Fprintf(&b, "0\nPOLYLINE\n8\n0\n66\n1\n70\n1\n0\nVERTEX\n10\n%d\n20\n0\n0\nVERTEX\n10\n%d\n20\n3\n0\nSEQEND\n", i, i+1)
+		b.WriteString("0\nINSERT\n2\nB\n10\n0\n20\n0\n")
+		fmt.Fprintf(&b, "0\nTEXT\n8\nTEXT\n10\n%d\n20\n4\n40\n2\n1\nT%d\n", i, i)
+	}
+	b.WriteString("0\nENDSEC\n0\nEOF\n")
+	return b.String()
+}
+
+func parseWorkers(t *testing.T, input string, opts ParseOptions) (*Drawing, error) {
+	t.Helper()
+	return ParseContext(context.Background(), strings.NewReader(input), opts)
+}
+
+func TestParseContext_Workers(t *testing.T) {
+	truncated := largeDXF(300, 0)
+	// End the file after a group code
+	truncated = truncated[:strings.LastIndex(truncated[:20000], "\n10\n")+4]
+	inputs := map[string]string{
+		"large":      largeDXF(500, 0),
+		"large bad":  largeDXF(500, 97),
+		"truncated":  truncated,
+		"entity cap": largeDXF(300, 0),
+	}
+	files, err := filepath.Glob("../../fixtures/*.dxf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[filepath.Base(f)] = string(data)
+	}
+
+	for name, input := range inputs {
+		for _, lenient := range []bool{false, true} {
+			opts := ParseOptions{Lenient: lenient}
+			if name == "entity cap" {
+				opts.MaxEntities = 1000
+			}
+			want, wantErr := parseWorkers(t, input, opts)
+			opts.Workers = 4
+			got, gotErr := parseWorkers(t, input, opts)
+			if fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+				t.Errorf("%s (lenient %v): error = %v, want %v", name, lenient, gotErr, wantErr)
+				continue
+			}
+			if wantErr != nil {
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s (lenient %v): parallel drawing differs from sequential", name, lenient)
+			}
+		}
+	}
+}
+
+func BenchmarkParseContext(b *testing.B) {
+	input := largeDXF(20000, 0)
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for b.Loop() {
+				if _, err := ParseContext(context.Background(), strings.NewReader(input), ParseOptions{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,49 +1,100 @@
 package dxf
 
 import (
+	"context"
 	"io"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
 )
 
 // Reference: https://help.autodesk.com/view/OARX/2021/ENU/?guid=GUID-235B22E0-A567-4CF6-92D3-38A2306D73F3
 
+// ParseOptions configures ParseContext. Zero limits mean no limit.
+type ParseOptions struct {
+	// MaxEntities is the maximum number of entities in the ENTITIES section
+	MaxEntities int
+	// MaxBlockDepth is the maximum nesting depth of block references: an
+	// INSERT of a block counts 1, an INSERT of a block that inserts another
+	// block 2, and so on. Recursive blocks always exceed the limit.
+	MaxBlockDepth int
+	// Lenient skips entities and table records with malformed values, lines
+	// that are not group codes and a truncated end of file instead of failing.
+	// The problems are listed in Drawing.Diagnostics.
+	Lenient bool
+	// Workers is the number of goroutines parsing the values of entities
+	// while ParseContext reads the file. Values below 2 parse sequentially.
+	// Decoders always parse sequentially.
+	Workers int
+}
+
+// parseState holds what the parser tracks across sections
+type parseState struct {
+	opts ParseOptions
+	// blocks maps each block name to the names of the blocks it inserts
+	blocks map[string][]string
+	// inserts lists the blocks inserted by the ENTITIES section
+	inserts []string
+	// section is the name of the section being read
+	section string
+	// entities counts the entities read
+	entities int
+}
+
 // Parse reads a DXF file and returns a Drawing.
 func Parse(r io.Reader) (*Drawing, error) {
-	scanner := NewScanner(r)
-	drawing := &Drawing{}
+	return ParseContext(context.Background(), r, ParseOptions{})
+}
 
-	for scanner.Scan() {
-		tag := scanner.NextTag
-		if tag.Code == 0 && tag.Value == "SECTION" {
-			if err := parseSection(scanner, drawing); err != nil {
-				return nil, err
-			}
-		} else if tag.Code == 0 && tag.Value == "EOF" {
+// ParseContext reads a DXF file and returns a Drawing. It stops with the
+// context's error when ctx is done, and with a *dxfconverror.LimitError when
+// the drawing exceeds the limits of opts.
+func ParseContext(ctx context.Context, r io.Reader, opts ParseOptions) (*Drawing, error) {
+	dec := NewDecoderContext(ctx, r, opts)
+	if opts.Workers > 1 {
+		return dec.readAll()
+	}
+	var entities []Entity
+	for {
+		e, err := dec.Next()
+		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, e)
 	}
-
-	if scanner.Err != nil {
-		return nil, scanner.Err
-	}
-
+	drawing := dec.Drawing()
+	drawing.Entities = entities
 	return drawing, nil
 }
 
-func parseSection(s *Scanner, d *Drawing) error {
+// parseSection reads a section up to its ENDSEC, except for the ENTITIES
+// section, whose entities are left to nextEntity. It reports whether the
+// section is the ENTITIES section.
+func parseSection(s *Scanner, d *Drawing, st *parseState) (bool, error) {
 	for s.Scan() {
 		tag := s.NextTag
 		if tag.Code == 2 {
-			if tag.Value == "ENTITIES" {
-				return parseEntities(s, d)
+			st.section = tag.Value
+			switch tag.Value {
+			case "HEADER":
+				return false, parseHeader(s, d)
+			case "TABLES":
+				return false, parseTables(s, d)
+			case "BLOCKS":
+				return false, parseBlocks(s, st)
+			case "ENTITIES":
+				return true, nil
 			}
 			// Skip other sections
-			return skipSection(s)
+			return false, skipSection(s)
 		}
 		if tag.Code == 0 && tag.Value == "ENDSEC" {
-			return nil
+			return false, nil
 		}
 	}
-	return nil
+	return false, nil
 }
 
 func skipSection(s *Scanner) error {
@@ -55,25 +106,156 @@ func skipSection(s *Scanner) error {
 	return s.Err
 }
 
-func parseEntities(s *Scanner, d *Drawing) error {
+func parseHeader(s *Scanner, d *Drawing) error {
+	d.Header.Variables = make(map[string][]Tag)
+	var name string
 	for s.Scan() {
 		tag := s.NextTag
-		if tag.Code == 0 {
-			if tag.Value == "ENDSEC" {
-				return nil
-			}
-			entity, err := parseEntity(tag.Value, s)
+		if tag.Code == 0 && tag.Value == "ENDSEC" {
+			return nil
+		}
+		if tag.Code == 9 {
+			name = tag.Value
+			d.Header.Variables[name] = nil
+			continue
+		}
+		if name != "" {
+			d.Header.Variables[name] = append(d.Header.Variables[name], tag.keep())
+		}
+	}
+	return s.Err
+}
+
+// nextEntity returns the next entity of the ENTITIES section, or nil at the
+// end of the section. INSERTs are recorded for the block depth limit.
+func nextEntity(s *Scanner, st *parseState) (Entity, error) {
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code != 0 {
+			continue
+		}
+		if tag.Value == "ENDSEC" {
+			return nil, nil
+		}
+		if tag.Value == "INSERT" {
+			name, err := skipInsert(s)
 			if err != nil {
-				return err
+				return nil, err
 			}
+			st.inserts = append(st.inserts, name)
+			continue
+		}
+		// The scanner reuses tag for the values of the entity
+		typ := tag.Value
+		entity, err := parseEntity(typ, s)
+		if err != nil {
+			var handle string
 			if entity != nil {
-				d.Entities = append(d.Entities, entity)
+				handle = entity.Common().Handle
+			}
+			if err := s.skipMalformed(err, typ, handle); err != nil {
+				return nil, s.annotate(err, "ENTITIES", typ, handle)
+			}
+			continue
+		}
+		if entity != nil {
+			if limit := st.opts.MaxEntities; limit > 0 && st.entities >= limit {
+				return nil, &dxfconverror.LimitError{Limit: "MaxEntities", Max: int64(limit)}
+			}
+			st.entities++
+			return entity, nil
+		}
+	}
+	return nil, s.Err
+}
+
+// parseBlocks records the block references of the BLOCKS section. The block
+// definitions themselves are not drawn.
+func parseBlocks(s *Scanner, st *parseState) error {
+	var block string
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code != 0 {
+			continue
+		}
+		switch tag.Value {
+		case "ENDSEC":
+			return nil
+		case "BLOCK":
+			for s.Scan() {
+				if s.NextTag.Code == 0 {
+					s.PushBack()
+					break
+				}
+				if s.NextTag.Code == 2 {
+					block = s.NextTag.Value
+				}
 			}
+		case "INSERT":
+			name, err := skipInsert(s)
+			if err != nil {
+				return err
+			}
+			st.blocks[block] = append(st.blocks[block], name)
 		}
 	}
 	return s.Err
 }
 
+// skipInsert skips an INSERT entity and returns the name of the inserted block
+func skipInsert(s *Scanner) (string, error) {
+	var name string
+	for s.Scan() {
+		if s.NextTag.Code == 0 {
+			s.PushBack()
+			return name, nil
+		}
+		if s.NextTag.Code == 2 {
+			name = s.NextTag.Value
+		}
+	}
+	return name, s.Err
+}
+
+// checkBlockDepth returns a LimitError if the inserts of the ENTITIES section
+// nest deeper than MaxBlockDepth
+func (st *parseState) checkBlockDepth() error {
+	limit := st.opts.MaxBlockDepth
+	if limit <= 0 {
+		return nil
+	}
+	// depths caches the nesting depth of each block; -1 marks the blocks on
+	// the current path, so that recursion exceeds the limit
+	depths := make(map[string]int)
+	var fits func(name string, level int) bool
+	fits = func(name string, level int) bool {
+		if level > limit {
+			return false
+		}
+		if d, ok := depths[name]; ok {
+			return d > 0 && level+d-1 <= limit
+		}
+		depths[name] = -1
+		deepest := 1
+		for _, child := range st.blocks[name] {
+			if !fits(child, level+1) {
+				return false
+			}
+			deepest = max(deepest, 1+depths[child])
+		}
+		depths[name] = deepest
+		return true
+	}
+	for _, name := range st.inserts {
+		if !fits(name, 1) {
+			return &dxfconverror.LimitError{Limit: "MaxBlockDepth", Max: int64(limit)}
+		}
+	}
+	return nil
+}
+
+// parseEntity parses an entity of the given type. On a malformed value the
+// partly parsed entity is returned with the error, for diagnostics.
 func parseEntity(typeStr string, s *Scanner) (Entity, error) {
 	var e Entity
 	var err error
@@ -114,29 +296,49 @@ func skipEntity(s *Scanner) error {
 	return s.Err
 }
 
-func parseCommon(s *Scanner, e *BaseEntity) bool {
+// parseCommon reads the group codes shared by all entities. It reports
+// whether the current tag was one, and the error of a malformed value.
+func parseCommon(s *Scanner, e *BaseEntity) (bool, error) {
 	tag := s.NextTag
-	if tag.Code == 8 {
+	var err error
+	switch tag.Code {
+	case 5:
+		e.Handle = tag.Value
+	case 6:
+		e.LineType = tag.Value
+	case 8:
 		e.LayerName = tag.Value
-		return true
+	case 48:
+		e.LineTypeScale, err = tag.Float()
+	case 62:
+		e.Color, err = tag.Int()
+	case 370:
+		e.LineWeight, err = tag.Int()
+	case 100, 102, 330, 360:
+		// Subclass markers, application groups and owner handles carry no geometry
+	default:
+		return false, nil
 	}
-	return false
+	return true, err
 }
 
 func parseLine(s *Scanner) (*Line, error) {
-	l := &Line{BaseEntity: BaseEntity{EntityType: LineType}}
+	l := &Line{BaseEntity: newBase(LineType)}
 	for s.Scan() {
 		tag := s.NextTag
 		if tag.Code == 0 {
 			s.PushBack()
 			return l, nil
 		}
-		if parseCommon(s, &l.BaseEntity) {
+		if ok, err := parseCommon(s, &l.BaseEntity); ok {
+			if err != nil {
+				return l, err
+			}
 			continue
 		}
 		val, err := tag.Float()
 		if err != nil {
-			return nil, err
+			return l, err
 		}
 		switch tag.Code {
 		case 10:
@@ -153,19 +355,22 @@ func parseLine(s *Scanner) (*Line, error) {
 }
 
 func parseCircle(s *Scanner) (*Circle, error) {
-	c := &Circle{BaseEntity: BaseEntity{EntityType: CircleType}}
+	c := &Circle{BaseEntity: newBase(CircleType)}
 	for s.Scan() {
 		tag := s.NextTag
 		if tag.Code == 0 {
 			s.PushBack()
 			return c, nil
 		}
-		if parseCommon(s, &c.BaseEntity) {
+		if ok, err := parseCommon(s, &c.BaseEntity); ok {
+			if err != nil {
+				return c, err
+			}
 			continue
 		}
 		val, err := tag.Float()
 		if err != nil {
-			return nil, err
+			return c, err
 		}
 		switch tag.Code {
 		case 10:
@@ -180,19 +385,22 @@ func parseCircle(s *Scanner) (*Circle, error) {
 }
 
 func parseArc(s *Scanner) (*Arc, error) {
-	a := &Arc{BaseEntity: BaseEntity{EntityType: ArcType}}
+	a := &Arc{BaseEntity: newBase(ArcType)}
 	for s.Scan() {
 		tag := s.NextTag
 		if tag.Code == 0 {
 			s.PushBack()
 			return a, nil
 		}
-		if parseCommon(s, &a.BaseEntity) {
+		if ok, err := parseCommon(s, &a.BaseEntity); ok {
+			if err != nil {
+				return a, err
+			}
 			continue
 		}
 		val, err := tag.Float()
 		if err != nil {
-			return nil, err
+			return a, err
 		}
 		switch tag.Code {
 		case 10:
@@ -211,7 +419,7 @@ func parseArc(s *Scanner) (*Arc, error) {
 }
 
 func parseLwPolyline(s *Scanner) (*LwPolyline, error) {
-	l := &LwPolyline{BaseEntity: BaseEntity{EntityType: LwPolylineType}}
+	l := &LwPolyline{BaseEntity: newBase(LwPolylineType)}
 	var currentVertex *LwPolylineVertex
 
 	// Helper to commit current vertex
@@ -229,7 +437,10 @@ func parseLwPolyline(s *Scanner) (*LwPolyline, error) {
 			s.PushBack()
 			return l, nil
 		}
-		if parseCommon(s, &l.BaseEntity) {
+		if ok, err := parseCommon(s, &l.BaseEntity); ok {
+			if err != nil {
+				return l, err
+			}
 			continue
 		}
 
@@ -237,7 +448,7 @@ func parseLwPolyline(s *Scanner) (*LwPolyline, error) {
 			commitVertex()
 			val, err := tag.Float()
 			if err != nil {
-				return nil, err
+				return l, err
 			}
 			currentVertex = &LwPolylineVertex{X: val}
 			continue
@@ -254,7 +465,7 @@ func parseLwPolyline(s *Scanner) (*LwPolyline, error) {
 		if currentVertex != nil {
 			val, err := tag.Float()
 			if err != nil {
-				return nil, err
+				return l, err
 			}
 			switch tag.Code {
 			case 20:
@@ -269,7 +480,7 @@ func parseLwPolyline(s *Scanner) (*LwPolyline, error) {
 }
 
 func parsePolyline(s *Scanner) (*Polyline, error) {
-	p := &Polyline{BaseEntity: BaseEntity{EntityType: PolylineType}}
+	p := &Polyline{BaseEntity: newBase(PolylineType)}
 	// FLAGS: 70
 	for s.Scan() {
 		tag := s.NextTag
@@ -277,7 +488,10 @@ func parsePolyline(s *Scanner) (*Polyline, error) {
 			s.PushBack()
 			break
 		}
-		if parseCommon(s, &p.BaseEntity) {
+		if ok, err := parseCommon(s, &p.BaseEntity); ok {
+			if err != nil {
+				return p, err
+			}
 			continue
 		}
 		if tag.Code == 70 {
@@ -302,7 +516,7 @@ func parsePolyline(s *Scanner) (*Polyline, error) {
 			} else if tag.Value == "VERTEX" {
 				v, err := parseVertex(s)
 				if err != nil {
-					return nil, err
+					return p, err
 				}
 				p.Vertices = append(p.Vertices, *v)
 			} else {
@@ -342,7 +556,7 @@ func parseVertex(s *Scanner) (*Vertex, error) {
 }
 
 func parseSpline(s *Scanner) (*Spline, error) {
-	sp := &Spline{BaseEntity: BaseEntity{EntityType: SplineType}}
+	sp := &Spline{BaseEntity: newBase(SplineType)}
 	var currentControl *[3]float64
 
 	commitControl := func() {
@@ -359,7 +573,10 @@ func parseSpline(s *Scanner) (*Spline, error) {
 			s.PushBack()
 			return sp, nil
 		}
-		if parseCommon(s, &sp.BaseEntity) {
+		if ok, err := parseCommon(s, &sp.BaseEntity); ok {
+			if err != nil {
+				return sp, err
+			}
 			continue
 		}
 
@@ -368,7 +585,7 @@ func parseSpline(s *Scanner) (*Spline, error) {
 			commitControl()
 			val, err := tag.Float()
 			if err != nil {
-				return nil, err
+				return sp, err
 			}
 			currentControl = &[3]float64{val, 0, 0}
 			continue
@@ -405,19 +622,22 @@ func parseSpline(s *Scanner) (*Spline, error) {
 }
 
 func parsePoint(s *Scanner) (*Point, error) {
-	p := &Point{BaseEntity: BaseEntity{EntityType: PointType}}
+	p := &Point{BaseEntity: newBase(PointType)}
 	for s.Scan() {
 		tag := s.NextTag
 		if tag.Code == 0 {
 			s.PushBack()
 			return p, nil
 		}
-		if parseCommon(s, &p.BaseEntity) {
+		if ok, err := parseCommon(s, &p.BaseEntity); ok {
+			if err != nil {
+				return p, err
+			}
 			continue
 		}
 		val, err := tag.Float()
 		if err != nil {
-			return nil, err
+			return p, err
 		}
 		switch tag.Code {
 		case 10:
@@ -432,14 +652,17 @@ func parsePoint(s *Scanner) (*Point, error) {
 }
 
 func parseText(s *Scanner) (*Text, error) {
-	t := &Text{BaseEntity: BaseEntity{EntityType: TextType}}
+	t := &Text{BaseEntity: newBase(TextType)}
 	for s.Scan() {
 		tag := s.NextTag
 		if tag.Code == 0 {
 			s.PushBack()
 			return t, nil
 		}
-		if parseCommon(s, &t.BaseEntity) {
+		if ok, err := parseCommon(s, &t.BaseEntity); ok {
+			if err != nil {
+				return t, err
+			}
 			continue
 		}
 
@@ -467,7 +690,7 @@ func parseText(s *Scanner) (*Text, error) {
 }
 
 func parseMText(s *Scanner) (*MText, error) {
-	t := &MText{BaseEntity: BaseEntity{EntityType: MTextType}}
+	t := &MText{BaseEntity: newBase(MTextType)}
 	var textBuf string // MText can be split across multiple code 1/3 tags
 
 	for s.Scan() {
@@ -477,7 +700,10 @@ func parseMText(s *Scanner) (*MText, error) {
 			t.Value = textBuf
 			return t, nil
 		}
-		if parseCommon(s, &t.BaseEntity) {
+		if ok, err := parseCommon(s, &t.BaseEntity); ok {
+			if err != nil {
+				return t, err
+			}
 			continue
 		}
 
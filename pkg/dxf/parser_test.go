@@ -1,8 +1,16 @@
 package dxf
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
 )
 
 func TestParse_Line(t *testing.T) {
@@ -79,3 +87,280 @@ func TestParse_MText_MultiTags(t *testing.T) {
 		t.Errorf("Expected 'Hello World', got '%s'", mtext.Value)
 	}
 }
+
+func TestParse_Header(t *testing.T) {
+	dxfPath := "../../fixtures/header.dxf"
+	f, err := os.Open(dxfPath)
+	if err != nil {
+		t.Fatalf("Failed to open DXF from %s: %v", dxfPath, err)
+	}
+	defer f.Close()
+
+	d, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	h := &d.Header
+	if v := h.Version(); v != "AC1018" {
+		t.Errorf("Expected $ACADVER 'AC1018', got '%s'", v)
+	}
+	if u := h.Units(); u != UnitsMillimeters || u.Millimeters() != 1 {
+		t.Errorf("Expected $INSUNITS millimeters, got %v", u)
+	}
+	if s := h.LTScale(); s != 2.5 {
+		t.Errorf("Expected $LTSCALE 2.5, got %f", s)
+	}
+	if m := h.PDMode(); m != 35 {
+		t.Errorf("Expected $PDMODE 35, got %d", m)
+	}
+	min, max, ok := h.Extents()
+	if !ok {
+		t.Fatal("Expected valid extents")
+	}
+	if min[0] != -10 || min[1] != -20 || max[0] != 90 || max[1] != 80 {
+		t.Errorf("Unexpected extents %v - %v", min, max)
+	}
+	if v, ok := h.String("$CUSTOMVAR"); !ok || v != "custom" {
+		t.Errorf("Expected $CUSTOMVAR 'custom', got '%s'", v)
+	}
+	if len(d.Entities) != 1 {
+		t.Errorf("Expected 1 entity, got %d", len(d.Entities))
+	}
+}
+
+func TestHeader_UninitializedExtents(t *testing.T) {
+	h := Header{Variables: map[string][]Tag{
+		"$EXTMIN": {{Code: 10, Value: "1e+20"}, {Code: 20, Value: "1e+20"}},
+		"$EXTMAX": {{Code: 10, Value: "-1e+20"}, {Code: 20, Value: "-1e+20"}},
+	}}
+	if _, _, ok := h.Extents(); ok {
+		t.Error("Expected uninitialized extents to be reported as invalid")
+	}
+	if s := h.LTScale(); s != 1 {
+		t.Errorf("Expected default $LTSCALE 1, got %f", s)
+	}
+}
+
+func TestHeader_Dates(t *testing.T) {
+	h := Header{Variables: map[string][]Tag{
+		"$TDCREATE":  {{Code: 40, Value: "2460000.75"}},
+		"$TDUCREATE": {{Code: 40, Value: "2460000.5"}},
+		"$TDUPDATE":  {{Code: 40, Value: "2460001.25"}},
+		"$TDUUPDATE": {{Code: 40, Value: "0"}},
+	}}
+	created, ok := h.Created()
+	if want := time.Date(2023, 2, 25, 0, 0, 0, 0, time.UTC); !ok || !created.Equal(want) {
+		t.Errorf("Created() = %v, %v; want %v", created, ok, want)
+	}
+	// An unset universal date falls back to the local one
+	updated, ok := h.Updated()
+	if want := time.Date(2023, 2, 25, 18, 0, 0, 0, time.UTC); !ok || !updated.Equal(want) {
+		t.Errorf("Updated() = %v, %v; want %v", updated, ok, want)
+	}
+	if _, ok := (&Header{}).Created(); ok {
+		t.Error("Created() reported a date for an empty header")
+	}
+}
+
+func TestParse_Views(t *testing.T) {
+	dxfPath := "../../fixtures/view.dxf"
+	f, err := os.Open(dxfPath)
+	if err != nil {
+		t.Fatalf("Failed to open DXF from %s: %v", dxfPath, err)
+	}
+	defer f.Close()
+
+	d, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	v, ok := d.View("DETAIL")
+	if !ok {
+		t.Fatalf("Expected view DETAIL, got %+v", d.Views)
+	}
+	minX, minY, maxX, maxY := v.Bounds()
+	if minX != 0 || minY != 0 || maxX != 50 || maxY != 50 {
+		t.Errorf("Unexpected view bounds (%v, %v) - (%v, %v)", minX, minY, maxX, maxY)
+	}
+	if len(d.Entities) != 2 {
+		t.Errorf("Expected 2 entities, got %d", len(d.Entities))
+	}
+}
+
+func TestParse_Tables(t *testing.T) {
+	dxfPath := "../../fixtures/layers.dxf"
+	f, err := os.Open(dxfPath)
+	if err != nil {
+		t.Fatalf("Failed to open DXF from %s: %v", dxfPath, err)
+	}
+	defer f.Close()
+
+	d, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(d.Layers) != 5 {
+		t.Fatalf("Expected 5 layers, got %d", len(d.Layers))
+	}
+	dim, ok := d.Layer("dim")
+	if !ok {
+		t.Fatal("Expected layer DIM")
+	}
+	if dim.Color != 1 || dim.LineType != "DASHED" || dim.LineWeight != 50 {
+		t.Errorf("Unexpected DIM layer %+v", dim)
+	}
+	if l, _ := d.Layer("HIDDEN"); !l.Off() {
+		t.Error("Expected layer HIDDEN to be off")
+	}
+	if l, _ := d.Layer("FROZEN"); !l.Frozen() {
+		t.Error("Expected layer FROZEN to be frozen")
+	}
+	if l, _ := d.Layer("Construction"); l.Plot {
+		t.Error("Expected layer Construction to be not plottable")
+	}
+
+	lt, ok := d.Linetype("DASHED")
+	if !ok {
+		t.Fatal("Expected linetype DASHED")
+	}
+	if len(lt.Pattern) != 2 || lt.Pattern[0] != 0.5 || lt.Pattern[1] != -0.25 {
+		t.Errorf("Unexpected DASHED pattern %v", lt.Pattern)
+	}
+
+	if len(d.Entities) != 6 {
+		t.Fatalf("Expected 6 entities, got %d", len(d.Entities))
+	}
+	base := d.Entities[1].Common()
+	if base.Handle != "1B" || base.Color != 5 {
+		t.Errorf("Unexpected common properties %+v", base)
+	}
+	if c := d.Entities[0].Common().Color; c != ColorByLayer {
+		t.Errorf("Expected default colour BYLAYER, got %d", c)
+	}
+}
+
+func TestParseContext_RecursiveBlocks(t *testing.T) {
+	// Block A inserts B, which inserts A again
+	input := "0\nSECTION\n2\nBLOCKS\n" +
+		"0\nBLOCK\n2\nA\n0\nINSERT\n2\nB\n0\nENDBLK\n" +
+		"0\nBLOCK\n2\nB\n0\nINSERT\n2\nA\n0\nENDBLK\n" +
+		"0\nENDSEC\n0\nSECTION\n2\nENTITIES\n0\nINSERT\n2\nA\n0\nENDSEC\n0\nEOF\n"
+
+	_, err := ParseContext(context.Background(), strings.NewReader(input), ParseOptions{MaxBlockDepth: 100})
+	var limitErr *dxfconverror.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxBlockDepth" {
+		t.Errorf("ParseContext() error = %v, want LimitError for MaxBlockDepth", err)
+	}
+	// Without a limit the references are not followed
+	if _, err := Parse(strings.NewReader(input)); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestParseContext_Lenient(t *testing.T) {
+	// A circle with a malformed radius, a line that is not a group code and
+	// a file truncated within a POINT
+	input := "0\nSECTION\n2\nENTITIES\n" +
+		"0\nLINE\n5\n1A\n10\n0\n20\n0\n11\n1\n21\n1\n" +
+		"0\nCIRCLE\n5\n2B\n40\nabc\n10\n5\n" +
+		"x\n0\nPOINT\n10\n3\n20\n"
+
+	if _, err := Parse(strings.NewReader(input)); err == nil {
+		t.Fatal("Parse() succeeded on malformed input")
+	}
+	d, err := ParseContext(context.Background(), strings.NewReader(input), ParseOptions{Lenient: true})
+	if err != nil {
+		t.Fatalf("ParseContext() error = %v", err)
+	}
+	if len(d.Entities) != 1 || d.Entities[0].Common().Handle != "1A" {
+		t.Errorf("Expected only LINE 1A, got %v", d.Entities)
+	}
+	want := []dxfconverror.Diagnostic{
+		{Line: 21, EntityType: "CIRCLE", Handle: "2B", Severity: dxfconverror.SeverityError},
+		{Line: 25, Severity: dxfconverror.SeverityWarning},
+		{Line: 30, Severity: dxfconverror.SeverityWarning},
+	}
+	if len(d.Diagnostics) != len(want) {
+		t.Fatalf("Diagnostics = %v, want %d", d.Diagnostics, len(want))
+	}
+	for i, w := range want {
+		got := d.Diagnostics[i]
+		got.Message = ""
+		if got != w {
+			t.Errorf("Diagnostics[%d] = %+v, want %+v", i, d.Diagnostics[i], w)
+		}
+	}
+	if got := d.Diagnostics[0].String(); got != "line 21: error: CIRCLE 2B: invalid value 'abc' for group code 40, CIRCLE skipped" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestParse_MalformedCommon(t *testing.T) {
+	for _, code := range []string{"48", "62", "370"} {
+		input := "0\nSECTION\n2\nENTITIES\n" +
+			"0\nLINE\n5\n1A\n" + code + "\nabc\n10\n0\n20\n0\n11\n1\n21\n1\n" +
+			"0\nPOINT\n5\n2B\n10\n3\n20\n4\n0\nENDSEC\n0\nEOF\n"
+
+		_, err := Parse(strings.NewReader(input))
+		pe, ok := dxfconverror.AsParseError(err)
+		if !ok || pe.Line != 9 || strconv.Itoa(pe.Code) != code || pe.EntityType != "LINE" {
+			t.Errorf("group code %s: Parse() error = %v", code, err)
+		}
+		for _, workers := range []int{1, 2} {
+			d, err := ParseContext(context.Background(), strings.NewReader(input), ParseOptions{Lenient: true, Workers: workers})
+			if err != nil {
+				t.Fatalf("group code %s: ParseContext() error = %v", code, err)
+			}
+			if len(d.Entities) != 1 || d.Entities[0].Common().Handle != "2B" {
+				t.Errorf("group code %s: expected only POINT 2B, got %v", code, d.Entities)
+			}
+			if len(d.Diagnostics) != 1 || d.Diagnostics[0].Line != 9 || d.Diagnostics[0].Handle != "1A" {
+				t.Errorf("group code %s: Diagnostics = %v", code, d.Diagnostics)
+			}
+		}
+	}
+}
+
+func TestParse_ErrorPosition(t *testing.T) {
+	data, err := os.ReadFile("../../fixtures/broken.dxf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Parse(bytes.NewReader(data))
+	pe, ok := dxfconverror.AsParseError(err)
+	if !ok {
+		t.Fatalf("Parse() error = %v, want ParseError", err)
+	}
+	// The offending tag is group code 31 on line 29
+	offset := int64(0)
+	for _, l := range strings.SplitAfter(string(data), "\n")[:28] {
+		offset += int64(len(l))
+	}
+	if pe.Line != 29 || pe.Offset != offset || pe.Code != 31 || pe.Value != "invalid_float_here" ||
+		pe.Section != "ENTITIES" || pe.EntityType != "LINE" {
+		t.Errorf("ParseError = %+v", pe)
+	}
+	if got, want := pe.Location(), "line 29, ENTITIES section, LINE, group code 31"; got != want {
+		t.Errorf("Location() = %q, want %q", got, want)
+	}
+	if !strings.Contains(pe.Excerpt, "> 29 |  31\n> 30 | invalid_float_here\n  31 |   0") {
+		t.Errorf("Excerpt =\n%s", pe.Excerpt)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		line  int
+		code  int
+	}{
+		{"group code", "0\nSECTION\n2\nENTITIES\n0\nPOINT\nten\n1\n", 7, -1},
+		{"truncated", "0\nSECTION\n2\nENTITIES\n0\nCIRCLE\n5\n2B\n40\n", 9, 40},
+	}
+	for _, tt := range tests {
+		_, err := Parse(strings.NewReader(tt.input))
+		pe, ok := dxfconverror.AsParseError(err)
+		if !ok || pe.Line != tt.line || pe.Code != tt.code || pe.Section != "ENTITIES" {
+			t.Errorf("%s: error = %v (%+v)", tt.name, err, pe)
+		}
+	}
+}
@@ -2,10 +2,15 @@ package dxf
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
 )
 
 // Tag represents a single DXF tag (group code and value).
@@ -13,23 +18,80 @@ type Tag struct {
 	Code  int
 	Value string
 	Line  int
+
+	// raw holds the value of numeric group codes as read, instead of Value,
+	// for scanners of the package. It is only valid until the next Scan.
+	raw []byte
 }
 
-// Scanner scans a DXF stream for tags.
+// Scanner scans a DXF stream for tags. NextTag is reused by every Scan.
 type Scanner struct {
-	scanner       *bufio.Scanner
-	Line          int
-	NextTag       *Tag
-	pushedBackTag *Tag
-	Err           error
+	reader     *bufio.Reader
+	Line       int
+	NextTag    *Tag
+	tag        Tag
+	pushedBack bool
+	Err        error
+	readErr    error
+	long       []byte // holds lines longer than the buffer of reader
+	strings    map[string]string
+	// rawNumbers leaves Value empty for numeric group codes, which are read
+	// with Int, Float or Text without allocating
+	rawNumbers bool
+
+	ctx  context.Context
+	tags int // tags read, for periodic cancellation checks
+
+	// lenient skips malformed input, recording it in diagnostics
+	lenient     bool
+	diagnostics []dxfconverror.Diagnostic
+
+	// offset is the byte offset of the input read, lineOffset the offset of
+	// the last line read
+	offset, lineOffset int64
+	// recent holds the positions of the last lines read, indexed by line
+	// number, and history the input around them, for excerpts
+	recent  [16]sourceLine
+	history *history
+	// tagOffset is the byte offset of the last tag read from the input
+	tagOffset int64
+
+	// record is set for scanners that replay the tags of an entity read by
+	// another scanner; replay holds the tags not scanned yet
+	record *entityRecord
+	replay []Tag
 }
 
+// sourceLine is the position of a line of the input: the byte offsets of
+// its start and of the end of its text
+type sourceLine struct {
+	n           int
+	offset, end int64
+}
+
+// How many tags are read between checks of the context
+const cancelCheckInterval = 1024
+
+// Size of the read buffer. Longer lines are collected in Scanner.long.
+const readBufferSize = 64 << 10
+
+// Bytes of input kept for excerpts. Lines starting before are left out.
+const historySize = 4 * readBufferSize
+
+// Number of distinct values kept by a Scanner for reuse, e.g. entity types
+// and layer names
+const maxInterned = 4096
+
 // NewScanner creates a new scanner.
 func NewScanner(r io.Reader) *Scanner {
-	return &Scanner{
-		scanner: bufio.NewScanner(r),
-		Line:    0,
+	h := &history{r: r, buf: make([]byte, historySize)}
+	s := &Scanner{
+		reader:  bufio.NewReaderSize(h, readBufferSize),
+		strings: make(map[string]string),
+		history: h,
 	}
+	s.NextTag = &s.tag
+	return s
 }
 
 // Scan advances to the next tag.
@@ -38,62 +100,366 @@ func (s *Scanner) Scan() bool {
 		return false
 	}
 
-	if s.pushedBackTag != nil {
-		s.NextTag = s.pushedBackTag
-		s.pushedBackTag = nil
+	if s.pushedBack {
+		s.pushedBack = false
 		return true
 	}
 
+	if s.record != nil {
+		if len(s.replay) == 0 {
+			return false
+		}
+		s.NextTag = &s.replay[0]
+		s.replay = s.replay[1:]
+		return true
+	}
+
+	if s.ctx != nil {
+		if s.tags++; s.tags%cancelCheckInterval == 0 {
+			if err := s.ctx.Err(); err != nil {
+				s.Err = err
+				return false
+			}
+		}
+	}
+
 	// Read Code
-	if !s.scanner.Scan() {
-		s.Err = s.scanner.Err()
+	text, ok := s.readLine()
+	if !ok {
+		s.Err = s.readErr
+		return false
+	}
+	codeLine, codeOffset := s.Line, s.lineOffset
+	code, err := parseCode(text)
+	for err != nil && s.lenient {
+		// Resynchronize on the next line that holds a group code
+		s.report(dxfconverror.SeverityWarning, codeLine, "", "", fmt.Sprintf("invalid group code '%s' skipped", bytes.TrimSpace(text)))
+		if text, ok = s.readLine(); !ok {
+			s.Err = s.readErr
+			return false
+		}
+		codeLine, codeOffset = s.Line, s.lineOffset
+		code, err = parseCode(text)
+	}
+	if err != nil {
+		codeStr := string(bytes.TrimSpace(text))
+		s.Err = &dxfconverror.ParseError{
+			Line:  codeLine,
+			Code:  -1,
+			Value: codeStr,
+			Err:   fmt.Errorf("invalid group code '%s': %w", codeStr, err),
+		}
+		// The value line is part of an excerpt
+		s.readLine()
 		return false
 	}
-	s.Line++
-	codeLine := s.Line
-	codeStr := strings.TrimSpace(s.scanner.Text())
 
 	// Read Value
-	if !s.scanner.Scan() {
-		s.Err = s.scanner.Err()
+	if text, ok = s.readLine(); !ok {
+		s.Err = s.readErr
 		if s.Err == nil {
-			s.Err = io.ErrUnexpectedEOF
+			s.Err = &dxfconverror.ParseError{Line: codeLine, Code: code, Err: io.ErrUnexpectedEOF}
 		}
 		return false
 	}
-	s.Line++
 	// We only trim leading whitespace for values to preserve significant trailing spaces (e.g. in Text).
-	// Newline characters are already stripped by Scanner.Scan().
-	valStr := strings.TrimLeft(s.scanner.Text(), " \t")
+	// Line endings are already stripped by readLine.
+	val := bytes.TrimLeft(text, " \t")
 
-	code, err := strconv.Atoi(codeStr)
-	if err != nil {
-		s.Err = fmt.Errorf("line %d: invalid group code '%s': %w", codeLine, codeStr, err)
-		return false
+	s.tag = Tag{Code: code, Line: codeLine}
+	switch {
+	case isNumeric(code) && s.rawNumbers:
+		s.tag.raw = val
+	case isName(code):
+		s.tag.Value = s.intern(val)
+	default:
+		s.tag.Value = string(val)
 	}
-
-	s.NextTag = &Tag{Code: code, Value: valStr, Line: codeLine}
+	s.NextTag = &s.tag
+	s.tagOffset = codeOffset
 	return true
 }
 
+// parseCode parses a group code line
+func parseCode(text []byte) (int, error) {
+	// The conversion does not allocate for short lines
+	return strconv.Atoi(string(bytes.TrimSpace(text)))
+}
+
+// isNumeric reports whether the values of a group code are numbers
+func isNumeric(code int) bool {
+	switch {
+	case code >= 10 && code <= 99,
+		code >= 110 && code <= 149,
+		code >= 160 && code <= 179,
+		code >= 210 && code <= 239,
+		code >= 270 && code <= 299,
+		code >= 370 && code <= 389,
+		code >= 400 && code <= 409,
+		code >= 420 && code <= 429,
+		code >= 440 && code <= 469,
+		code >= 1010 && code <= 1071:
+		return true
+	}
+	return false
+}
+
+// isName reports whether the values of a group code repeat throughout a
+// file, like entity types, names and subclass markers
+func isName(code int) bool {
+	switch code {
+	case 0, 2, 6, 7, 8, 9, 100:
+		return true
+	}
+	return false
+}
+
+// intern returns val as a string, sharing the strings of repeated values
+func (s *Scanner) intern(val []byte) string {
+	if v, ok := s.strings[string(val)]; ok {
+		return v
+	}
+	v := string(val)
+	if len(s.strings) < maxInterned {
+		s.strings[v] = v
+	}
+	return v
+}
+
+// readLine reads the next line of the input, without its line ending. The
+// line is only valid until the next read.
+func (s *Scanner) readLine() ([]byte, bool) {
+	if s.record != nil || s.readErr != nil {
+		return nil, false
+	}
+	line, err := s.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		s.long = append(s.long[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = s.reader.ReadSlice('\n')
+			s.long = append(s.long, line...)
+		}
+		line = s.long
+	}
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		if err != io.EOF {
+			s.readErr = err
+		}
+		return nil, false
+	}
+	s.lineOffset = s.offset
+	s.offset += int64(len(line))
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+
+	s.Line++
+	s.recent[s.Line%len(s.recent)] = sourceLine{n: s.Line, offset: s.lineOffset, end: s.lineOffset + int64(len(line))}
+	return line, true
+}
+
+// PushBack makes the next Scan return the current tag again
 func (s *Scanner) PushBack() {
-	s.pushedBackTag = s.NextTag
+	s.pushedBack = true
+}
+
+// report records a diagnostic of lenient parsing
+func (s *Scanner) report(severity dxfconverror.Severity, line int, entityType, handle, msg string) {
+	s.diagnostics = append(s.diagnostics, dxfconverror.Diagnostic{
+		Line:       line,
+		EntityType: entityType,
+		Handle:     handle,
+		Severity:   severity,
+		Message:    msg,
+	})
+}
+
+// skipMalformed handles err, a malformed value at the current tag of a record
+// such as an entity. In lenient mode the record is reported and skipped up to
+// the next record; otherwise, or if reading failed, err is returned.
+func (s *Scanner) skipMalformed(err error, record, handle string) error {
+	if !s.lenient || s.Err != nil {
+		return err
+	}
+	tag := s.NextTag
+	s.report(dxfconverror.SeverityError, tag.Line, record, handle,
+		fmt.Sprintf("invalid value '%s' for group code %d, %s skipped", tag.Text(), tag.Code, record))
+	return skipEntity(s)
+}
+
+// Text returns the value as a string.
+func (t *Tag) Text() string {
+	if t.raw != nil {
+		return string(t.raw)
+	}
+	return t.Value
 }
 
 // Int returns the value as an int.
 func (t *Tag) Int() (int, error) {
-	v, err := strconv.Atoi(t.Value)
+	var (
+		v   int
+		err error
+	)
+	if t.raw != nil {
+		// The conversion does not allocate for short values
+		v, err = strconv.Atoi(string(t.raw))
+	} else {
+		v, err = strconv.Atoi(t.Value)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("line %d: invalid integer '%s': %w", t.Line+1, t.Value, err)
+		return 0, t.error(fmt.Errorf("invalid integer '%s': %w", t.Text(), err))
 	}
 	return v, nil
 }
 
 // Float returns the value as a float64.
 func (t *Tag) Float() (float64, error) {
-	v, err := strconv.ParseFloat(t.Value, 64)
+	var (
+		v   float64
+		err error
+	)
+	if t.raw != nil {
+		v, err = strconv.ParseFloat(string(t.raw), 64)
+	} else {
+		v, err = strconv.ParseFloat(t.Value, 64)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("line %d: invalid float '%s': %w", t.Line+1, t.Value, err)
+		return 0, t.error(fmt.Errorf("invalid float '%s': %w", t.Text(), err))
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		// ParseFloat accepts "NaN" and "Inf", which no drawing can hold
+		return 0, t.error(fmt.Errorf("invalid float '%s': not finite", t.Text()))
 	}
 	return v, nil
 }
+
+// keep returns a copy of the tag that remains valid after the next Scan,
+// with Value set
+func (t *Tag) keep() Tag {
+	c := *t
+	if c.raw != nil {
+		c.Value, c.raw = string(c.raw), nil
+	}
+	return c
+}
+
+// error returns a ParseError located at the tag
+func (t *Tag) error(err error) error {
+	return &dxfconverror.ParseError{Line: t.Line, Code: t.Code, Value: t.Text(), Err: err}
+}
+
+// Lines of input shown before and after the offending tag of an excerpt
+const excerptContext = 3
+
+// annotate completes the ParseError in err with the section and entity being
+// read, the byte offset and an excerpt of the input. Other errors are
+// returned unchanged.
+func (s *Scanner) annotate(err error, section, entityType, handle string) error {
+	pe, ok := dxfconverror.AsParseError(err)
+	if !ok || !pe.HasPosition() {
+		return err
+	}
+	if pe.Section == "" {
+		pe.Section = section
+	}
+	if pe.EntityType == "" {
+		pe.EntityType, pe.Handle = entityType, handle
+	}
+	if pe.Excerpt == "" {
+		pe.Offset, pe.Excerpt = s.excerpt(pe)
+	}
+	return err
+}
+
+// line returns the byte offset and the text of the numbered line, if it is
+// still known
+func (s *Scanner) line(n int) (int64, []byte, bool) {
+	if s.record != nil {
+		return s.record.line(n)
+	}
+	l := s.recent[n%len(s.recent)]
+	if l.n != n {
+		return 0, nil, false
+	}
+	text, ok := s.history.bytes(l.offset, l.end)
+	return l.offset, text, ok
+}
+
+// excerpt returns the byte offset of the error's line and the input lines
+// around it. It reads ahead, so the scanner cannot be used afterwards.
+func (s *Scanner) excerpt(pe *dxfconverror.ParseError) (int64, string) {
+	// The value line of the tag, unless the group code is malformed
+	tagEnd := pe.Line + 1
+	if pe.Code < 0 {
+		tagEnd = pe.Line
+	}
+	for s.Line < tagEnd+excerptContext {
+		if _, ok := s.readLine(); !ok {
+			break
+		}
+	}
+
+	const maxWidth = 80
+	first := max(pe.Line-excerptContext, 1)
+	last := min(tagEnd+excerptContext, s.Line)
+	width := len(strconv.Itoa(last))
+	var (
+		offset int64
+		b      strings.Builder
+	)
+	for n := first; n <= last; n++ {
+		lineOffset, text, ok := s.line(n)
+		if !ok {
+			continue
+		}
+		mark := ' '
+		if n >= pe.Line && n <= tagEnd {
+			mark = '>'
+		}
+		if n == pe.Line {
+			offset = lineOffset
+		}
+		if len(text) > maxWidth {
+			text = append(bytes.ToValidUTF8(text[:maxWidth-3], nil), "..."...)
+		}
+		fmt.Fprintf(&b, "%c %*d | %s\n", mark, width, n, text)
+	}
+	return offset, strings.TrimSuffix(b.String(), "\n")
+}
+
+// history is a reader that keeps the last bytes read from r in a ring
+// buffer, from which excerpts are rebuilt
+type history struct {
+	r   io.Reader
+	buf []byte
+	n   int64 // bytes read
+}
+
+func (h *history) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	b := p[:n]
+	if len(b) > len(h.buf) {
+		b = b[len(b)-len(h.buf):]
+	}
+	i := int((h.n + int64(n-len(b))) % int64(len(h.buf)))
+	copied := copy(h.buf[i:], b)
+	copy(h.buf, b[copied:])
+	h.n += int64(n)
+	return n, err
+}
+
+// bytes returns a copy of the input from offset start to end, if it is
+// still held
+func (h *history) bytes(start, end int64) ([]byte, bool) {
+	if h == nil || start < h.n-int64(len(h.buf)) || end > h.n {
+		return nil, false
+	}
+	b := make([]byte, 0, end-start)
+	for start < end {
+		i := int(start % int64(len(h.buf)))
+		chunk := h.buf[i:min(len(h.buf), i+int(end-start))]
+		b = append(b, chunk...)
+		start += int64(len(chunk))
+	}
+	return b, true
+}
@@ -0,0 +1,159 @@
+package dxf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+func TestScanner_LineEndings(t *testing.T) {
+	// CRLF line endings, a padded group code and a final line without ending
+	s := NewScanner(strings.NewReader("  0\r\nLINE\r\n 10\r\n1.5\r\n1\r\n  text \r\n8\nWALLS"))
+	want := []struct {
+		code  int
+		value string
+		line  int
+	}{
+		{0, "LINE", 1},
+		{10, "1.5", 3},
+		{1, "text ", 5},
+		{8, "WALLS", 7},
+	}
+	for _, w := range want {
+		if !s.Scan() {
+			t.Fatalf("Scan() = false, err %v", s.Err)
+		}
+		tag := s.NextTag
+		if tag.Code != w.code || tag.Text() != w.value || tag.Line != w.line {
+			t.Errorf("tag = %d %q line %d, want %d %q line %d", tag.Code, tag.Text(), tag.Line, w.code, w.value, w.line)
+		}
+	}
+	if s.Scan() || s.Err != nil {
+		t.Errorf("Scan() at the end = true or err %v", s.Err)
+	}
+}
+
+func TestScanner_LongLine(t *testing.T) {
+	long := strings.Repeat("x", 3*readBufferSize)
+	s := NewScanner(strings.NewReader("0\nMTEXT\n1\n" + long + "\n10\n2\n"))
+	var values []string
+	for s.Scan() {
+		values = append(values, s.NextTag.Text())
+	}
+	if s.Err != nil {
+		t.Fatal(s.Err)
+	}
+	if len(values) != 3 || values[1] != long || values[2] != "2" {
+		t.Errorf("got %d values", len(values))
+	}
+}
+
+func TestScanner_Values(t *testing.T) {
+	s := NewScanner(strings.NewReader("10\n2.5\n70\n  3\n8\nA\n"))
+	s.Scan()
+	if tag := s.NextTag; tag.Value != "2.5" || tag.Text() != "2.5" {
+		t.Errorf("numeric tag Value = %q, Text() = %q", tag.Value, tag.Text())
+	}
+	if v, err := s.NextTag.Float(); err != nil || v != 2.5 {
+		t.Errorf("Float() = %v, %v", v, err)
+	}
+	kept := s.NextTag.keep()
+	s.Scan()
+	if v, err := s.NextTag.Int(); err != nil || v != 3 {
+		t.Errorf("Int() = %v, %v", v, err)
+	}
+	s.PushBack()
+	if !s.Scan() || s.NextTag.Code != 70 {
+		t.Errorf("pushed back tag not returned")
+	}
+	s.Scan()
+	if s.NextTag.Value != "A" {
+		t.Errorf("Value = %q", s.NextTag.Value)
+	}
+	if kept.Value != "2.5" {
+		t.Errorf("kept Value = %q", kept.Value)
+	}
+
+	// Scanners of the package leave Value empty for numeric group codes
+	s = NewScanner(strings.NewReader("10\n2.5\n"))
+	s.rawNumbers = true
+	s.Scan()
+	if tag := s.NextTag; tag.Value != "" || tag.Text() != "2.5" {
+		t.Errorf("raw numeric tag Value = %q, Text() = %q", tag.Value, tag.Text())
+	}
+	if kept := s.NextTag.keep(); kept.Value != "2.5" {
+		t.Errorf("kept Value = %q", kept.Value)
+	}
+}
+
+func TestScanner_Excerpt(t *testing.T) {
+	const tail = "> 5 | 40\n> 6 | abc\n  7 | 0\n  8 | EOF"
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"short", "title", "  2 | MTEXT\n  3 | 1\n  4 | title\n" + tail},
+		{"truncated", strings.Repeat("x", 100), "  2 | MTEXT\n  3 | 1\n  4 | " + strings.Repeat("x", 77) + "...\n" + tail},
+		// The lines up to the long one are no longer held
+		{"beyond history", strings.Repeat("x", historySize+1), tail},
+	}
+	for _, tt := range tests {
+		s := NewScanner(strings.NewReader("0\nMTEXT\n1\n" + tt.text + "\n40\nabc\n0\nEOF\n"))
+		s.rawNumbers = true
+		var err error
+		for err == nil && s.Scan() {
+			if s.NextTag.Code == 40 {
+				_, err = s.NextTag.Float()
+			}
+		}
+		pe, ok := dxfconverror.AsParseError(s.annotate(err, "ENTITIES", "MTEXT", ""))
+		if !ok {
+			t.Fatalf("%s: error = %v", tt.name, err)
+		}
+		if pe.Excerpt != tt.want || pe.Offset != int64(len("0\nMTEXT\n1\n")+len(tt.text)+1) {
+			t.Errorf("%s: offset %d, excerpt =\n%s", tt.name, pe.Offset, pe.Excerpt)
+		}
+	}
+}
+
+func TestScanner_Allocs(t *testing.T) {
+	s := NewScanner(strings.NewReader(polylineTags(1000)))
+	s.rawNumbers = true
+	allocs := testing.AllocsPerRun(500, func() {
+		if !s.Scan() {
+			t.Fatal("Scan() = false")
+		}
+		if _, err := s.NextTag.Float(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Scan() allocates %v times per numeric tag", allocs)
+	}
+}
+
+// polylineTags returns the coordinate tags of n vertices
+func polylineTags(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString("10\n123.456789\n20\n-98.7654321\n")
+	}
+	return b.String()
+}
+
+func BenchmarkScanner(b *testing.B) {
+	input := largeDXF(20000, 0)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		s := NewScanner(strings.NewReader(input))
+		s.rawNumbers = true
+		for s.Scan() {
+		}
+		if s.Err != nil {
+			b.Fatal(s.Err)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package dxf
+
+import (
+	"path"
+	"strings"
+)
+
+// Layer represents an entry of the LAYER table.
+type Layer struct {
+	Name string
+	// Color is the ACI colour number; it is negative when the layer is off.
+	Color      int
+	LineType   string
+	LineWeight int
+	Flags      int
+	// Plot is false for layers marked as not plottable.
+	Plot bool
+}
+
+// Frozen reports whether the layer is frozen.
+func (l *Layer) Frozen() bool {
+	return l.Flags&1 != 0
+}
+
+// Off reports whether the layer is turned off.
+func (l *Layer) Off() bool {
+	return l.Color < 0
+}
+
+// Linetype represents an entry of the LTYPE table.
+type Linetype struct {
+	Name        string
+	Description string
+	// Pattern holds the dash lengths in drawing units:
+	// positive for dashes, negative for gaps and zero for dots.
+	Pattern []float64
+}
+
+// View represents an entry of the VIEW table.
+type View struct {
+	Name string
+	// Center is the view center in display coordinates, relative to Target.
+	Center [2]float64
+	// Direction is the view direction from the target.
+	Direction [3]float64
+	// Target is the target point in WCS.
+	Target [3]float64
+	Height float64
+	Width  float64
+}
+
+// Bounds returns the model space rectangle shown by a plan view.
+func (v *View) Bounds() (minX, minY, maxX, maxY float64) {
+	cx := v.Target[0] + v.Center[0]
+	cy := v.Target[1] + v.Center[1]
+	return cx - v.Width/2, cy - v.Height/2, cx + v.Width/2, cy + v.Height/2
+}
+
+// Layer returns the named LAYER table entry. Names are compared case-insensitively.
+func (d *Drawing) Layer(name string) (*Layer, bool) {
+	for i := range d.Layers {
+		if strings.EqualFold(d.Layers[i].Name, name) {
+			return &d.Layers[i], true
+		}
+	}
+	return nil, false
+}
+
+// Linetype returns the named LTYPE table entry. Names are compared case-insensitively.
+func (d *Drawing) Linetype(name string) (*Linetype, bool) {
+	for i := range d.Linetypes {
+		if strings.EqualFold(d.Linetypes[i].Name, name) {
+			return &d.Linetypes[i], true
+		}
+	}
+	return nil, false
+}
+
+// View returns the named VIEW table entry. Names are compared case-insensitively.
+func (d *Drawing) View(name string) (*View, bool) {
+	for i := range d.Views {
+		if strings.EqualFold(d.Views[i].Name, name) {
+			return &d.Views[i], true
+		}
+	}
+	return nil, false
+}
+
+func parseTables(s *Scanner, d *Drawing) error {
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code != 0 {
+			continue
+		}
+		// The scanner reuses tag for the values of the record
+		record := tag.Value
+		switch record {
+		case "ENDSEC":
+			return nil
+		case "LAYER":
+			l, err := parseLayer(s)
+			if err != nil {
+				if err := s.skipMalformed(err, record, ""); err != nil {
+					return s.annotate(err, "TABLES", record, "")
+				}
+				continue
+			}
+			d.Layers = append(d.Layers, *l)
+		case "LTYPE":
+			lt, err := parseLinetype(s)
+			if err != nil {
+				if err := s.skipMalformed(err, record, ""); err != nil {
+					return s.annotate(err, "TABLES", record, "")
+				}
+				continue
+			}
+			d.Linetypes = append(d.Linetypes, *lt)
+		case "VIEW":
+			v, err := parseView(s)
+			if err != nil {
+				if err := s.skipMalformed(err, record, ""); err != nil {
+					return s.annotate(err, "TABLES", record, "")
+				}
+				continue
+			}
+			d.Views = append(d.Views, *v)
+		}
+	}
+	return s.Err
+}
+
+func parseLayer(s *Scanner) (*Layer, error) {
+	l := &Layer{Color: 7, LineWeight: LineWeightDefault, Plot: true}
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code == 0 {
+			s.PushBack()
+			return l, nil
+		}
+		switch tag.Code {
+		case 2:
+			l.Name = tag.Value
+		case 6:
+			l.LineType = tag.Value
+		case 62, 70, 290, 370:
+			val, err := tag.Int()
+			if err != nil {
+				return nil, err
+			}
+			switch tag.Code {
+			case 62:
+				l.Color = val
+			case 70:
+				l.Flags = val
+			case 290:
+				l.Plot = val != 0
+			case 370:
+				l.LineWeight = val
+			}
+		}
+	}
+	return l, s.Err
+}
+
+func parseLinetype(s *Scanner) (*Linetype, error) {
+	lt := &Linetype{}
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code == 0 {
+			s.PushBack()
+			return lt, nil
+		}
+		switch tag.Code {
+		case 2:
+			lt.Name = tag.Value
+		case 3:
+			lt.Description = tag.Value
+		case 49:
+			val, err := tag.Float()
+			if err != nil {
+				return nil, err
+			}
+			lt.Pattern = append(lt.Pattern, val)
+		}
+	}
+	return lt, s.Err
+}
+
+func parseView(s *Scanner) (*View, error) {
+	v := &View{Direction: [3]float64{0, 0, 1}}
+	for s.Scan() {
+		tag := s.NextTag
+		if tag.Code == 0 {
+			s.PushBack()
+			return v, nil
+		}
+		if tag.Code == 2 {
+			v.Name = tag.Value
+			continue
+		}
+
+		var target *float64
+		switch tag.Code {
+		case 10:
+			target = &v.Center[0]
+		case 20:
+			target = &v.Center[1]
+		case 11:
+			target = &v.Direction[0]
+		case 21:
+			target = &v.Direction[1]
+		case 31:
+			target = &v.Direction[2]
+		case 12:
+			target = &v.Target[0]
+		case 22:
+			target = &v.Target[1]
+		case 32:
+			target = &v.Target[2]
+		case 40:
+			target = &v.Height
+		case 41:
+			target = &v.Width
+		default:
+			continue
+		}
+		val, err := tag.Float()
+		if err != nil {
+			return nil, err
+		}
+		*target = val
+	}
+	return v, s.Err
+}
+
+// MatchName reports whether a table entry name, such as a layer name, matches
+// the wildcard pattern, ignoring case. '*' matches any run of characters,
+// including the '/' and '|' of external reference names, '?' matches any one
+// character, and '[...]' is a character class as in path.Match. A backslash
+// escapes the next character. The only possible error is path.ErrBadPattern.
+func MatchName(pattern, name string) (bool, error) {
+	p := []rune(strings.ToLower(pattern))
+	if err := checkPattern(p); err != nil {
+		return false, err
+	}
+	s := []rune(strings.ToLower(name))
+	// On a mismatch, the last star absorbs one more character and matching
+	// resumes after it
+	i, j := 0, 0
+	star, resume := -1, 0
+	for j < len(s) {
+		if i < len(p) {
+			n := 0
+			switch p[i] {
+			case '*':
+				star, resume = i, j
+				i++
+				continue
+			case '?':
+				n = 1
+			case '[':
+				if ok, w := matchClass(p[i:], s[j]); ok {
+					n = w
+				}
+			case '\\':
+				if p[i+1] == s[j] {
+					n = 2
+				}
+			default:
+				if p[i] == s[j] {
+					n = 1
+				}
+			}
+			if n > 0 {
+				i += n
+				j++
+				continue
+			}
+		}
+		if star < 0 {
+			return false, nil
+		}
+		resume++
+		i, j = star+1, resume
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+	return i == len(p), nil
+}
+
+// checkPattern reports malformed escapes and character classes
+func checkPattern(p []rune) error {
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '\\':
+			if i++; i == len(p) {
+				return path.ErrBadPattern
+			}
+		case '[':
+			_, n := matchClass(p[i:], 0)
+			if n == 0 {
+				return path.ErrBadPattern
+			}
+			i += n - 1
+		}
+	}
+	return nil
+}
+
+// matchClass matches c against the character class at the start of p and
+// returns the width of the class, or zero if it is malformed
+func matchClass(p []rune, c rune) (bool, int) {
+	i := 1
+	negated := i < len(p) && p[i] == '^'
+	if negated {
+		i++
+	}
+	matched := false
+	for n := 0; ; n++ {
+		if i < len(p) && p[i] == ']' && n > 0 {
+			return matched != negated, i + 1
+		}
+		lo, w := classChar(p[i:])
+		if w == 0 {
+			return false, 0
+		}
+		i += w
+		hi := lo
+		if i < len(p) && p[i] == '-' {
+			if hi, w = classChar(p[i+1:]); w == 0 {
+				return false, 0
+			}
+			i += 1 + w
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+}
+
+// classChar returns the possibly escaped character at the start of p and
+// its width, or zero if there is none
+func classChar(p []rune) (rune, int) {
+	switch {
+	case len(p) == 0 || p[0] == '-' || p[0] == ']':
+		return 0, 0
+	case p[0] == '\\':
+		if len(p) < 2 {
+			return 0, 0
+		}
+		return p[1], 2
+	}
+	return p[0], 1
+}
@@ -0,0 +1,73 @@
+package dxf
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestMatchName(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"WALLS", "walls", true},
+		{"WALL?", "Walls", true},
+		{"WALL?", "Wall", false},
+		{"*", "", true},
+		{"A-*", "A-DOOR", true},
+		{"A-*", "B-DOOR", false},
+		{"*-DIM", "A-WALL-DIM", true},
+		{"*DIM*", "DIMENSIONS", true},
+		{"*a*b", "aXbXb", true},
+		{"*a*b", "aXbXc", false},
+		// External reference names have paths and bars
+		{"*|WALLS", "plans/site.dwg|WALLS", true},
+		{"plans*", "plans/site.dwg|WALLS", true},
+		{"plans/?ite*", "plans/site.dwg|WALLS", true},
+		{"[a-c]*", "Base", true},
+		{"[^a-c]*", "Base", false},
+		{"[^a-c]*", "Dims", true},
+		{"[/]x", "/x", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{`[\]]`, "]", true},
+	}
+	for _, tt := range tests {
+		got, err := MatchName(tt.pattern, tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("MatchName(%q, %q) = %v, %v; want %v", tt.pattern, tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, pattern := range []string{"[", "[]", "[a-]", "[-a]", "a[", `a\`, "[^]"} {
+		if _, err := MatchName(pattern, "a"); !errors.Is(err, path.ErrBadPattern) {
+			t.Errorf("MatchName(%q) error = %v, want %v", pattern, err, path.ErrBadPattern)
+		}
+	}
+}
+
+func FuzzMatchName(f *testing.F) {
+	f.Add("A-*", "a-door")
+	f.Add("*[!a-c]?", "xyz")
+	f.Add(`\[*]`, "[x]")
+	f.Fuzz(func(t *testing.T, pattern, name string) {
+		got, err := MatchName(pattern, name)
+		// Without slashes the syntax is that of path.Match
+		want, wantErr := path.Match(pattern, name)
+		if wantErr == nil && (err != nil) {
+			t.Fatalf("MatchName(%q) error = %v, path.Match accepts it", pattern, err)
+		}
+		if wantErr != nil || err != nil {
+			return
+		}
+		for _, c := range pattern + name {
+			if c == '/' || c >= 'A' && c <= 'Z' || c > 0x7f {
+				return
+			}
+		}
+		if got != want {
+			t.Fatalf("MatchName(%q, %q) = %v, path.Match = %v", pattern, name, got, want)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package dxf
+
+// Units represents the drawing units stored in $INSUNITS.
+type Units int
+
+const (
+	UnitsUnitless          Units = 0
+	UnitsInches            Units = 1
+	UnitsFeet              Units = 2
+	UnitsMiles             Units = 3
+	UnitsMillimeters       Units = 4
+	UnitsCentimeters       Units = 5
+	UnitsMeters            Units = 6
+	UnitsKilometers        Units = 7
+	UnitsMicroinches       Units = 8
+	UnitsMils              Units = 9
+	UnitsYards             Units = 10
+	UnitsAngstroms         Units = 11
+	UnitsNanometers        Units = 12
+	UnitsMicrons           Units = 13
+	UnitsDecimeters        Units = 14
+	UnitsDecameters        Units = 15
+	UnitsHectometers       Units = 16
+	UnitsGigameters        Units = 17
+	UnitsAstronomicalUnits Units = 18
+	UnitsLightYears        Units = 19
+	UnitsParsecs           Units = 20
+	UnitsUSSurveyFeet      Units = 21
+)
+
+// Length of one unit in millimeters, indexed by Units.
+var unitMillimeters = [...]float64{
+	UnitsUnitless:          1,
+	UnitsInches:            25.4,
+	UnitsFeet:              304.8,
+	UnitsMiles:             1609344,
+	UnitsMillimeters:       1,
+	UnitsCentimeters:       10,
+	UnitsMeters:            1000,
+	UnitsKilometers:        1e6,
+	UnitsMicroinches:       25.4e-6,
+	UnitsMils:              25.4e-3,
+	UnitsYards:             914.4,
+	UnitsAngstroms:         1e-7,
+	UnitsNanometers:        1e-6,
+	UnitsMicrons:           1e-3,
+	UnitsDecimeters:        100,
+	UnitsDecameters:        1e4,
+	UnitsHectometers:       1e5,
+	UnitsGigameters:        1e12,
+	UnitsAstronomicalUnits: 1.495978707e14,
+	UnitsLightYears:        9.4607304725808e18,
+	UnitsParsecs:           3.0856775814913673e19,
+	UnitsUSSurveyFeet:      1200.0 / 3937.0 * 1000,
+}
+
+var unitNames = [...]string{
+	"unitless", "in", "ft", "mi", "mm", "cm", "m", "km", "uin", "mil", "yd",
+	"angstrom", "nm", "um", "dm", "dam", "hm", "Gm", "au", "ly", "pc", "us-ft",
+}
+
+// Millimeters returns the length of one unit in millimeters.
+// Unitless and unknown units are treated as millimeters.
+func (u Units) Millimeters() float64 {
+	if u < 0 || int(u) >= len(unitMillimeters) {
+		return 1
+	}
+	return unitMillimeters[u]
+}
+
+// String returns the abbreviation of the unit, e.g. "mm".
+func (u Units) String() string {
+	if u < 0 || int(u) >= len(unitNames) {
+		return "unknown"
+	}
+	return unitNames[u]
+}
+
+// ParseUnits returns the Units for an abbreviation as returned by Units.String.
+func ParseUnits(s string) (Units, bool) {
+	for i, name := range unitNames {
+		if name == s {
+			return Units(i), true
+		}
+	}
+	return UnitsUnitless, false
+}
+
+// Units returns $INSUNITS as Units.
+func (h *Header) Units() Units {
+	return Units(h.InsUnits())
+}
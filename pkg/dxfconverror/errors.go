@@ -1,6 +1,10 @@
 package dxfconverror
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // InternalError represents an internal error such as I/O failure during temp file creation.
 type InternalError struct {
@@ -15,13 +19,64 @@ func (e *InternalError) Unwrap() error {
 	return e.Err
 }
 
-// ParseError represents an error during DXF parsing.
+// ParseError represents an error during DXF parsing. Errors found in the
+// input carry its position; errors such as failed reads only Err.
 type ParseError struct {
-	Err error
+	// Line is the line number of the offending group code, 0 if unknown
+	Line int
+	// Offset is the byte offset of the start of Line
+	Offset int64
+	// Code and Value are the group code and raw value of the offending tag.
+	// Code is -1 if the group code itself is malformed; Value then holds it.
+	Code  int
+	Value string
+	// Section is the DXF section, e.g. "ENTITIES"
+	Section string
+	// EntityType and Handle identify the entity or table record being read
+	EntityType string
+	Handle     string
+	// Excerpt shows the input lines around Line, the offending tag marked with '>'
+	Excerpt string
+	Err     error
+}
+
+// AsParseError returns the first ParseError in err's chain.
+func AsParseError(err error) (*ParseError, bool) {
+	var e *ParseError
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// HasPosition reports whether the error is located in the input.
+func (e *ParseError) HasPosition() bool {
+	return e.Line > 0
+}
+
+// Location describes where the error is in the input, e.g.
+// "line 27, ENTITIES section, LINE 1A, group code 31". It is empty if the
+// error has no position.
+func (e *ParseError) Location() string {
+	if !e.HasPosition() {
+		return ""
+	}
+	parts := []string{fmt.Sprintf("line %d", e.Line)}
+	if e.Section != "" {
+		parts = append(parts, e.Section+" section")
+	}
+	if e.EntityType != "" {
+		parts = append(parts, strings.TrimSpace(e.EntityType+" "+e.Handle))
+	}
+	if e.Code >= 0 {
+		parts = append(parts, fmt.Sprintf("group code %d", e.Code))
+	}
+	return strings.Join(parts, ", ")
 }
 
 func (e *ParseError) Error() string {
-	return fmt.Sprintf("parse error: %v", e.Err)
+	if !e.HasPosition() {
+		return fmt.Sprintf("parse error: %v", e.Err)
+	}
+	return fmt.Sprintf("parse error: %s: %v", e.Location(), e.Err)
 }
 
 func (e *ParseError) Unwrap() error {
@@ -40,3 +95,81 @@ func (e *RenderingError) Error() string {
 func (e *RenderingError) Unwrap() error {
 	return e.Err
 }
+
+// OptionError represents an invalid conversion option.
+type OptionError struct {
+	Option string
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid option %s: %v", e.Option, e.Err)
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// FitError represents a drawing that does not fit on the page at the requested plot scale.
+// All dimensions are in millimeters.
+type FitError struct {
+	Width, Height                   float64
+	AvailableWidth, AvailableHeight float64
+}
+
+func (e *FitError) Error() string {
+	return fmt.Sprintf("drawing (%.1f x %.1f mm) does not fit the printable area (%.1f x %.1f mm) at the requested scale",
+		e.Width, e.Height, e.AvailableWidth, e.AvailableHeight)
+}
+
+// LimitError represents a drawing that exceeds a resource limit of the options.
+type LimitError struct {
+	// Limit is the name of the exceeded option, e.g. "MaxEntities"
+	Limit string
+	// Max is the value of the limit
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limit exceeded: %s is %d", e.Limit, e.Max)
+}
+
+// Severity classifies a Diagnostic.
+type Severity int
+
+const (
+	// SeverityWarning marks a problem that was repaired, e.g. a skipped line
+	SeverityWarning Severity = iota
+	// SeverityError marks data that was dropped, e.g. a skipped entity
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic describes a problem in a DXF file that lenient parsing recovered from.
+type Diagnostic struct {
+	// Line is the line number of the offending group code
+	Line int
+	// EntityType and Handle identify the affected entity or table record, if any
+	EntityType string
+	Handle     string
+	Severity   Severity
+	Message    string
+}
+
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("line %d: %s: ", d.Line, d.Severity)
+	if d.EntityType != "" {
+		s += d.EntityType
+		if d.Handle != "" {
+			s += " " + d.Handle
+		}
+		s += ": "
+	}
+	return s + d.Message
+}
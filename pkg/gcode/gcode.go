@@ -0,0 +1,262 @@
+// Package gcode writes the outlines of a DXF drawing as G-code toolpaths for
+// laser cutters and CNC routers.
+package gcode
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
+// Convert reads DXF data from r and writes G-code to w
+func Convert(r io.Reader, w io.Writer, opts *Options) error {
+	_, err := ConvertContext(context.Background(), r, w, opts)
+	return err
+}
+
+// ConvertContext is like Convert but stops with the context's error when ctx
+// is done while parsing. Input exceeding the limits of opts.Input fails with
+// a *dxfconverror.LimitError. The Result lists the warnings of lenient parsing.
+func ConvertContext(ctx context.Context, r io.Reader, w io.Writer, opts *Options) (*converter.Result, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	d, err := converter.Parse(ctx, r, opts.Input)
+	if err != nil {
+		return nil, err
+	}
+	if err := Write(w, d, opts); err != nil {
+		return nil, &dxfconverror.RenderingError{Err: err}
+	}
+	return &converter.Result{Warnings: d.Diagnostics}, nil
+}
+
+func (o *Options) validate() error {
+	switch o.Machine {
+	case MachineLaser, MachineSpindle:
+	default:
+		return &dxfconverror.OptionError{Option: "Machine", Err: fmt.Errorf("unknown machine %q", o.Machine)}
+	}
+	switch o.Origin {
+	case OriginDrawing, OriginLowerLeft, OriginCenter:
+	default:
+		return &dxfconverror.OptionError{Option: "Origin", Err: fmt.Errorf("unknown origin %q", o.Origin)}
+	}
+	if err := checkOperation(o.DefaultOperation); err != nil {
+		return &dxfconverror.OptionError{Option: "DefaultOperation", Err: err}
+	}
+	for _, lo := range o.LayerOperations {
+		if _, err := dxf.MatchName(lo.Layer, ""); err != nil {
+			return &dxfconverror.OptionError{Option: "LayerOperations", Err: fmt.Errorf("bad layer pattern %q: %w", lo.Layer, err)}
+		}
+		if err := checkOperation(lo.Operation); err != nil {
+			return &dxfconverror.OptionError{Option: "LayerOperations", Err: err}
+		}
+	}
+	for _, op := range []Operation{OpCut, OpEngrave} {
+		if s, ok := o.Operations[op]; ok && s.Feed <= 0 {
+			return &dxfconverror.OptionError{Option: "Operations", Err: fmt.Errorf("%s feed rate must be positive", op)}
+		}
+	}
+	return nil
+}
+
+func checkOperation(op Operation) error {
+	switch op {
+	case OpCut, OpEngrave, OpSkip:
+		return nil
+	}
+	return fmt.Errorf("unknown operation %q", op)
+}
+
+// operation returns the operation for the entities of a layer
+func (o *Options) operation(d *dxf.Drawing, layer string) Operation {
+	// Layers hidden in the layer table are not machined
+	if l, ok := d.Layer(layer); ok && (l.Frozen() || l.Off() || !l.Plot) {
+		return OpSkip
+	}
+	op := o.DefaultOperation
+	for _, lo := range o.LayerOperations {
+		if ok, _ := dxf.MatchName(lo.Layer, layer); ok {
+			op = lo.Operation
+		}
+	}
+	return op
+}
+
+// Write writes the toolpaths of the drawing to w. Engraving comes first so
+// that parts are not cut free before their details are done.
+func Write(w io.Writer, d *dxf.Drawing, opts *Options) error {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	// Collect the paths of each operation in millimeters
+	k := d.Header.Units().Millimeters()
+	var all []*toolpath
+	byOp := make(map[Operation][]*toolpath)
+	for _, e := range d.Entities {
+		op := opts.operation(d, e.Layer())
+		if op == OpSkip {
+			continue
+		}
+		segs := entitySegments(e, k)
+		if len(segs) == 0 {
+			continue
+		}
+		p := &toolpath{op: op, segments: segs}
+		all = append(all, p)
+		byOp[op] = append(byOp[op], p)
+	}
+	for op := range byOp {
+		if _, ok := opts.Operations[op]; !ok {
+			return &dxfconverror.OptionError{Option: "Operations", Err: fmt.Errorf("no settings for %s", op)}
+		}
+	}
+
+	if len(all) > 0 && opts.Origin != OriginDrawing {
+		min, max := extent(all)
+		shift := min
+		if opts.Origin == OriginCenter {
+			shift = point{(min[0] + max[0]) / 2, (min[1] + max[1]) / 2}
+		}
+		for _, p := range all {
+			for i := range p.segments {
+				s := &p.segments[i]
+				for _, q := range []*point{&s.start, &s.end, &s.center} {
+					q[0] -= shift[0]
+					q[1] -= shift[1]
+				}
+			}
+		}
+	}
+
+	g := &writer{w: bufio.NewWriter(w), opts: opts}
+	g.header()
+	var pos point
+	for _, op := range []Operation{OpEngrave, OpCut} {
+		paths := order(byOp[op], pos)
+		if len(paths) == 0 {
+			continue
+		}
+		g.operation(op)
+		for _, p := range paths {
+			g.path(p)
+		}
+		pos = paths[len(paths)-1].end()
+	}
+	g.footer()
+	return g.w.Flush()
+}
+
+// writer emits G-code and tracks the machine state
+type writer struct {
+	w        *bufio.Writer
+	opts     *Options
+	settings Settings
+	pos      point
+	cutting  bool    // tool on and at cutting depth
+	feed     float64 // last F word
+}
+
+// num formats a coordinate or word value to the micrometer
+func num(v float64) string {
+	return renderers.FormatNumber(v, 3)
+}
+
+func (g *writer) line(format string, args ...any) {
+	fmt.Fprintf(g.w, format+"\n", args...)
+}
+
+func (g *writer) header() {
+	g.line("(Generated by dxfconv)")
+	g.line("G21 (millimeters)")
+	g.line("G90 (absolute positioning)")
+	if g.opts.Machine == MachineSpindle {
+		g.line("G0 Z%s", num(g.opts.SafeZ))
+	} else {
+		g.line("M5")
+	}
+}
+
+func (g *writer) footer() {
+	g.stop()
+	if g.opts.Machine == MachineSpindle {
+		g.line("M5")
+	}
+	g.line("M2")
+}
+
+// operation switches to the settings of op
+func (g *writer) operation(op Operation) {
+	g.stop()
+	g.settings = g.opts.Operations[op]
+	g.line("(%s)", op)
+	if g.opts.Machine == MachineSpindle {
+		g.line("M3 S%s", num(g.settings.Power))
+	}
+}
+
+// start switches the tool on at the current position
+func (g *writer) start() {
+	if g.opts.Machine == MachineSpindle {
+		g.line("G1 Z%s F%s", num(g.settings.Depth), num(g.settings.Feed))
+	} else {
+		g.line("M3 S%s", num(g.settings.Power))
+	}
+	g.feed = 0
+	g.cutting = true
+}
+
+// stop switches the tool off
+func (g *writer) stop() {
+	if !g.cutting {
+		return
+	}
+	if g.opts.Machine == MachineSpindle {
+		g.line("G0 Z%s", num(g.opts.SafeZ))
+	} else {
+		g.line("M5")
+	}
+	g.cutting = false
+}
+
+// path cuts p, continuing the previous cut when p starts where it ended
+func (g *writer) path(p *toolpath) {
+	if !g.cutting || g.pos.distance(p.start()) > g.opts.Tolerance {
+		g.stop()
+		g.line("G0 X%s Y%s", num(p.start()[0]), num(p.start()[1]))
+		g.start()
+	}
+	for _, s := range p.segments {
+		var cmd string
+		if s.arc {
+			cmd = "G2"
+			if s.ccw {
+				cmd = "G3"
+			}
+			cmd += fmt.Sprintf(" X%s Y%s I%s J%s", num(s.end[0]), num(s.end[1]),
+				num(s.center[0]-s.start[0]), num(s.center[1]-s.start[1]))
+		} else {
+			cmd = fmt.Sprintf("G1 X%s Y%s", num(s.end[0]), num(s.end[1]))
+		}
+		if g.feed != g.settings.Feed {
+			cmd += " F" + num(g.settings.Feed)
+			g.feed = g.settings.Feed
+		}
+		g.line("%s", cmd)
+	}
+	g.pos = p.end()
+}
@@ -0,0 +1,226 @@
+package gcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+func base(layer string) dxf.BaseEntity {
+	return dxf.BaseEntity{LayerName: layer}
+}
+
+func write(t *testing.T, d *dxf.Drawing, opts *Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, d, opts); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return buf.String()
+}
+
+func assertContains(t *testing.T, out string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWrite_LinesChained(t *testing.T) {
+	d := &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.Line{BaseEntity: base("0"), Start: [3]float64{10, 0}, End: [3]float64{10, 10}},
+		&dxf.Line{BaseEntity: base("0"), Start: [3]float64{0, 0}, End: [3]float64{10, 0}},
+	}}
+	out := write(t, d, DefaultOptions())
+
+	// The second line starts at the origin and continues into the first one
+	// without switching the laser off
+	want := "G0 X0 Y0\nM3 S1000\nG1 X10 Y0 F300\nG1 X10 Y10\nM5\n"
+	assertContains(t, out, "G21", "G90", want)
+	if n := strings.Count(out, "G0 "); n != 1 {
+		t.Errorf("got %d rapid moves, want 1:\n%s", n, out)
+	}
+	if !strings.HasSuffix(out, "M2\n") {
+		t.Errorf("missing program end:\n%s", out)
+	}
+}
+
+func TestWrite_Arcs(t *testing.T) {
+	d := &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.Arc{BaseEntity: base("0"), Center: [3]float64{0, 0}, Radius: 10, StartAngle: 0, EndAngle: 90},
+	}}
+	out := write(t, d, DefaultOptions())
+	// Counter-clockwise from (10, 0), centre offset from the start point
+	assertContains(t, out, "G0 X10 Y0\n", "G3 X0 Y10 I-10 J0 F300\n")
+
+	// Degenerate arcs have no toolpath
+	d = &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.Arc{BaseEntity: base("0"), Center: [3]float64{5, 5}, Radius: 0, StartAngle: 0, EndAngle: 90},
+		&dxf.Arc{BaseEntity: base("0"), Center: [3]float64{5, 5}, Radius: -2, StartAngle: 0, EndAngle: 90},
+	}}
+	if out := write(t, d, DefaultOptions()); strings.Contains(out, "G2 ") || strings.Contains(out, "G3 ") || strings.Contains(out, "G0 ") {
+		t.Errorf("degenerate arcs should be skipped:\n%s", out)
+	}
+}
+
+func TestWrite_Circle(t *testing.T) {
+	d := &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.Circle{BaseEntity: base("0"), Center: [3]float64{5, 5}, Radius: 5},
+	}}
+	out := write(t, d, DefaultOptions())
+	assertContains(t, out, "G0 X10 Y5\n", "G3 X0 Y5 I-5 J0 F300\n", "G3 X10 Y5 I5 J0\n")
+}
+
+func TestWrite_Bulge(t *testing.T) {
+	d := &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.LwPolyline{BaseEntity: base("0"), Vertices: []dxf.LwPolylineVertex{
+			// Clockwise half circle from (0, 0) to (10, 0) through (5, 5)
+			{X: 0, Y: 0, Bulge: -1},
+			{X: 10, Y: 0},
+			{X: 10, Y: -10},
+		}},
+	}}
+	out := write(t, d, DefaultOptions())
+	assertContains(t, out, "G0 X0 Y0\n", "G2 X10 Y0 I5 J0 F300\n", "G1 X10 Y-10\n")
+}
+
+func TestBulgeSegment(t *testing.T) {
+	// A quarter circle has a bulge of tan(90°/4)
+	s := bulgeSegment(point{10, 0}, point{0, 10}, math.Tan(math.Pi/8))
+	if !s.arc || !s.ccw || math.Abs(s.center[0]) > 1e-9 || math.Abs(s.center[1]) > 1e-9 {
+		t.Errorf("bulgeSegment() = %+v, want counter-clockwise arc around the origin", s)
+	}
+}
+
+func TestWrite_LayerOperations(t *testing.T) {
+	d := &dxf.Drawing{
+		Layers: []dxf.Layer{{Name: "Hidden", Color: -7, Plot: true}},
+		Entities: []dxf.Entity{
+			&dxf.Line{BaseEntity: base("Outline"), Start: [3]float64{0, 0}, End: [3]float64{10, 0}},
+			&dxf.Line{BaseEntity: base("Text-Engrave"), Start: [3]float64{0, 5}, End: [3]float64{10, 5}},
+			&dxf.Line{BaseEntity: base("Dims"), Start: [3]float64{0, 20}, End: [3]float64{10, 20}},
+			&dxf.Line{BaseEntity: base("Hidden"), Start: [3]float64{0, 30}, End: [3]float64{10, 30}},
+			&dxf.Line{BaseEntity: base("parts/plate.dwg|Dims"), Start: [3]float64{0, 40}, End: [3]float64{10, 40}},
+		},
+	}
+	opts := DefaultOptions()
+	opts.LayerOperations = []LayerOperation{
+		{Layer: "*-engrave", Operation: OpEngrave},
+		{Layer: "*DIMS", Operation: OpSkip},
+	}
+	out := write(t, d, opts)
+
+	engrave, cut := strings.Index(out, "(engrave)"), strings.Index(out, "(cut)")
+	if engrave < 0 || cut < engrave {
+		t.Fatalf("want engraving before cutting:\n%s", out)
+	}
+	assertContains(t, out[engrave:cut], "M3 S300\n", "G1 X10 Y5 F1500\n")
+	assertContains(t, out[cut:], "M3 S1000\n", "Y0 F300\n")
+	if strings.Contains(out, "Y20") || strings.Contains(out, "Y30") || strings.Contains(out, "Y40") {
+		t.Errorf("skipped or hidden layer machined:\n%s", out)
+	}
+}
+
+func TestWrite_Origin(t *testing.T) {
+	d := &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.Circle{BaseEntity: base("0"), Center: [3]float64{100, 50}, Radius: 10},
+	}}
+	opts := DefaultOptions()
+	opts.Origin = OriginLowerLeft
+	assertContains(t, write(t, d, opts), "G0 X20 Y10\n")
+
+	opts.Origin = OriginCenter
+	assertContains(t, write(t, d, opts), "G0 X10 Y0\n")
+}
+
+func TestWrite_Spindle(t *testing.T) {
+	d := &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.Line{BaseEntity: base("0"), Start: [3]float64{0, 0}, End: [3]float64{10, 0}},
+		&dxf.Line{BaseEntity: base("0"), Start: [3]float64{0, 10}, End: [3]float64{10, 10}},
+	}}
+	opts := DefaultOptions()
+	opts.Machine = MachineSpindle
+	opts.Operations[OpCut] = Settings{Feed: 600, Power: 12000, Depth: -2}
+	out := write(t, d, opts)
+	assertContains(t, out,
+		"M3 S12000\n",
+		"G0 X0 Y0\nG1 Z-2 F600\nG1 X10 Y0 F600\nG0 Z5\n",
+		"G0 X10 Y10\nG1 Z-2 F600\nG1 X0 Y10 F600\nG0 Z5\nM5\nM2\n",
+	)
+}
+
+func TestWrite_Units(t *testing.T) {
+	d := &dxf.Drawing{
+		Header: dxf.Header{Variables: map[string][]dxf.Tag{"$INSUNITS": {{Code: 70, Value: "1"}}}},
+		Entities: []dxf.Entity{
+			&dxf.Line{BaseEntity: base("0"), Start: [3]float64{0, 0}, End: [3]float64{1, 0}},
+		},
+	}
+	// Inches are converted to millimeters
+	assertContains(t, write(t, d, DefaultOptions()), "G1 X25.4 Y0")
+}
+
+func TestConvert_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		modify func(o *Options)
+		option string
+	}{
+		{func(o *Options) { o.Machine = "plasma" }, "Machine"},
+		{func(o *Options) { o.Origin = "top" }, "Origin"},
+		{func(o *Options) { o.LayerOperations = []LayerOperation{{Layer: "[", Operation: OpCut}} }, "LayerOperations"},
+		{func(o *Options) { o.LayerOperations = []LayerOperation{{Layer: "*", Operation: "drill"}} }, "LayerOperations"},
+		{func(o *Options) { o.Operations = map[Operation]Settings{OpCut: {}} }, "Operations"},
+	}
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		tt.modify(opts)
+		err := Convert(strings.NewReader("0\nEOF\n"), &bytes.Buffer{}, opts)
+		var optErr *dxfconverror.OptionError
+		if !errors.As(err, &optErr) || optErr.Option != tt.option {
+			t.Errorf("Convert() error = %v, want OptionError for %s", err, tt.option)
+		}
+	}
+}
+
+func TestConvertContext_Input(t *testing.T) {
+	input := "0\nSECTION\n2\nENTITIES\n" +
+		"0\nLINE\n8\n0\n10\n0\n20\n0\n11\n1\n21\n0\n" +
+		"0\nCIRCLE\n8\n0\n10\n0\n20\n0\n40\nabc\n" +
+		"0\nENDSEC\n0\nEOF\n"
+
+	opts := DefaultOptions()
+	if _, err := ConvertContext(context.Background(), strings.NewReader(input), &bytes.Buffer{}, opts); !errors.As(err, new(*dxfconverror.ParseError)) {
+		t.Errorf("strict ConvertContext() error = %v, want ParseError", err)
+	}
+
+	opts.Input = converter.DefaultOptions()
+	opts.Input.Lenient = true
+	var buf bytes.Buffer
+	res, err := ConvertContext(context.Background(), strings.NewReader(input), &buf, opts)
+	if err != nil || len(res.Warnings) != 1 {
+		t.Fatalf("lenient ConvertContext() = %v, %v; want one warning", res, err)
+	}
+	assertContains(t, buf.String(), "G1 X1 Y0")
+
+	opts.Input.MaxEntities = 1
+	opts.Input.Lenient = false
+	if _, err := ConvertContext(context.Background(), strings.NewReader(strings.Replace(input, "abc", "1", 1)), &bytes.Buffer{}, opts); !errors.As(err, new(*dxfconverror.LimitError)) {
+		t.Errorf("ConvertContext() error = %v, want LimitError", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts.Input = nil
+	if _, err := ConvertContext(ctx, strings.NewReader(input), &bytes.Buffer{}, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled ConvertContext() error = %v, want %v", err, context.Canceled)
+	}
+}
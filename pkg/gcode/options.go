@@ -0,0 +1,90 @@
+package gcode
+
+import "github.com/daidai-ok/dxfconv/pkg/converter"
+
+// Machine selects the commands that switch the tool on and off
+type Machine string
+
+const (
+	// MachineLaser switches the laser on with M3 S<power> before each cut and
+	// off with M5 after it.
+	MachineLaser Machine = "laser"
+	// MachineSpindle starts the spindle once and plunges to the cut depth
+	// before each cut, retracting to the safe height after it.
+	MachineSpindle Machine = "spindle"
+)
+
+// Operation is what the machine does with the geometry of a layer
+type Operation string
+
+const (
+	OpCut     Operation = "cut"
+	OpEngrave Operation = "engrave"
+	OpSkip    Operation = "skip"
+)
+
+// Origin selects the point of the drawing placed at the machine origin
+type Origin string
+
+const (
+	// OriginDrawing keeps the drawing coordinates
+	OriginDrawing Origin = "drawing"
+	// OriginLowerLeft places the lower-left corner of the geometry at 0,0
+	OriginLowerLeft Origin = "lower-left"
+	// OriginCenter places the centre of the geometry at 0,0
+	OriginCenter Origin = "center"
+)
+
+// Settings holds the machine parameters of an operation
+type Settings struct {
+	// Feed rate of cutting moves in mm/min
+	Feed float64
+	// Power is the S word: laser power or spindle speed
+	Power float64
+	// Depth is the Z of cutting moves for MachineSpindle, in mm
+	Depth float64
+}
+
+// LayerOperation assigns an operation to the layers matching Layer
+type LayerOperation struct {
+	// Layer is a layer name or a wildcard pattern of dxf.MatchName, matched
+	// without regard to case
+	Layer     string
+	Operation Operation
+}
+
+// Options configures the G-code output
+type Options struct {
+	Machine Machine
+	// Operations holds the settings of OpCut and OpEngrave
+	Operations map[Operation]Settings
+	// LayerOperations maps layers to operations. When several entries match
+	// a layer, later entries take precedence.
+	LayerOperations []LayerOperation
+	// DefaultOperation applies to layers without a LayerOperations entry
+	DefaultOperation Operation
+	// Origin places the geometry relative to the machine origin
+	Origin Origin
+	// SafeZ is the height of rapid moves for MachineSpindle, in mm
+	SafeZ float64
+	// Tolerance is the distance in mm below which path ends are joined
+	Tolerance float64
+	// Input holds the parse options and limits of the DXF data read by
+	// Convert, as for converter.Parse. Nil parses strictly without limits.
+	Input *converter.Options
+}
+
+// DefaultOptions returns settings for a laser cutter that cuts every visible layer
+func DefaultOptions() *Options {
+	return &Options{
+		Machine: MachineLaser,
+		Operations: map[Operation]Settings{
+			OpCut:     {Feed: 300, Power: 1000, Depth: -1},
+			OpEngrave: {Feed: 1500, Power: 300, Depth: -0.2},
+		},
+		DefaultOperation: OpCut,
+		Origin:           OriginDrawing,
+		SafeZ:            5,
+		Tolerance:        0.01,
+	}
+}
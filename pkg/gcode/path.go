@@ -0,0 +1,215 @@
+package gcode
+
+import (
+	"math"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/pathorder"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
+type point [2]float64
+
+func (p point) distance(q point) float64 {
+	return math.Hypot(q[0]-p[0], q[1]-p[1])
+}
+
+// segment is a straight or circular move in millimeters
+type segment struct {
+	start, end point
+	arc        bool
+	center     point
+	ccw        bool
+}
+
+func (s segment) reversed() segment {
+	s.start, s.end = s.end, s.start
+	s.ccw = !s.ccw
+	return s
+}
+
+// toolpath is a sequence of connected segments cut in one go
+type toolpath struct {
+	op       Operation
+	segments []segment
+}
+
+func (p *toolpath) start() point {
+	return p.segments[0].start
+}
+
+func (p *toolpath) end() point {
+	return p.segments[len(p.segments)-1].end
+}
+
+func (p *toolpath) reverse() {
+	n := len(p.segments)
+	for i := 0; i < n/2; i++ {
+		p.segments[i], p.segments[n-1-i] = p.segments[n-1-i], p.segments[i]
+	}
+	for i := range p.segments {
+		p.segments[i] = p.segments[i].reversed()
+	}
+}
+
+// entitySegments returns the toolpath of an entity, scaled by k to millimeters.
+// Entities without an outline, such as points and text, have none.
+func entitySegments(e dxf.Entity, k float64) []segment {
+	pt := func(x, y float64) point { return point{x * k, y * k} }
+
+	switch e := e.(type) {
+	case *dxf.Line:
+		return lines([]point{pt(e.Start[0], e.Start[1]), pt(e.End[0], e.End[1])}, false)
+	case *dxf.Circle:
+		return circle(pt(e.Center[0], e.Center[1]), e.Radius*k)
+	case *dxf.Arc:
+		c := pt(e.Center[0], e.Center[1])
+		r := e.Radius * k
+		if r <= 0 {
+			return nil
+		}
+		sweep := math.Mod(e.EndAngle-e.StartAngle, 360)
+		if sweep < 0 {
+			sweep += 360
+		}
+		if sweep == 0 {
+			return circle(c, r)
+		}
+		return []segment{{
+			start:  polar(c, r, e.StartAngle),
+			end:    polar(c, r, e.EndAngle),
+			arc:    true,
+			center: c,
+			ccw:    true,
+		}}
+	case *dxf.LwPolyline:
+		n := len(e.Vertices)
+		if n < 2 {
+			return nil
+		}
+		var segs []segment
+		last := n - 1
+		if e.Closed {
+			last = n
+		}
+		for i := 0; i < last; i++ {
+			v, w := e.Vertices[i], e.Vertices[(i+1)%n]
+			p, q := pt(v.X, v.Y), pt(w.X, w.Y)
+			if p == q {
+				continue
+			}
+			segs = append(segs, bulgeSegment(p, q, v.Bulge))
+		}
+		return segs
+	case *dxf.Polyline:
+		pts := make([]point, len(e.Vertices))
+		for i, v := range e.Vertices {
+			pts[i] = pt(v.X, v.Y)
+		}
+		return lines(pts, e.Closed)
+	case *dxf.Spline:
+		// Approximated by the control polygon
+		pts := make([]point, len(e.ControlPoints))
+		for i, v := range e.ControlPoints {
+			pts[i] = pt(v[0], v[1])
+		}
+		return lines(pts, e.Closed)
+	}
+	return nil
+}
+
+func polar(c point, r, degrees float64) point {
+	a := degrees * math.Pi / 180
+	return point{c[0] + r*math.Cos(a), c[1] + r*math.Sin(a)}
+}
+
+func lines(pts []point, closed bool) []segment {
+	if len(pts) < 2 {
+		return nil
+	}
+	if closed {
+		pts = append(pts, pts[0])
+	}
+	segs := make([]segment, 0, len(pts)-1)
+	for i := 1; i < len(pts); i++ {
+		if pts[i-1] != pts[i] {
+			segs = append(segs, segment{start: pts[i-1], end: pts[i]})
+		}
+	}
+	return segs
+}
+
+// circle returns a full circle as two half arcs, which all controllers accept
+func circle(c point, r float64) []segment {
+	if r <= 0 {
+		return nil
+	}
+	right, left := point{c[0] + r, c[1]}, point{c[0] - r, c[1]}
+	return []segment{
+		{start: right, end: left, arc: true, center: c, ccw: true},
+		{start: left, end: right, arc: true, center: c, ccw: true},
+	}
+}
+
+// bulgeSegment returns the segment from p to q of a polyline vertex with the
+// given bulge: the tangent of a quarter of the arc's included angle, positive
+// for counter-clockwise arcs.
+func bulgeSegment(p, q point, bulge float64) segment {
+	if bulge == 0 {
+		return segment{start: p, end: q}
+	}
+	c, _, _, _ := renderers.BulgeArc(p, q, bulge)
+	return segment{start: p, end: q, arc: true, center: c, ccw: bulge > 0}
+}
+
+// extent returns the bounding box of the segments
+func extent(paths []*toolpath) (min, max point) {
+	min = point{math.MaxFloat64, math.MaxFloat64}
+	max = point{-math.MaxFloat64, -math.MaxFloat64}
+	add := func(p point) {
+		min[0], min[1] = math.Min(min[0], p[0]), math.Min(min[1], p[1])
+		max[0], max[1] = math.Max(max[0], p[0]), math.Max(max[1], p[1])
+	}
+	for _, p := range paths {
+		for _, s := range p.segments {
+			add(s.start)
+			add(s.end)
+			if !s.arc {
+				continue
+			}
+			// Add the quadrant points the arc passes through
+			r := s.center.distance(s.start)
+			a0 := math.Atan2(s.start[1]-s.center[1], s.start[0]-s.center[0])
+			a1 := math.Atan2(s.end[1]-s.center[1], s.end[0]-s.center[0])
+			if !s.ccw {
+				a0, a1 = a1, a0
+			}
+			for a1 <= a0 {
+				a1 += 2 * math.Pi
+			}
+			for q := math.Ceil(a0 / (math.Pi / 2)); q*math.Pi/2 < a1; q++ {
+				a := q * math.Pi / 2
+				add(point{s.center[0] + r*math.Cos(a), s.center[1] + r*math.Sin(a)})
+			}
+		}
+	}
+	return min, max
+}
+
+// order sorts the paths so that each starts as close as possible to where the
+// previous one ended, reversing paths when that shortens the rapid move.
+func order(paths []*toolpath, from point) []*toolpath {
+	ends := make([]pathorder.Path, len(paths))
+	for i, p := range paths {
+		ends[i] = pathorder.Path{Start: p.start(), End: p.end(), Reversible: true}
+	}
+	ordered := make([]*toolpath, 0, len(paths))
+	for _, s := range pathorder.Nearest(ends, from) {
+		p := paths[s.Index]
+		if s.Reversed {
+			p.reverse()
+		}
+		ordered = append(ordered, p)
+	}
+	return ordered
+}
@@ -0,0 +1,246 @@
+// Package geojson writes the entities of a DXF drawing as a GeoJSON
+// FeatureCollection. Coordinates are the drawing's own, without any
+// reprojection; curves are tessellated into line strings.
+package geojson
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+// DefaultTolerance is the default maximum distance, in drawing units, between
+// a curve and the line segments approximating it.
+const DefaultTolerance = 0.01
+
+// Options configures the GeoJSON output
+type Options struct {
+	// Tolerance is the maximum deviation of tessellated arcs, circles, bulges
+	// and splines from the true curve, in drawing units. Zero selects DefaultTolerance.
+	Tolerance float64
+	// Input holds the parse options and limits of the DXF data read by
+	// Convert, as for converter.Parse. Nil parses strictly without limits.
+	Input *converter.Options
+}
+
+// DefaultOptions returns the default GeoJSON options
+func DefaultOptions() *Options {
+	return &Options{Tolerance: DefaultTolerance}
+}
+
+type featureCollection struct {
+	Type     string     `json:"type"`
+	Features []*feature `json:"features"`
+}
+
+type feature struct {
+	Type       string     `json:"type"`
+	Geometry   geometry   `json:"geometry"`
+	Properties properties `json:"properties"`
+}
+
+type geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+type properties struct {
+	EntityType string `json:"entityType"`
+	Layer      string `json:"layer"`
+	Handle     string `json:"handle,omitempty"`
+	// Color is the resolved ACI colour number and RGB its "#rrggbb" value
+	Color int    `json:"color"`
+	RGB   string `json:"rgb"`
+	// Text and TextHeight are set for TEXT and MTEXT
+	Text       string  `json:"text,omitempty"`
+	TextHeight float64 `json:"textHeight,omitempty"`
+}
+
+// Convert reads DXF data from r and writes GeoJSON to w
+func Convert(r io.Reader, w io.Writer, opts *Options) error {
+	_, err := ConvertContext(context.Background(), r, w, opts)
+	return err
+}
+
+// ConvertContext is like Convert but stops with the context's error when ctx
+// is done while parsing. Input exceeding the limits of opts.Input fails with
+// a *dxfconverror.LimitError. The Result lists the warnings of lenient parsing.
+func ConvertContext(ctx context.Context, r io.Reader, w io.Writer, opts *Options) (*converter.Result, error) {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	d, err := converter.Parse(ctx, r, opts.Input)
+	if err != nil {
+		return nil, err
+	}
+	if err := Write(w, d, opts); err != nil {
+		return nil, &dxfconverror.RenderingError{Err: err}
+	}
+	return &converter.Result{Warnings: d.Diagnostics}, nil
+}
+
+func (o *Options) validate() error {
+	if o.Tolerance < 0 || math.IsNaN(o.Tolerance) {
+		return &dxfconverror.OptionError{Option: "Tolerance", Err: fmt.Errorf("must not be negative, got %v", o.Tolerance)}
+	}
+	return nil
+}
+
+// Write writes the entities of the drawing to w as a FeatureCollection.
+// Entities on hidden layers are included; their layer property allows
+// filtering them out.
+func Write(w io.Writer, d *dxf.Drawing, opts *Options) error {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	tol := opts.Tolerance
+	if tol == 0 {
+		tol = DefaultTolerance
+	}
+
+	fc := featureCollection{Type: "FeatureCollection", Features: []*feature{}}
+	for _, e := range d.Entities {
+		g, ok := entityGeometry(e, tol)
+		if !ok {
+			continue
+		}
+		fc.Features = append(fc.Features, &feature{
+			Type:       "Feature",
+			Geometry:   g,
+			Properties: entityProperties(d, e),
+		})
+	}
+	return json.NewEncoder(w).Encode(fc)
+}
+
+// entityGeometry returns the geometry of an entity, or false if it has none
+func entityGeometry(e dxf.Entity, tol float64) (geometry, bool) {
+	switch e := e.(type) {
+	case *dxf.Line:
+		return lineString([]point{{e.Start[0], e.Start[1]}, {e.End[0], e.End[1]}})
+	case *dxf.Circle:
+		if e.Radius <= 0 {
+			return geometry{}, false
+		}
+		pts := arcPoints(point{e.Center[0], e.Center[1]}, e.Radius, 0, 2*math.Pi, tol)
+		pts[len(pts)-1] = pts[0]
+		return polygon(pts)
+	case *dxf.Arc:
+		sweep := math.Mod(e.EndAngle-e.StartAngle, 360)
+		if sweep <= 0 {
+			sweep += 360
+		}
+		return lineString(arcPoints(point{e.Center[0], e.Center[1]}, e.Radius,
+			e.StartAngle*math.Pi/180, sweep*math.Pi/180, tol))
+	case *dxf.LwPolyline:
+		n := len(e.Vertices)
+		if n == 0 {
+			return geometry{}, false
+		}
+		pts := []point{{e.Vertices[0].X, e.Vertices[0].Y}}
+		last := n - 1
+		if e.Closed {
+			last = n
+		}
+		for i := 0; i < last; i++ {
+			v, w := e.Vertices[i], e.Vertices[(i+1)%n]
+			pts = append(pts, bulgePoints(point{v.X, v.Y}, point{w.X, w.Y}, v.Bulge, tol)...)
+		}
+		if e.Closed {
+			return polygon(pts)
+		}
+		return lineString(pts)
+	case *dxf.Polyline:
+		pts := make([]point, len(e.Vertices))
+		for i, v := range e.Vertices {
+			pts[i] = point{v.X, v.Y}
+		}
+		if e.Closed && len(pts) > 0 {
+			return polygon(append(pts, pts[0]))
+		}
+		return lineString(pts)
+	case *dxf.Spline:
+		pts := splinePoints(e, tol)
+		if e.Closed && len(pts) > 0 {
+			if pts[len(pts)-1] != pts[0] {
+				pts = append(pts, pts[0])
+			}
+			return polygon(pts)
+		}
+		return lineString(pts)
+	case *dxf.Point:
+		return geometry{Type: "Point", Coordinates: point{e.Coord[0], e.Coord[1]}}, true
+	case *dxf.Text:
+		return geometry{Type: "Point", Coordinates: point{e.Point[0], e.Point[1]}}, true
+	case *dxf.MText:
+		return geometry{Type: "Point", Coordinates: point{e.Point[0], e.Point[1]}}, true
+	}
+	return geometry{}, false
+}
+
+func lineString(pts []point) (geometry, bool) {
+	if len(pts) < 2 {
+		return geometry{}, false
+	}
+	return geometry{Type: "LineString", Coordinates: pts}, true
+}
+
+// polygon returns a polygon of a closed ring, or a line string when the ring
+// has too few positions to be valid GeoJSON
+func polygon(ring []point) (geometry, bool) {
+	if len(ring) < 4 {
+		return lineString(ring)
+	}
+	return geometry{Type: "Polygon", Coordinates: [][]point{ring}}, true
+}
+
+func entityProperties(d *dxf.Drawing, e dxf.Entity) properties {
+	base := e.Common()
+	p := properties{
+		EntityType: string(base.EntityType),
+		Layer:      base.LayerName,
+		Handle:     base.Handle,
+		Color:      resolveColor(d, base),
+	}
+	if p.Color == 7 {
+		// The foreground colour, black like in the other outputs
+		p.RGB = "#000000"
+	} else {
+		c := dxf.ACIColor(p.Color)
+		p.RGB = fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	switch e := e.(type) {
+	case *dxf.Text:
+		p.Text, p.TextHeight = e.Value, e.Height
+	case *dxf.MText:
+		p.Text, p.TextHeight = e.Value, e.Height
+	}
+	return p
+}
+
+// resolveColor returns the ACI colour of an entity, looking up BYLAYER in the
+// layer table. BYBLOCK and missing layers resolve to colour 7.
+func resolveColor(d *dxf.Drawing, base *dxf.BaseEntity) int {
+	switch base.Color {
+	case dxf.ColorByLayer:
+		if l, ok := d.Layer(base.LayerName); ok {
+			return int(math.Abs(float64(l.Color)))
+		}
+		return 7
+	case dxf.ColorByBlock:
+		return 7
+	}
+	return int(math.Abs(float64(base.Color)))
+}
@@ -0,0 +1,202 @@
+package geojson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+)
+
+type decodedFeature struct {
+	Geometry struct {
+		Type        string
+		Coordinates json.RawMessage
+	}
+	Properties map[string]any
+}
+
+func write(t *testing.T, d *dxf.Drawing, opts *Options) []decodedFeature {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, d, opts); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var fc struct {
+		Type     string
+		Features []decodedFeature
+	}
+	if err := json.Unmarshal(buf.Bytes(), &fc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if fc.Type != "FeatureCollection" {
+		t.Errorf("type = %q, want FeatureCollection", fc.Type)
+	}
+	return fc.Features
+}
+
+func base(t dxf.EntityType, layer string, color int) dxf.BaseEntity {
+	return dxf.BaseEntity{EntityType: t, LayerName: layer, Color: color}
+}
+
+func TestWrite_Geometries(t *testing.T) {
+	d := &dxf.Drawing{
+		Layers: []dxf.Layer{{Name: "Walls", Color: -1, Plot: true}},
+		Entities: []dxf.Entity{
+			&dxf.Line{BaseEntity: base(dxf.LineType, "Walls", dxf.ColorByLayer), Start: [3]float64{0, 0}, End: [3]float64{10, 0}},
+			&dxf.Circle{BaseEntity: base(dxf.CircleType, "0", 3), Center: [3]float64{5, 5}, Radius: 5},
+			&dxf.LwPolyline{BaseEntity: base(dxf.LwPolylineType, "0", 7), Closed: true, Vertices: []dxf.LwPolylineVertex{
+				{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10},
+			}},
+			&dxf.Point{BaseEntity: base(dxf.PointType, "0", 7), Coord: [3]float64{1, 2}},
+			&dxf.Text{BaseEntity: base(dxf.TextType, "Notes", dxf.ColorByBlock), Point: [3]float64{3, 4}, Height: 2.5, Value: "Hi"},
+		},
+	}
+	features := write(t, d, nil)
+
+	wantTypes := []string{"LineString", "Polygon", "Polygon", "Point", "Point"}
+	if len(features) != len(wantTypes) {
+		t.Fatalf("got %d features, want %d", len(features), len(wantTypes))
+	}
+	for i, want := range wantTypes {
+		if got := features[i].Geometry.Type; got != want {
+			t.Errorf("feature %d: geometry %s, want %s", i, got, want)
+		}
+	}
+
+	// The layer is off, but its colour still applies
+	line := features[0].Properties
+	if line["layer"] != "Walls" || line["color"] != 1.0 || line["rgb"] != "#ff0000" || line["entityType"] != "LINE" {
+		t.Errorf("line properties = %v", line)
+	}
+	if got := string(features[0].Geometry.Coordinates); got != "[[0,0],[10,0]]" {
+		t.Errorf("line coordinates = %s", got)
+	}
+	if got := string(features[2].Geometry.Coordinates); got != "[[[0,0],[10,0],[10,10],[0,0]]]" {
+		t.Errorf("polygon coordinates = %s", got)
+	}
+	text := features[4].Properties
+	if text["text"] != "Hi" || text["textHeight"] != 2.5 || text["color"] != 7.0 || text["rgb"] != "#000000" {
+		t.Errorf("text properties = %v", text)
+	}
+}
+
+func TestWrite_Tolerance(t *testing.T) {
+	d := &dxf.Drawing{Entities: []dxf.Entity{
+		&dxf.Arc{BaseEntity: base(dxf.ArcType, "0", 7), Radius: 100, StartAngle: 0, EndAngle: 90},
+	}}
+	for _, tol := range []float64{1, 0.01} {
+		features := write(t, d, &Options{Tolerance: tol})
+		var pts []point
+		if err := json.Unmarshal(features[0].Geometry.Coordinates, &pts); err != nil {
+			t.Fatal(err)
+		}
+		if pts[0] != (point{100, 0}) || math.Abs(pts[len(pts)-1][0]) > 1e-9 || pts[len(pts)-1][1] != 100 {
+			t.Errorf("tolerance %v: arc runs from %v to %v", tol, pts[0], pts[len(pts)-1])
+		}
+		// The chord midpoints stay within the tolerance of the arc
+		for i := 1; i < len(pts); i++ {
+			mx, my := (pts[i-1][0]+pts[i][0])/2, (pts[i-1][1]+pts[i][1])/2
+			if dev := 100 - math.Hypot(mx, my); dev > tol {
+				t.Errorf("tolerance %v: chord %d deviates by %v", tol, i, dev)
+			}
+		}
+	}
+}
+
+func TestBulgePoints(t *testing.T) {
+	// A half circle to the right of the chord from (0, 0) to (10, 0)
+	pts := bulgePoints(point{0, 0}, point{10, 0}, -1, 0.01)
+	if pts[len(pts)-1] != (point{10, 0}) {
+		t.Errorf("bulge ends at %v", pts[len(pts)-1])
+	}
+	mid := pts[len(pts)/2-1]
+	if math.Abs(mid[0]-5) > 0.5 || math.Abs(mid[1]-5) > 0.1 {
+		t.Errorf("clockwise bulge passes through %v, want near (5, 5)", mid)
+	}
+}
+
+func TestSplinePoints(t *testing.T) {
+	// A quadratic Bézier as a clamped B-spline
+	s := &dxf.Spline{
+		Degree:        2,
+		ControlPoints: [][3]float64{{0, 0}, {5, 10}, {10, 0}},
+		Knots:         []float64{0, 0, 0, 1, 1, 1},
+	}
+	pts := splinePoints(s, 0.01)
+	if pts[0] != (point{0, 0}) || pts[len(pts)-1] != (point{10, 0}) {
+		t.Errorf("spline runs from %v to %v", pts[0], pts[len(pts)-1])
+	}
+	// The apex of the curve is at half the control point's height
+	apex := 0.0
+	for _, p := range pts {
+		apex = math.Max(apex, p[1])
+	}
+	if math.Abs(apex-5) > 0.01 {
+		t.Errorf("spline apex = %v, want 5", apex)
+	}
+
+	// Without valid knots the control polygon is used
+	s.Knots = nil
+	if pts := splinePoints(s, 0.01); len(pts) != 3 {
+		t.Errorf("got %d points, want the 3 control points", len(pts))
+	}
+}
+
+func TestConvert_Fixture(t *testing.T) {
+	f, err := os.Open("../../fixtures/overall.dxf")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if err := Convert(f, &buf, nil); err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if !json.Valid(buf.Bytes()) || !strings.Contains(buf.String(), `"type":"Feature"`) {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestConvert_InvalidTolerance(t *testing.T) {
+	err := Convert(strings.NewReader("0\nEOF\n"), &bytes.Buffer{}, &Options{Tolerance: -1})
+	var optErr *dxfconverror.OptionError
+	if !errors.As(err, &optErr) || optErr.Option != "Tolerance" {
+		t.Errorf("Convert() error = %v, want OptionError for Tolerance", err)
+	}
+}
+
+func TestConvertContext_Input(t *testing.T) {
+	input := "0\nSECTION\n2\nENTITIES\n" +
+		"0\nLINE\n8\n0\n10\n0\n20\n0\n11\n1\n21\n0\n" +
+		"0\nCIRCLE\n8\n0\n10\n0\n20\n0\n40\nabc\n" +
+		"0\nENDSEC\n0\nEOF\n"
+
+	if _, err := ConvertContext(context.Background(), strings.NewReader(input), &bytes.Buffer{}, nil); !errors.As(err, new(*dxfconverror.ParseError)) {
+		t.Errorf("strict ConvertContext() error = %v, want ParseError", err)
+	}
+
+	opts := DefaultOptions()
+	opts.Input = converter.DefaultOptions()
+	opts.Input.Lenient = true
+	var buf bytes.Buffer
+	res, err := ConvertContext(context.Background(), strings.NewReader(input), &buf, opts)
+	if err != nil || len(res.Warnings) != 1 {
+		t.Fatalf("lenient ConvertContext() = %v, %v; want one warning", res, err)
+	}
+	if !strings.Contains(buf.String(), `"LineString"`) {
+		t.Errorf("output without the line:\n%s", buf.String())
+	}
+
+	opts.Input.MaxInputBytes = 10
+	if _, err := ConvertContext(context.Background(), strings.NewReader(input), &bytes.Buffer{}, opts); !errors.As(err, new(*dxfconverror.LimitError)) {
+		t.Errorf("ConvertContext() error = %v, want LimitError", err)
+	}
+}
@@ -0,0 +1,101 @@
+package geojson
+
+import (
+	"math"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/renderers"
+)
+
+// Upper bound of the segments used for a full circle, whatever the tolerance
+const maxCircleSegments = 1024
+
+type point [2]float64
+
+// arcPoints returns the points of an arc from angle a0 sweeping by sweep
+// radians (counter-clockwise when positive), both ends included. The chords
+// deviate from the arc by at most tol.
+func arcPoints(c point, r, a0, sweep, tol float64) []point {
+	n := 1
+	if tol < r {
+		step := 2 * math.Acos(1-tol/r)
+		n = int(math.Ceil(math.Abs(sweep) / step))
+	}
+	turns := math.Abs(sweep) / (2 * math.Pi)
+	n = min(n, int(math.Ceil(turns*maxCircleSegments)))
+	n = max(n, int(math.Ceil(turns*4)), 1)
+	pts := make([]point, n+1)
+	for i := range pts {
+		a := a0 + sweep*float64(i)/float64(n)
+		pts[i] = point{c[0] + r*math.Cos(a), c[1] + r*math.Sin(a)}
+	}
+	return pts
+}
+
+// bulgePoints returns the points from p to q of a polyline segment with the
+// given bulge, without p.
+func bulgePoints(p, q point, bulge, tol float64) []point {
+	if bulge == 0 {
+		return []point{q}
+	}
+	c, r, start, sweep := renderers.BulgeArc(p, q, bulge)
+	pts := arcPoints(c, r, start*math.Pi/180, sweep*math.Pi/180, tol)
+	pts[len(pts)-1] = q
+	return pts[1:]
+}
+
+// splinePoints evaluates a B-spline from its knots. Splines with an
+// inconsistent knot vector are approximated by their control points.
+func splinePoints(s *dxf.Spline, tol float64) []point {
+	n, p := len(s.ControlPoints), s.Degree
+	cps := make([][2]float64, n)
+	for i, v := range s.ControlPoints {
+		cps[i] = [2]float64{v[0], v[1]}
+	}
+	if !renderers.ValidKnots(s) {
+		pts := make([]point, n)
+		for i, c := range cps {
+			pts[i] = c
+		}
+		return pts
+	}
+	u := s.Knots
+	eval := func(t float64) point { return renderers.SplinePoint(cps, u, p, t) }
+
+	pts := []point{eval(u[p])}
+	// Each knot span is split in four and then subdivided until flat
+	for k := p; k < n; k++ {
+		if u[k+1] <= u[k] {
+			continue
+		}
+		for i := 0; i < 4; i++ {
+			t0 := u[k] + (u[k+1]-u[k])*float64(i)/4
+			t1 := u[k] + (u[k+1]-u[k])*float64(i+1)/4
+			pts = subdivide(pts, eval, t0, t1, eval(t1), tol, 16)
+		}
+	}
+	return pts
+}
+
+// subdivide appends the points of the curve from t0 (already in pts) to t1,
+// splitting the interval while its midpoint is farther than tol from the chord.
+func subdivide(pts []point, eval func(float64) point, t0, t1 float64, end point, tol float64, depth int) []point {
+	mid := (t0 + t1) / 2
+	m := eval(mid)
+	if depth > 0 && chordDistance(pts[len(pts)-1], end, m) > tol {
+		pts = subdivide(pts, eval, t0, mid, m, tol, depth-1)
+		return subdivide(pts, eval, mid, t1, end, tol, depth-1)
+	}
+	return append(pts, end)
+}
+
+// chordDistance returns the distance of m from the segment a-b
+func chordDistance(a, b, m point) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	l := dx*dx + dy*dy
+	if l == 0 {
+		return math.Hypot(m[0]-a[0], m[1]-a[1])
+	}
+	t := math.Max(0, math.Min(1, ((m[0]-a[0])*dx+(m[1]-a[1])*dy)/l))
+	return math.Hypot(m[0]-a[0]-t*dx, m[1]-a[1]-t*dy)
+}
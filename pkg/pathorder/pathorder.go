@@ -0,0 +1,188 @@
+// Package pathorder orders the paths of plotter and machine output so that
+// the travel from the end of one path to the start of the next stays short.
+package pathorder
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Path gives the end points of a path to order
+type Path struct {
+	Start, End [2]float64
+	// Reversible paths may also be traversed from End to Start
+	Reversible bool
+}
+
+// Step is an entry of an order: the index of a path in the input and
+// whether it is traversed from its end
+type Step struct {
+	Index    int
+	Reversed bool
+}
+
+// Nearest orders the paths greedily: from the position from, it travels to
+// the nearest start of a path, or end of a reversible path, traverses that
+// path and repeats. Ties go to the path that comes first, then to its start.
+//
+// The end points are kept in a k-d tree, so that each step takes about
+// logarithmic rather than linear time in the number of paths.
+func Nearest(paths []Path, from [2]float64) []Step {
+	t := newTree(paths)
+	order := make([]Step, 0, len(paths))
+	pos := from
+	next := 0 // first path that may not be visited yet
+	for range paths {
+		n, ok := t.nearest(pos)
+		if !ok {
+			// Only points at infinite or NaN distance are left: take the
+			// paths in input order
+			for t.visited[next] {
+				next++
+			}
+			n = node{path: next}
+		}
+		t.remove(n.path)
+		p := paths[n.path]
+		order = append(order, Step{Index: n.path, Reversed: n.end})
+		pos = p.End
+		if n.end {
+			pos = p.Start
+		}
+	}
+	return order
+}
+
+// node is an end point of a path in the tree
+type node struct {
+	p    [2]float64
+	path int
+	end  bool
+	// live counts the points of the subtree rooted here that are not removed
+	live   int
+	dead   bool
+	parent int
+}
+
+// before reports whether n takes precedence over m at the same distance
+func (n *node) before(m *node) bool {
+	if n.path != m.path {
+		return n.path < m.path
+	}
+	return !n.end && m.end
+}
+
+// tree is a k-d tree stored in an array: the root of the subtree of the
+// range [lo, hi) is at the middle index, its children cover the two halves.
+// Splits alternate between x and y.
+type tree struct {
+	nodes   []node
+	visited []bool
+	// index of the start and end node of each path, -1 if none
+	starts, ends []int
+}
+
+func newTree(paths []Path) *tree {
+	t := &tree{
+		visited: make([]bool, len(paths)),
+		starts:  make([]int, len(paths)),
+		ends:    make([]int, len(paths)),
+	}
+	for i, p := range paths {
+		t.nodes = append(t.nodes, node{p: p.Start, path: i})
+		if p.Reversible {
+			t.nodes = append(t.nodes, node{p: p.End, path: i, end: true})
+		}
+	}
+	t.build(0, len(t.nodes), 0, -1)
+	for i := range t.ends {
+		t.ends[i] = -1
+	}
+	for i, n := range t.nodes {
+		if n.end {
+			t.ends[n.path] = i
+		} else {
+			t.starts[n.path] = i
+		}
+	}
+	return t
+}
+
+func (t *tree) build(lo, hi, axis, parent int) {
+	if lo >= hi {
+		return
+	}
+	nodes := t.nodes[lo:hi]
+	slices.SortFunc(nodes, func(a, b node) int {
+		if c := cmp.Compare(a.p[axis], b.p[axis]); c != 0 {
+			return c
+		}
+		switch {
+		case a.before(&b):
+			return -1
+		case b.before(&a):
+			return 1
+		}
+		return 0
+	})
+	mid := (lo + hi) / 2
+	t.nodes[mid].live = hi - lo
+	t.nodes[mid].parent = parent
+	t.build(lo, mid, 1-axis, mid)
+	t.build(mid+1, hi, 1-axis, mid)
+}
+
+// remove marks both end points of the path as visited
+func (t *tree) remove(path int) {
+	t.visited[path] = true
+	for _, i := range []int{t.starts[path], t.ends[path]} {
+		if i < 0 {
+			continue
+		}
+		t.nodes[i].dead = true
+		for ; i >= 0; i = t.nodes[i].parent {
+			t.nodes[i].live--
+		}
+	}
+}
+
+// nearest returns the point nearest to q that is not removed. It reports
+// false if there is none at a finite distance.
+func (t *tree) nearest(q [2]float64) (node, bool) {
+	best := -1
+	bestDist := math.Inf(1)
+	var search func(lo, hi, axis int)
+	search = func(lo, hi, axis int) {
+		if lo >= hi {
+			return
+		}
+		mid := (lo + hi) / 2
+		n := &t.nodes[mid]
+		if n.live == 0 {
+			return
+		}
+		if !n.dead {
+			dx, dy := q[0]-n.p[0], q[1]-n.p[1]
+			if d := dx*dx + dy*dy; d < bestDist || d == bestDist && best >= 0 && n.before(&t.nodes[best]) {
+				best, bestDist = mid, d
+			}
+		}
+		// The far side can only be skipped if it is farther than the best
+		// point; a NaN coordinate tells nothing
+		diff := q[axis] - n.p[axis]
+		near, far := [2]int{mid + 1, hi}, [2]int{lo, mid}
+		if diff < 0 {
+			near, far = far, near
+		}
+		search(near[0], near[1], 1-axis)
+		if !(diff*diff > bestDist) {
+			search(far[0], far[1], 1-axis)
+		}
+	}
+	search(0, len(t.nodes), 0)
+	if best < 0 {
+		return node{}, false
+	}
+	return t.nodes[best], true
+}
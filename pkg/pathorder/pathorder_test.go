@@ -0,0 +1,101 @@
+package pathorder
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// bruteForce is Nearest by linear search
+func bruteForce(paths []Path, from [2]float64) []Step {
+	visited := make([]bool, len(paths))
+	var order []Step
+	pos := from
+	for range paths {
+		best, bestDist := Step{Index: -1}, math.Inf(1)
+		for i, p := range paths {
+			if visited[i] {
+				continue
+			}
+			ends := []Step{{i, false}}
+			if p.Reversible {
+				ends = append(ends, Step{i, true})
+			}
+			for _, s := range ends {
+				q := p.Start
+				if s.Reversed {
+					q = p.End
+				}
+				dx, dy := pos[0]-q[0], pos[1]-q[1]
+				if d := dx*dx + dy*dy; d < bestDist {
+					best, bestDist = s, d
+				}
+			}
+		}
+		if best.Index < 0 {
+			best.Index = slices.Index(visited, false)
+		}
+		visited[best.Index] = true
+		order = append(order, best)
+		pos = paths[best.Index].End
+		if best.Reversed {
+			pos = paths[best.Index].Start
+		}
+	}
+	return order
+}
+
+func TestNearest(t *testing.T) {
+	paths := []Path{
+		{Start: [2]float64{10, 0}, End: [2]float64{20, 0}},
+		{Start: [2]float64{0, 5}, End: [2]float64{0, 1}, Reversible: true},
+		{Start: [2]float64{20, 1}, End: [2]float64{30, 1}, Reversible: true},
+		{Start: [2]float64{30, 1}, End: [2]float64{40, 1}},
+	}
+	want := []Step{{1, true}, {0, false}, {2, false}, {3, false}}
+	if got := Nearest(paths, [2]float64{0, 0}); !slices.Equal(got, want) {
+		t.Errorf("Nearest() = %v, want %v", got, want)
+	}
+	if got := Nearest(nil, [2]float64{}); len(got) != 0 {
+		t.Errorf("Nearest(nil) = %v", got)
+	}
+}
+
+func TestNearest_BruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := range 500 {
+		n := rng.Intn(60)
+		// A small grid makes ties and shared end points common
+		size := 1 + rng.Intn(8)
+		pt := func() [2]float64 {
+			return [2]float64{float64(rng.Intn(size)), float64(rng.Intn(size))}
+		}
+		paths := make([]Path, n)
+		for i := range paths {
+			paths[i] = Path{Start: pt(), End: pt(), Reversible: rng.Intn(3) > 0}
+		}
+		if round%10 == 0 && n > 0 {
+			paths[rng.Intn(n)].End = [2]float64{math.NaN(), math.Inf(1)}
+			paths[rng.Intn(n)].Start = [2]float64{math.Inf(-1), 0}
+			paths[rng.Intn(n)].Start = [2]float64{1, math.NaN()}
+		}
+		from := pt()
+		got, want := Nearest(paths, from), bruteForce(paths, from)
+		if !slices.Equal(got, want) {
+			t.Fatalf("round %d: Nearest() = %v, want %v for %v from %v", round, got, want, paths, from)
+		}
+	}
+}
+
+func BenchmarkNearest(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	paths := make([]Path, 40000)
+	for i := range paths {
+		x, y := rng.Float64()*1000, rng.Float64()*1000
+		paths[i] = Path{Start: [2]float64{x, y}, End: [2]float64{x + rng.Float64()*10, y + rng.Float64()*10}, Reversible: true}
+	}
+	for b.Loop() {
+		Nearest(paths, [2]float64{})
+	}
+}
@@ -0,0 +1,147 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAppendNum(t *testing.T) {
+	tests := []struct {
+		v    float64
+		prec int
+		want string
+	}{
+		{10, 2, "10"},
+		{1.5, 2, "1.5"},
+		{0.125, 3, "0.125"},
+		{2.005, 2, "2"},
+		{-0.001, 2, "0"},
+		{-3.1, 2, "-3.1"},
+		{100, 0, "100"},
+	}
+	for _, tt := range tests {
+		if got := formatNum(tt.v, tt.prec); got != tt.want {
+			t.Errorf("formatNum(%v, %d) = %q, want %q", tt.v, tt.prec, got, tt.want)
+		}
+	}
+}
+
+// stream returns the decompressed data of the stream of object id
+func stream(t *testing.T, pdf []byte, id int) (string, []byte) {
+	t.Helper()
+	re := regexp.MustCompile(fmt.Sprintf(`(?s)\n%d 0 obj\n(<<.*?>>)\nstream\n`, id))
+	m := re.FindSubmatchIndex(pdf)
+	if m == nil {
+		t.Fatalf("object %d not found", id)
+	}
+	dict := string(pdf[m[2]:m[3]])
+	length, err := strconv.Atoi(regexp.MustCompile(`/Length (\d+)`).FindStringSubmatch(dict)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pdf[m[1] : m[1]+length]
+	if !strings.Contains(dict, "/FlateDecode") {
+		return dict, data
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("object %d: %v", id, err)
+	}
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("object %d: %v", id, err)
+	}
+	return dict, data
+}
+
+func TestPDF_Compress(t *testing.T) {
+	p := New(100, 100)
+	p.Line(10, 10, 90, 90)
+	p.Compress = true
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "%PDF-1.4\n%") {
+		t.Errorf("header = %.12q", buf.String())
+	}
+	if _, content := stream(t, buf.Bytes(), 4); string(content) != "10 90 m 90 10 l S\n" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestPDF_ObjectStreams(t *testing.T) {
+	p := New(100, 100)
+	p.Line(10, 10, 90, 90)
+	p.AddLayer("WALLS", true)
+	p.Compress = true
+	p.ObjectStreams = true
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.5\n")) {
+		t.Errorf("header = %.12q", out)
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("startxref not found")
+	}
+	xrefOffset, _ := strconv.Atoi(string(m[1]))
+	xrefObj := regexp.MustCompile(`^(\d+) 0 obj\n`).FindSubmatch(out[xrefOffset:])
+	if xrefObj == nil {
+		t.Fatalf("no object at startxref %d", xrefOffset)
+	}
+	xrefID, _ := strconv.Atoi(string(xrefObj[1]))
+	dict, xref := stream(t, out, xrefID)
+	w := regexp.MustCompile(`/W \[1 (\d) 2\]`).FindStringSubmatch(dict)
+	if w == nil || !strings.Contains(dict, "/Type /XRef") || !strings.Contains(dict, "/Root 1 0 R") {
+		t.Fatalf("xref dictionary = %s", dict)
+	}
+	width, _ := strconv.Atoi(w[1])
+	size := 3 + width
+	if len(xref) != size*(xrefID+1) {
+		t.Fatalf("xref stream has %d bytes for %d objects", len(xref), xrefID+1)
+	}
+
+	var inStream []int
+	objStmID := 0
+	for id := 1; id <= xrefID; id++ {
+		e := xref[id*size : (id+1)*size]
+		field := 0
+		for _, b := range e[1 : 1+width] {
+			field = field<<8 | int(b)
+		}
+		switch e[0] {
+		case 1:
+			if want := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(out[field:], []byte(want)) {
+				t.Errorf("object %d not at offset %d", id, field)
+			}
+		case 2:
+			inStream = append(inStream, id)
+			objStmID = field
+		default:
+			t.Errorf("object %d has entry type %d", id, e[0])
+		}
+	}
+	// Catalog, pages, page, font and layer are compressed
+	if len(inStream) != 5 {
+		t.Fatalf("objects in object stream = %v", inStream)
+	}
+	dict, objects := stream(t, out, objStmID)
+	if !strings.Contains(dict, "/Type /ObjStm /N 5") {
+		t.Errorf("object stream dictionary = %s", dict)
+	}
+	first, _ := strconv.Atoi(regexp.MustCompile(`/First (\d+)`).FindStringSubmatch(dict)[1])
+	if !bytes.HasPrefix(objects[first:], []byte("<< /Type /Catalog")) {
+		t.Errorf("first object = %.30q", objects[first:])
+	}
+}
@@ -0,0 +1,93 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// sRGBProfile returns an ICC version 2 display profile of the sRGB colour
+// space (IEC 61966-2.1), for the output intent of PDF/A
+func sRGBProfile() []byte {
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	xyz := func(x, y, z float64) []byte {
+		b := []byte("XYZ \x00\x00\x00\x00")
+		for _, v := range []float64{x, y, z} {
+			b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(v*65536))))
+		}
+		return b
+	}
+	text := func(typ, s string) []byte {
+		b := []byte(typ + "\x00\x00\x00\x00")
+		if typ == "desc" {
+			b = binary.BigEndian.AppendUint32(b, uint32(len(s)+1))
+		}
+		b = append(b, s...)
+		b = append(b, 0)
+		if typ == "desc" {
+			// Empty Unicode and ScriptCode descriptions
+			b = append(b, make([]byte, 4+4+2+1+67)...)
+		}
+		return b
+	}
+	// The sRGB transfer function, sampled
+	trc := []byte("curv\x00\x00\x00\x00")
+	const samples = 1024
+	trc = binary.BigEndian.AppendUint32(trc, samples)
+	for i := range samples {
+		v := float64(i) / (samples - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		trc = binary.BigEndian.AppendUint16(trc, uint16(math.Round(v*65535)))
+	}
+
+	// Primaries adapted to the D50 illuminant of the profile connection space
+	tags := []tag{
+		{"desc", text("desc", "sRGB IEC61966-2.1")},
+		{"cprt", text("text", "No copyright, use freely")},
+		{"wtpt", xyz(0.9642, 1, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntrRGB XYZ ")
+	for i, v := range []uint16{2000, 1, 1, 0, 0, 0} {
+		binary.BigEndian.PutUint16(header[24+2*i:], v)
+	}
+	copy(header[36:], "acsp")
+	copy(header[68:], xyz(0.9642, 1, 0.8249)[8:])
+
+	b := binary.BigEndian.AppendUint32(header, uint32(len(tags)))
+	offset := len(b) + 12*len(tags)
+	var data []byte
+	offsets := make(map[string]int) // tags sharing their data
+	for _, t := range tags {
+		o, ok := offsets[string(t.data)]
+		if !ok {
+			o = offset + len(data)
+			offsets[string(t.data)] = o
+			data = append(data, t.data...)
+			// Tag data starts on four-byte boundaries
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+		b = append(b, t.sig...)
+		b = binary.BigEndian.AppendUint32(b, uint32(o))
+		b = binary.BigEndian.AppendUint32(b, uint32(len(t.data)))
+	}
+	b = append(b, data...)
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
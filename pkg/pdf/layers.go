@@ -0,0 +1,76 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// layer is an optional content group (OCG)
+type layer struct {
+	name    string
+	visible bool
+}
+
+// AddLayer registers an optional content group and returns its id for BeginLayer.
+// Viewers list the group under name and show it initially if visible is true.
+func (p *PDF) AddLayer(name string, visible bool) int {
+	p.layers = append(p.layers, layer{name: name, visible: visible})
+	return len(p.layers) - 1
+}
+
+// BeginLayer starts a marked-content section whose content belongs to the layer id
+func (p *PDF) BeginLayer(id int) {
+	p.currentBuf.WriteString(fmt.Sprintf("/OC /oc%d BDC\n", id))
+}
+
+// EndLayer ends the marked-content section started by BeginLayer
+func (p *PDF) EndLayer() {
+	p.currentBuf.WriteString("EMC\n")
+}
+
+// ocProperties returns the /OCProperties catalog entry and the /Properties page resource
+// for the layers, whose objects are numbered from firstID.
+func (p *PDF) ocProperties(firstID int) (catalog, resources string) {
+	var refs, off, props strings.Builder
+	for i, l := range p.layers {
+		ref := fmt.Sprintf("%d 0 R", firstID+i)
+		refs.WriteString(" " + ref)
+		if !l.visible {
+			off.WriteString(" " + ref)
+		}
+		props.WriteString(fmt.Sprintf(" /oc%d %s", i, ref))
+	}
+	// PDF/A requires a name for the default configuration
+	catalog = fmt.Sprintf(" /OCProperties << /OCGs [%s ] /D << /Name (Layers) /Order [%s ] /OFF [%s ] >> >>", refs.String(), refs.String(), off.String())
+	resources = fmt.Sprintf(" /Properties <<%s >>", props.String())
+	return catalog, resources
+}
+
+// textString encodes s as a PDF text string: a literal string for ASCII,
+// UTF-16BE with a byte order mark otherwise.
+func textString(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + escapeString(s) + ")"
+	}
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		b.WriteString(fmt.Sprintf("%04X", u))
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// escapeString escapes the characters with a special meaning in PDF literal strings
+func escapeString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\r", `\r`, "\n", `\n`)
+	return r.Replace(s)
+}
@@ -0,0 +1,61 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPDF_Layers(t *testing.T) {
+	p := New(100, 100)
+	dims := p.AddLayer("DIM", true)
+	hidden := p.AddLayer("Hidden (old)", false)
+
+	p.BeginLayer(dims)
+	p.Line(0, 0, 10, 10)
+	p.EndLayer()
+	p.BeginLayer(hidden)
+	p.Line(0, 0, 20, 20)
+	p.EndLayer()
+
+	got := p.currentBuf.String()
+	if !strings.HasPrefix(got, "/OC /oc0 BDC\n") || !strings.Contains(got, "EMC\n/OC /oc1 BDC\n") {
+		t.Errorf("content should wrap drawing in marked-content sections, got %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		t.Fatalf("Output() error = %v", err)
+	}
+	output := buf.String()
+	checks := []string{
+		"%PDF-1.5",
+		"/OCProperties << /OCGs [ 6 0 R 7 0 R ]",
+		"/OFF [ 7 0 R ]",
+		"/Properties << /oc0 6 0 R /oc1 7 0 R >>",
+		"6 0 obj\n<< /Type /OCG /Name (DIM) >>",
+		"7 0 obj\n<< /Type /OCG /Name (Hidden \\(old\\)) >>",
+		"trailer\n<< /Size 8 ",
+	}
+	for _, check := range checks {
+		if !strings.Contains(output, check) {
+			t.Errorf("Output() missing %q", check)
+		}
+	}
+}
+
+func TestTextString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Layer 1", "(Layer 1)"},
+		{`a\b`, `(a\\b)`},
+		{"寸法", "<FEFF5BF86CD5>"},
+	}
+	for _, tt := range tests {
+		if got := textString(tt.in); got != tt.want {
+			t.Errorf("textString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package pdf
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrFontNotEmbedded is returned by Output for PDF/A documents with text.
+// Text is set in the standard Helvetica font, which is not embedded.
+var ErrFontNotEmbedded = errors.New("pdf: PDF/A requires embedded fonts, but text uses the standard Helvetica font")
+
+// Info is the document information shown by viewers, written to the
+// document information dictionary and the XMP metadata
+type Info struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	// Creator is the application the document was made with, Producer the
+	// one that converted it to PDF
+	Creator  string
+	Producer string
+	// Zero dates are omitted
+	CreationDate time.Time
+	ModDate      time.Time
+}
+
+func (i *Info) isZero() bool {
+	return *i == Info{}
+}
+
+// ViewerPreferences control how viewers present the document when it is opened
+type ViewerPreferences struct {
+	// FitPage opens the page zoomed to fit the window
+	FitPage bool
+	// FitWindow resizes the window to the page
+	FitWindow bool
+	// CenterWindow centers the window on the screen
+	CenterWindow bool
+	// DisplayDocTitle shows Info.Title instead of the file name in the title bar
+	DisplayDocTitle bool
+	// HideToolbar and HideMenubar hide the toolbar and menu bar of the viewer
+	HideToolbar bool
+	HideMenubar bool
+}
+
+// catalog returns the /ViewerPreferences and /OpenAction catalog entries
+func (v *ViewerPreferences) catalog() string {
+	var prefs strings.Builder
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{v.FitWindow, "FitWindow"},
+		{v.CenterWindow, "CenterWindow"},
+		{v.DisplayDocTitle, "DisplayDocTitle"},
+		{v.HideToolbar, "HideToolbar"},
+		{v.HideMenubar, "HideMenubar"},
+	} {
+		if f.set {
+			prefs.WriteString(" /" + f.name + " true")
+		}
+	}
+	var s string
+	if prefs.Len() > 0 {
+		s = " /ViewerPreferences <<" + prefs.String() + " >>"
+	}
+	if v.FitPage {
+		s += " /OpenAction [3 0 R /Fit]"
+	}
+	return s
+}
+
+// infoDict returns the document information dictionary
+func (i *Info) infoDict() string {
+	var b strings.Builder
+	b.WriteString("<<")
+	for _, e := range []struct{ key, value string }{
+		{"Title", i.Title},
+		{"Author", i.Author},
+		{"Subject", i.Subject},
+		{"Keywords", i.Keywords},
+		{"Creator", i.Creator},
+		{"Producer", i.Producer},
+	} {
+		if e.value != "" {
+			b.WriteString(" /" + e.key + " " + textString(e.value))
+		}
+	}
+	if !i.CreationDate.IsZero() {
+		b.WriteString(" /CreationDate " + pdfDate(i.CreationDate))
+	}
+	if !i.ModDate.IsZero() {
+		b.WriteString(" /ModDate " + pdfDate(i.ModDate))
+	}
+	b.WriteString(" >>")
+	return b.String()
+}
+
+// pdfDate formats t as a PDF date string, e.g. (D:20240131120000+01'00')
+func pdfDate(t time.Time) string {
+	s := t.Format("D:20060102150405")
+	_, offset := t.Zone()
+	if offset == 0 {
+		return "(" + s + "Z)"
+	}
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("(%s%c%02d'%02d')", s, sign, offset/3600, offset/60%60)
+}
+
+// xmp returns the XMP metadata packet with the document information, and
+// the PDF/A identification if pdfa is set
+func (i *Info) xmp(pdfa bool) []byte {
+	var b strings.Builder
+	esc := func(s string) string {
+		var e strings.Builder
+		xml.EscapeText(&e, []byte(s))
+		return e.String()
+	}
+	// Text in the default language
+	alt := func(name, s string) {
+		if s != "" {
+			fmt.Fprintf(&b, "<%s><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></%s>\n", name, esc(s), name)
+		}
+	}
+	simple := func(name, s string) {
+		if s != "" {
+			fmt.Fprintf(&b, "<%s>%s</%s>\n", name, esc(s), name)
+		}
+	}
+	date := func(name string, t time.Time) {
+		if !t.IsZero() {
+			simple(name, t.Format(time.RFC3339))
+		}
+	}
+
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("<rdf:Description rdf:about=\"\"" +
+		" xmlns:dc=\"http://purl.org/dc/elements/1.1/\"" +
+		" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\"" +
+		" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\"")
+	if pdfa {
+		b.WriteString(" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\"")
+	}
+	b.WriteString(">\n")
+	simple("dc:format", "application/pdf")
+	alt("dc:title", i.Title)
+	if i.Author != "" {
+		fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", esc(i.Author))
+	}
+	alt("dc:description", i.Subject)
+	simple("pdf:Keywords", i.Keywords)
+	simple("pdf:Producer", i.Producer)
+	simple("xmp:CreatorTool", i.Creator)
+	date("xmp:CreateDate", i.CreationDate)
+	date("xmp:ModifyDate", i.ModDate)
+	if pdfa {
+		simple("pdfaid:part", "2")
+		simple("pdfaid:conformance", "B")
+	}
+	b.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return []byte(b.String())
+}
+
+// fileID returns the file identifier of the trailer, derived from the
+// content and the document information
+func (p *PDF) fileID() string {
+	h := md5.New()
+	h.Write(p.currentBuf.Bytes())
+	h.Write([]byte(p.Info.infoDict()))
+	id := fmt.Sprintf("<%x>", h.Sum(nil))
+	return " /ID [" + id + " " + id + "]"
+}
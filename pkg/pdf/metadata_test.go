@@ -0,0 +1,155 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPDFDate(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(2024, 1, 31, 12, 0, 5, 0, time.UTC), "(D:20240131120005Z)"},
+		{time.Date(2024, 1, 31, 12, 0, 5, 0, time.FixedZone("", 5*3600+30*60)), "(D:20240131120005+05'30')"},
+		{time.Date(2024, 1, 31, 12, 0, 5, 0, time.FixedZone("", -3*3600)), "(D:20240131120005-03'00')"},
+	}
+	for _, tt := range tests {
+		if got := pdfDate(tt.t); got != tt.want {
+			t.Errorf("pdfDate(%v) = %s, want %s", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestPDF_Info(t *testing.T) {
+	p := New(100, 100)
+	p.Line(10, 10, 90, 90)
+	created := time.Date(2024, 1, 31, 12, 0, 0, 0, time.FixedZone("", 3600))
+	p.Info = Info{Title: "Plan <Ground floor>", Author: "Ösel", Producer: "dxfconv", CreationDate: created}
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dicts, err := validate(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "<< /Title (Plan <Ground floor>) /Author <FEFF00D600730065006C> /Producer (dxfconv) /CreationDate (D:20240131120000+01'00') >>"; dicts[6] != want {
+		t.Errorf("info = %s, want %s", dicts[6], want)
+	}
+	if !strings.Contains(dicts[1], "/Metadata 7 0 R") {
+		t.Errorf("catalog = %s", dicts[1])
+	}
+	if !regexp.MustCompile(`trailer\n<< /Size 8 /Root 1 0 R /Info 6 0 R /ID \[<[0-9a-f]{32}> <[0-9a-f]{32}>\] >>`).Match(buf.Bytes()) {
+		t.Errorf("trailer without /Info and /ID:\n%s", buf.Bytes())
+	}
+	_, xmp := stream(t, buf.Bytes(), 7)
+	for _, want := range []string{
+		`<rdf:li xml:lang="x-default">Plan &lt;Ground floor&gt;</rdf:li>`,
+		`<dc:creator><rdf:Seq><rdf:li>Ösel</rdf:li></rdf:Seq></dc:creator>`,
+		`<xmp:CreateDate>2024-01-31T12:00:00+01:00</xmp:CreateDate>`,
+		`<pdf:Producer>dxfconv</pdf:Producer>`,
+	} {
+		if !strings.Contains(string(xmp), want) {
+			t.Errorf("XMP missing %s:\n%s", want, xmp)
+		}
+	}
+	if strings.Contains(string(xmp), "pdfaid") {
+		t.Errorf("XMP claims PDF/A conformance:\n%s", xmp)
+	}
+}
+
+func TestPDF_PDFA(t *testing.T) {
+	for _, objectStreams := range []bool{false, true} {
+		p := New(100, 100)
+		p.BeginLayer(p.AddLayer("WALLS", true))
+		p.Line(10, 10, 90, 90)
+		p.EndLayer()
+		p.PDFA = true
+		p.Compress, p.ObjectStreams = objectStreams, objectStreams
+		var buf bytes.Buffer
+		if err := p.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.Bytes()
+		dicts, err := validate(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The binary comment after the header line
+		if c := out[9:15]; c[0] != '%' || c[1] < 0x80 || c[2] < 0x80 || c[3] < 0x80 || c[4] < 0x80 || c[5] != '\n' {
+			t.Errorf("header = %.16q", out)
+		}
+		if !strings.Contains(dicts[1], "/Metadata 8 0 R /OutputIntents [<< /Type /OutputIntent /S /GTS_PDFA1 ") ||
+			!strings.Contains(dicts[1], "/DestOutputProfile 9 0 R >>]") ||
+			!strings.Contains(dicts[1], "/D << /Name (Layers) ") {
+			t.Errorf("catalog = %s", dicts[1])
+		}
+		if strings.Contains(dicts[3], "/Font") {
+			t.Errorf("page = %s", dicts[3])
+		}
+		if _, xmp := stream(t, out, 8); !strings.Contains(string(xmp), "<pdfaid:part>2</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>") {
+			t.Errorf("XMP without PDF/A identification:\n%s", xmp)
+		}
+		if dict, profile := stream(t, out, 9); !strings.HasPrefix(dict, "<< /N 3 ") || !bytes.Equal(profile, sRGBProfile()) {
+			t.Errorf("profile %s of %d bytes", dict, len(profile))
+		}
+	}
+}
+
+func TestPDF_PDFAText(t *testing.T) {
+	p := New(100, 100)
+	chunk := p.Chunk()
+	chunk.Text(10, 10, 5, "A")
+	p.Append(chunk)
+	p.PDFA = true
+	var buf bytes.Buffer
+	if err := p.Output(&buf); !errors.Is(err, ErrFontNotEmbedded) {
+		t.Errorf("Output() error = %v, want %v", err, ErrFontNotEmbedded)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %d bytes", buf.Len())
+	}
+}
+
+func TestPDF_ViewerPreferences(t *testing.T) {
+	p := New(100, 100)
+	p.ViewerPreferences = ViewerPreferences{FitPage: true, FitWindow: true, DisplayDocTitle: true}
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "<< /Type /Catalog /Pages 2 0 R /ViewerPreferences << /FitWindow true /DisplayDocTitle true >> /OpenAction [3 0 R /Fit] >>"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %s:\n%s", want, buf.String())
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	b := sRGBProfile()
+	be := binary.BigEndian
+	if int(be.Uint32(b)) != len(b) || string(b[36:40]) != "acsp" || string(b[12:24]) != "mntrRGB XYZ " {
+		t.Fatalf("header = %q", b[:128])
+	}
+	n := int(be.Uint32(b[128:]))
+	tags := make(map[string]bool)
+	for i := range n {
+		e := b[132+12*i:]
+		sig, offset, size := string(e[:4]), int(be.Uint32(e[4:])), int(be.Uint32(e[8:]))
+		if offset%4 != 0 || offset+size > len(b) {
+			t.Errorf("tag %s at %d of %d bytes", sig, offset, size)
+		}
+		tags[sig] = true
+	}
+	for _, sig := range []string{"desc", "cprt", "wtpt", "rXYZ", "gXYZ", "bXYZ", "rTRC", "gTRC", "bTRC"} {
+		if !tags[sig] {
+			t.Errorf("missing tag %s", sig)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package pdf
+
+import (
+	"math"
+	"strconv"
+)
+
+// LineJoin is the shape of the corners of stroked paths
+type LineJoin int
+
+const (
+	MiterJoin LineJoin = iota
+	RoundJoin
+	BevelJoin
+)
+
+// LineCap is the shape of the ends of stroked open paths
+type LineCap int
+
+const (
+	ButtCap LineCap = iota
+	RoundCap
+	SquareCap
+)
+
+// SetLineJoin sets the shape of the corners of subsequent strokes
+func (p *PDF) SetLineJoin(j LineJoin) {
+	p.currentBuf.WriteString(strconv.Itoa(int(j)) + " j\n")
+}
+
+// SetLineCap sets the shape of the ends of subsequent strokes
+func (p *PDF) SetLineCap(c LineCap) {
+	p.currentBuf.WriteString(strconv.Itoa(int(c)) + " J\n")
+}
+
+// Paths are built with MoveTo, LineTo, CurveTo, ArcTo and ClosePath, and
+// painted with Stroke, Fill or FillStroke. Like the drawing methods, they
+// take coordinates with the origin at the top-left corner of the page.
+
+// writePoint writes the point x, y in PDF coordinates
+func (p *PDF) writePoint(x, y float64) {
+	p.writeNums(coordPrecision, x*p.k, p.height-y*p.k)
+}
+
+// MoveTo starts a new subpath at x, y
+func (p *PDF) MoveTo(x, y float64) {
+	p.writePoint(x, y)
+	p.currentBuf.WriteString("m\n")
+	p.current, p.last, p.first = true, [2]float64{x, y}, [2]float64{x, y}
+}
+
+// LineTo adds a line from the current point to x, y
+func (p *PDF) LineTo(x, y float64) {
+	p.writePoint(x, y)
+	p.currentBuf.WriteString("l\n")
+	p.last = [2]float64{x, y}
+}
+
+// CurveTo adds a cubic Bezier curve from the current point to x3, y3 with
+// the control points x1, y1 and x2, y2
+func (p *PDF) CurveTo(x1, y1, x2, y2, x3, y3 float64) {
+	p.writePoint(x1, y1)
+	p.writePoint(x2, y2)
+	p.writePoint(x3, y3)
+	p.currentBuf.WriteString("c\n")
+	p.last = [2]float64{x3, y3}
+}
+
+// ArcTo adds an arc of the circle around x, y with radius r from startAngle
+// to endAngle, in degrees clockwise on the page. The arc sweeps clockwise if
+// endAngle is larger, counter-clockwise otherwise. A line connects the
+// current point to the start of the arc; without a current point a new
+// subpath starts there. The arc is approximated by Bezier curves of at most
+// 90 degrees each.
+func (p *PDF) ArcTo(x, y, r, startAngle, endAngle float64) {
+	a := startAngle * math.Pi / 180
+	sweep := (endAngle - startAngle) * math.Pi / 180
+	if !(math.Abs(sweep) <= 2*math.Pi) {
+		// More than a full circle retraces it
+		sweep = math.Copysign(2*math.Pi, sweep)
+	}
+	sx, sy := x+r*math.Cos(a), y+r*math.Sin(a)
+	const eps = 1e-9
+	switch {
+	case !p.current:
+		p.MoveTo(sx, sy)
+	case math.Abs(p.last[0]-sx) > eps || math.Abs(p.last[1]-sy) > eps:
+		p.LineTo(sx, sy)
+	}
+
+	n := max(math.Ceil(math.Abs(sweep)/(math.Pi/2)), 1)
+	d := sweep / n
+	// Distance of the control points from the ends, relative to r
+	k := 4.0 / 3 * math.Tan(d/4)
+	for i := 0; i < int(n); i++ {
+		b := a + d
+		cosA, sinA := math.Cos(a), math.Sin(a)
+		cosB, sinB := math.Cos(b), math.Sin(b)
+		p.CurveTo(
+			x+r*(cosA-k*sinA), y+r*(sinA+k*cosA),
+			x+r*(cosB+k*sinB), y+r*(sinB-k*cosB),
+			x+r*cosB, y+r*sinB,
+		)
+		a = b
+	}
+}
+
+// ClosePath closes the current subpath with a line to its start
+func (p *PDF) ClosePath() {
+	p.currentBuf.WriteString("h\n")
+	p.last = p.first
+}
+
+// Stroke strokes the path and ends it
+func (p *PDF) Stroke() {
+	p.paint("S\n")
+}
+
+// Fill fills the path using the non-zero winding rule and ends it
+func (p *PDF) Fill() {
+	p.paint("f\n")
+}
+
+// FillStroke fills and then strokes the path and ends it
+func (p *PDF) FillStroke() {
+	p.paint("B\n")
+}
+
+func (p *PDF) paint(op string) {
+	p.currentBuf.WriteString(op)
+	p.current = false
+}
@@ -0,0 +1,60 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPDF_Path(t *testing.T) {
+	p := New(100, 100)
+	p.SetLineJoin(RoundJoin)
+	p.SetLineCap(SquareCap)
+	p.MoveTo(10, 10)
+	p.LineTo(90, 10)
+	p.LineTo(90, 90)
+	p.ClosePath()
+	p.FillStroke()
+	p.MoveTo(0, 0)
+	p.CurveTo(10, 0, 20, 10, 20, 20)
+	p.Fill()
+
+	got := p.currentBuf.String()
+	want := "1 j\n2 J\n" +
+		"10 90 m\n90 90 l\n90 10 l\nh\nB\n" +
+		"0 100 m\n10 100 20 90 20 80 c\nf\n"
+	if got != want {
+		t.Errorf("path got = %q, want %q", got, want)
+	}
+}
+
+func TestPDF_ArcTo(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end float64
+		curves     int
+		last       string
+	}{
+		// Clockwise on the page from 0 to 180 degrees ends left of the centre
+		{"half", 0, 180, 2, "40 50 c"},
+		// Counter-clockwise from 0 to -90 degrees ends above the centre
+		{"counter-clockwise", 0, -90, 1, "50 60 c"},
+		{"full", 90, 450, 4, "50 40 c"},
+		{"large", 0, 300, 4, "55 58.66 c"},
+	}
+	for _, tt := range tests {
+		p := New(100, 100)
+		p.MoveTo(60, 50)
+		p.ArcTo(50, 50, 10, tt.start, tt.end)
+		got := p.currentBuf.String()
+		if n := strings.Count(got, " c\n"); n != tt.curves {
+			t.Errorf("%s: %d curves, want %d: %q", tt.name, n, tt.curves, got)
+		}
+		if !strings.HasSuffix(got, " "+tt.last+"\n") {
+			t.Errorf("%s: got %q, want it to end with %q", tt.name, got, tt.last)
+		}
+		// The arc continues from the current point at its start
+		if (tt.start == 0) == strings.Contains(got, " l\n") {
+			t.Errorf("%s: unexpected connecting line in %q", tt.name, got)
+		}
+	}
+}
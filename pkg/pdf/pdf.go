@@ -2,23 +2,95 @@ package pdf
 
 import (
 	"bytes"
+	"compress/zlib"
 	"fmt"
 	"io"
-	"math"
+	"strconv"
+	"strings"
 )
 
+// UnitMM is the number of points in a millimeter, for use with NewWithUnit.
+const UnitMM = 72 / 25.4
+
 // PDF represents a simple PDF generator
 type PDF struct {
 	width      float64
 	height     float64
+	k          float64 // scale factor (number of points in user unit)
 	currentBuf bytes.Buffer
+	layers     []layer
+	num        []byte // scratch space for formatting numbers
+	// current is set while a path is under construction, with last the
+	// current point and first the start of the subpath
+	current     bool
+	last, first [2]float64
+
+	// Compress compresses the content stream with FlateDecode
+	Compress bool
+	// ObjectStreams stores the objects other than streams in a compressed
+	// object stream, indexed by a cross-reference stream. The output
+	// requires PDF 1.5.
+	ObjectStreams bool
+	// Info is written to the document information dictionary and XMP
+	// metadata, unless it is empty and PDFA is not set
+	Info Info
+	// PDFA writes a PDF/A-2b archival document, with XMP metadata and an
+	// sRGB output intent. Output fails with ErrFontNotEmbedded if text was
+	// drawn.
+	PDFA bool
+	// ViewerPreferences control how viewers open the document
+	ViewerPreferences ViewerPreferences
+	// text is set once text was drawn
+	text bool
+}
+
+// Decimals of coordinates and lengths in points, and of colour components
+const (
+	coordPrecision = 2
+	colorPrecision = 3
+)
+
+// appendNum appends v with at most prec decimals and no trailing zeros
+func appendNum(b []byte, v float64, prec int) []byte {
+	n := len(b)
+	b = strconv.AppendFloat(b, v, 'f', prec, 64)
+	if prec > 0 {
+		b = bytes.TrimRight(b, "0")
+		b = bytes.TrimSuffix(b, []byte("."))
+	}
+	if string(b[n:]) == "-0" {
+		b = append(b[:n], '0')
+	}
+	return b
+}
+
+// formatNum formats v as appendNum does
+func formatNum(v float64, prec int) string {
+	return string(appendNum(nil, v, prec))
 }
 
-// New creates a new PDF generator
+// writeNums writes the numbers to the content stream, each followed by a space
+func (p *PDF) writeNums(prec int, values ...float64) {
+	p.num = p.num[:0]
+	for _, v := range values {
+		p.num = appendNum(p.num, v, prec)
+		p.num = append(p.num, ' ')
+	}
+	p.currentBuf.Write(p.num)
+}
+
+// New creates a new PDF generator whose user unit is the PDF point
 func New(width, height float64) *PDF {
+	return NewWithUnit(width, height, 1)
+}
+
+// NewWithUnit creates a new PDF generator whose user unit is k points,
+// e.g. UnitMM to work in millimeters.
+func NewWithUnit(width, height, k float64) *PDF {
 	p := &PDF{
-		width:  width,
-		height: height,
+		width:  width * k,
+		height: height * k,
+		k:      k,
 	}
 	return p
 }
@@ -30,83 +102,133 @@ func (p *PDF) AddPage() {
 	p.currentBuf.Reset()
 }
 
+// Chunk returns an empty PDF of the same size and unit, to draw a part of
+// the page concurrently
+func (p *PDF) Chunk() *PDF {
+	return &PDF{width: p.width, height: p.height, k: p.k}
+}
+
+// Append adds the content drawn on q, typically a Chunk, after the content of
+// the page
+func (p *PDF) Append(q *PDF) {
+	p.currentBuf.Write(q.currentBuf.Bytes())
+	p.text = p.text || q.text
+}
+
 // Line draws a line
 func (p *PDF) Line(x1, y1, x2, y2 float64) {
+	x1, y1, x2, y2 = x1*p.k, y1*p.k, x2*p.k, y2*p.k
 	// PDF coordinates start at the bottom-left (0,0).
 	// We flip the Y coordinate to match the top-left origin used by the converter.
-	p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f m %.2f %.2f l S\n", x1, p.height-y1, x2, p.height-y2))
+	p.writeNums(coordPrecision, x1, p.height-y1)
+	p.currentBuf.WriteString("m ")
+	p.writeNums(coordPrecision, x2, p.height-y2)
+	p.currentBuf.WriteString("l S\n")
 }
 
-// Circle draws a circle
-func (p *PDF) Circle(x, y, r float64) {
-	// Approximation with Bezier curves using 4 segments
-	k := 0.552284749831 * r
-	cx, cy := x, p.height-y // center (flip Y for PDF coordinates)
-
-	// draw standard circle around cx, cy
-	// P1: (r, 0)
-	// C1: (r, k)  -> (r, -k) for Y flip
-	// C2: (k, r)  -> (k, -r) for Y flip
-	// P2: (0, r)  -> (0, -r) for Y flip
-	p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx+r, cy-k, cx+k, cy-r, cx, cy-r))
-
-	// P2: (0, r) -> P3: (-r, 0)
-	// C1: (-k, r) -> (-k, -r)
-	// C2: (-r, k) -> (-r, -k)
-	// P3: (-r, 0) -> (-r, 0)
-	p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx-k, cy-r, cx-r, cy-k, cx-r, cy))
-
-	// P3: (-r, 0) -> P4: (0, -r)
-	// C1: (-r, -k) -> (-r, k)
-	// C2: (-k, -r) -> (-k, r)
-	// P4: (0, -r)  -> (0, r)
-	p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx-r, cy+k, cx-k, cy+r, cx, cy+r))
-
-	// P4: (0, -r) -> P1: (r, 0)
-	// C1: (k, -r) -> (k, r)
-	// C2: (r, -k) -> (r, k)
-	// P1: (r, 0)
-	p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c S\n", cx+k, cy+r, cx+r, cy+k, cx+r, cy))
-}
-
-// Arc draws an arc
-func (p *PDF) Arc(x, y, r, startAngle, endAngle float64) {
-	// This is complex in PDF (requires bezier approximation).
-	// For now, just implement the math for a simple arc or skip if too complex for this step.
-	// Approximate with small line segments.
+// SetStrokeColor sets the colour used to stroke lines
+func (p *PDF) SetStrokeColor(r, g, b uint8) {
+	p.writeNums(colorPrecision, float64(r)/255, float64(g)/255, float64(b)/255)
+	p.currentBuf.WriteString("RG\n")
+}
 
-	step := 5.0 // degrees
-	cx, cy := x, p.height-y
+// SetFillColor sets the colour used to fill shapes and text
+func (p *PDF) SetFillColor(r, g, b uint8) {
+	p.writeNums(colorPrecision, float64(r)/255, float64(g)/255, float64(b)/255)
+	p.currentBuf.WriteString("rg\n")
+}
 
-	startRad := startAngle * math.Pi / 180
-	endRad := endAngle * math.Pi / 180
+// SetLineWidth sets the stroke width
+func (p *PDF) SetLineWidth(w float64) {
+	p.writeNums(coordPrecision, w*p.k)
+	p.currentBuf.WriteString("w\n")
+}
 
-	// Handling angle wrap around could be needed if start > end, but let's assume valid range or simple loop
-	if endRad < startRad {
-		endRad += 2 * math.Pi
+// SetDash sets the dash pattern as alternating dash and gap lengths.
+// An empty pattern draws solid lines.
+func (p *PDF) SetDash(pattern []float64) {
+	p.num = append(p.num[:0], '[')
+	for i, v := range pattern {
+		if i > 0 {
+			p.num = append(p.num, ' ')
+		}
+		p.num = appendNum(p.num, v*p.k, coordPrecision)
 	}
+	p.currentBuf.Write(p.num)
+	p.currentBuf.WriteString("] 0 d\n")
+}
 
-	// Move to start
-	// Note: We subtract r*sin(a) because PDF Y is up, but Input Y is down (so positive angle component in Y means "down" in PDF = minus Y)
-	p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f m\n", cx+r*math.Cos(startRad), cy-r*math.Sin(startRad)))
+// ClipRect restricts subsequent drawing on the page to the rectangle
+// with top-left corner x, y. Successive clips intersect.
+func (p *PDF) ClipRect(x, y, w, h float64) {
+	x, y, w, h = x*p.k, y*p.k, w*p.k, h*p.k
+	// PDF rectangles are given by their bottom-left corner
+	p.writeNums(coordPrecision, x, p.height-y-h, w, h)
+	p.currentBuf.WriteString("re W n\n")
+}
 
-	for a := startRad; a <= endRad; a += step * math.Pi / 180 {
-		p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f l\n", cx+r*math.Cos(a), cy-r*math.Sin(a)))
+// Circle draws a circle
+func (p *PDF) Circle(x, y, r float64) {
+	p.MoveTo(x+r, y)
+	p.ArcTo(x, y, r, 0, 360)
+	p.ClosePath()
+	p.Stroke()
+}
+
+// Arc draws an arc sweeping clockwise on the page from startAngle to
+// endAngle, in degrees
+func (p *PDF) Arc(x, y, r, startAngle, endAngle float64) {
+	if endAngle < startAngle {
+		endAngle += 360
 	}
-	// Final point
-	p.currentBuf.WriteString(fmt.Sprintf("%.2f %.2f l S\n", cx+r*math.Cos(endRad), cy-r*math.Sin(endRad)))
+	p.current = false
+	p.ArcTo(x, y, r, startAngle, endAngle)
+	p.Stroke()
 }
 
 // Text draws text
 func (p *PDF) Text(x, y, size float64, text string) {
+	p.text = true
+	x, y, size = x*p.k, y*p.k, size*p.k
 	// BT /F1 size Tf x y Td (text) Tj ET
 	// Escape text parens
 	// y needs flip
 	// Hard code /F1 for now as we standardizing on Helvetica
-	p.currentBuf.WriteString(fmt.Sprintf("BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET\n", size, x, p.height-y, text))
+	p.currentBuf.WriteString("BT /F1 ")
+	p.writeNums(coordPrecision, size)
+	p.currentBuf.WriteString("Tf ")
+	p.writeNums(coordPrecision, x, p.height-y)
+	p.currentBuf.WriteString("Td (" + escapeString(text) + ") Tj ET\n")
+}
+
+// object is an indirect object of the output. Streams have a dictionary
+// without /Length, which is added when the object is written.
+type object struct {
+	dict   string
+	stream []byte
+	// filtered is set for streams compressed with FlateDecode
+	filtered bool
+}
+
+// streamDict returns the dictionary of a stream with its length and filter
+func (o object) streamDict() string {
+	dict := strings.TrimSuffix(o.dict, " >>")
+	if o.filtered {
+		dict += " /Filter /FlateDecode"
+	}
+	return fmt.Sprintf("%s /Length %d >>", dict, len(o.stream))
 }
 
-// output writes the PDF to the writer
+// deflate compresses data for the FlateDecode filter
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// Output writes the PDF to the writer
 func (p *PDF) Output(w io.Writer) error {
 	// Object Map:
 	// 1: Catalog
@@ -114,60 +236,176 @@ func (p *PDF) Output(w io.Writer) error {
 	// 3: Page
 	// 4: Content Stream
 	// 5: Font (Helvetica)
+	// 6...: Optional Content Groups, if any
+	// Then the document information dictionary and the XMP metadata, if
+	// any, and the ICC profile of PDF/A. With ObjectStreams, the object
+	// stream and the cross-reference stream follow.
+
+	if p.PDFA && p.text {
+		return ErrFontNotEmbedded
+	}
+
+	var objects []object
+
+	version := "1.4"
+	var ocCatalog, ocResources string
+	if len(p.layers) > 0 {
+		// Optional content requires PDF 1.5
+		version = "1.5"
+		ocCatalog, ocResources = p.ocProperties(6)
+	}
+	if p.ObjectStreams {
+		version = "1.5"
+	}
 
-	// We need to build the objects first to calculate offsets
-	var objects []string
+	n := 5 + len(p.layers)
+	var infoID, metadataID, profileID int
+	withInfo := p.PDFA || !p.Info.isZero()
+	if withInfo {
+		infoID, metadataID = n+1, n+2
+		n += 2
+	}
+	if p.PDFA {
+		profileID = n + 1
+	}
 
 	// 1. Catalog
-	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	catalog := ocCatalog + p.ViewerPreferences.catalog()
+	var trailer string
+	if withInfo {
+		catalog += fmt.Sprintf(" /Metadata %d 0 R", metadataID)
+		trailer = fmt.Sprintf(" /Info %d 0 R", infoID) + p.fileID()
+	}
+	if p.PDFA {
+		catalog += fmt.Sprintf(" /OutputIntents [<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R >>]", profileID)
+	}
+	objects = append(objects, object{dict: fmt.Sprintf("<< /Type /Catalog /Pages 2 0 R%s >>", catalog)})
 
 	// 2. Pages
-	objects = append(objects, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	objects = append(objects, object{dict: "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"})
 
 	// 3. Page
-	objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>", p.width, p.height))
+	// PDF/A documents have no text, whose font is not embedded
+	fonts := " /Font << /F1 5 0 R >>"
+	if p.PDFA {
+		fonts = ""
+	}
+	objects = append(objects, object{dict: fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Contents 4 0 R /Resources <<%s%s >> >>",
+		formatNum(p.width, coordPrecision), formatNum(p.height, coordPrecision), fonts, ocResources)})
 
 	// 4. Content Stream
-	stream := p.currentBuf.String()
-	objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	content := object{dict: "<< >>", stream: p.currentBuf.Bytes()}
+	if p.Compress {
+		content.stream, content.filtered = deflate(content.stream), true
+	}
+	objects = append(objects, content)
 
 	// 5. Font
-	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	objects = append(objects, object{dict: "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"})
+
+	// 6... Optional Content Groups
+	for _, l := range p.layers {
+		objects = append(objects, object{dict: fmt.Sprintf("<< /Type /OCG /Name %s >>", textString(l.name))})
+	}
+
+	// Document information and metadata
+	if withInfo {
+		objects = append(objects, object{dict: p.Info.infoDict()})
+		objects = append(objects, object{dict: "<< /Type /Metadata /Subtype /XML >>", stream: p.Info.xmp(p.PDFA)})
+	}
+	if p.PDFA {
+		objects = append(objects, object{dict: "<< /N 3 >>", stream: deflate(sRGBProfile()), filtered: true})
+	}
 
 	// Write Header
-	n, err := w.Write([]byte("%PDF-1.4\n"))
-	if err != nil {
-		return err
+	cw := &countingWriter{w: w}
+	cw.WriteString("%PDF-" + version + "\n")
+	if p.Compress || p.ObjectStreams || p.PDFA {
+		// A comment with high bytes marks the file as binary
+		cw.WriteString("%\xe2\xe3\xcf\xd3\n")
+	}
+
+	if p.ObjectStreams {
+		writeObjectStreams(cw, objects, trailer)
+		return cw.err
 	}
-	offset := n
 
 	// Write Objects
 	var offsets []int
 	for i, obj := range objects {
-		offsets = append(offsets, offset)
-		id := i + 1
-		header := fmt.Sprintf("%d 0 obj\n", id)
-		w.Write([]byte(header))
-		w.Write([]byte(obj))
-		w.Write([]byte("\nendobj\n"))
-		offset += len(header) + len(obj) + 8 // 8 for \nendobj\n
+		offsets = append(offsets, cw.n)
+		cw.writeObject(i+1, obj)
 	}
 
 	// Write Xref
-	xrefOffset := offset
-	w.Write([]byte("xref\n"))
-	w.Write([]byte(fmt.Sprintf("0 %d\n", len(objects)+1)))
-	w.Write([]byte("0000000000 65535 f \n"))
+	xrefOffset := cw.n
+	cw.printf("xref\n0 %d\n", len(objects)+1)
+	cw.WriteString("0000000000 65535 f \n")
 	for _, o := range offsets {
-		w.Write([]byte(fmt.Sprintf("%010d 00000 n \n", o)))
+		cw.printf("%010d 00000 n \n", o)
 	}
 
 	// Write Trailer
-	w.Write([]byte("trailer\n"))
-	w.Write([]byte(fmt.Sprintf("<< /Size %d /Root 1 0 R >>\n", len(objects)+1)))
-	w.Write([]byte("startxref\n"))
-	w.Write([]byte(fmt.Sprintf("%d\n", xrefOffset)))
-	w.Write([]byte("%%EOF\n"))
+	cw.printf("trailer\n<< /Size %d /Root 1 0 R%s >>\n", len(objects)+1, trailer)
+	cw.printf("startxref\n%d\n%%%%EOF\n", xrefOffset)
+	return cw.err
+}
+
+// writeObjectStreams writes the streams of objects, followed by an object
+// stream holding the other objects and a cross-reference stream
+func writeObjectStreams(cw *countingWriter, objects []object, trailer string) {
+	objStmID := len(objects) + 1
+	xrefID := len(objects) + 2
+
+	// Cross-reference entries: type 1 for objects at an offset, type 2 for
+	// objects in the object stream with their index
+	type entry struct{ typ, field2, field3 int }
+	entries := make([]entry, xrefID+1)
+	entries[0] = entry{0, 0, 0xffff}
 
-	return nil
+	var index, body bytes.Buffer
+	count := 0
+	for i, obj := range objects {
+		id := i + 1
+		if obj.stream != nil {
+			entries[id] = entry{1, cw.n, 0}
+			cw.writeObject(id, obj)
+			continue
+		}
+		fmt.Fprintf(&index, "%d %d ", id, body.Len())
+		body.WriteString(obj.dict)
+		body.WriteByte('\n')
+		entries[id] = entry{2, objStmID, count}
+		count++
+	}
+	objStm := object{
+		dict:     fmt.Sprintf("<< /Type /ObjStm /N %d /First %d >>", count, index.Len()),
+		stream:   deflate(append(index.Bytes(), body.Bytes()...)),
+		filtered: true,
+	}
+	entries[objStmID] = entry{1, cw.n, 0}
+	cw.writeObject(objStmID, objStm)
+
+	xrefOffset := cw.n
+	entries[xrefID] = entry{1, xrefOffset, 0}
+	// Offsets take as many bytes as the largest one needs
+	width := 1
+	for xrefOffset >= 1<<(8*width) {
+		width++
+	}
+	var data []byte
+	for _, e := range entries {
+		data = append(data, byte(e.typ))
+		for i := width - 1; i >= 0; i-- {
+			data = append(data, byte(e.field2>>(8*i)))
+		}
+		data = append(data, byte(e.field3>>8), byte(e.field3))
+	}
+	xref := object{
+		dict:     fmt.Sprintf("<< /Type /XRef /Size %d /W [1 %d 2] /Root 1 0 R%s >>", xrefID+1, width, trailer),
+		stream:   deflate(data),
+		filtered: true,
+	}
+	cw.writeObject(xrefID, xref)
+	cw.printf("startxref\n%d\n%%%%EOF\n", xrefOffset)
 }
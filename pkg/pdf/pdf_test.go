@@ -12,7 +12,7 @@ func TestPDF_Line(t *testing.T) {
 
 	got := p.currentBuf.String()
 	// Y is flipped: 100 - 10 = 90, 100 - 90 = 10
-	want := "10.00 90.00 m 90.00 10.00 l S\n"
+	want := "10 90 m 90 10 l S\n"
 
 	if got != want {
 		t.Errorf("Line() got = %q, want %q", got, want)
@@ -42,31 +42,13 @@ func TestPDF_Arc(t *testing.T) {
 	p.Arc(50, 50, 10, 0, 90)
 
 	got := p.currentBuf.String()
-	// Should contain move 'm', line 'l', and stroke 'S'
-	if !strings.Contains(got, "m") {
-		t.Errorf("Arc() output should contain move 'm', got %q", got)
-	}
-	if !strings.Contains(got, "l") {
-		t.Errorf("Arc() output should contain line 'l', got %q", got)
-	}
-	if !strings.HasSuffix(got, "S\n") {
-		t.Errorf("Arc() output should end with stroke 'S', got %q", got)
-	}
-
-	// Verify start point
+	// A quarter circle is a single Bezier curve from the start to the end
 	// 0 degrees: x=50+10=60, y=50 (flipped y=100-50=50)
-	// PDF coords: 60.00 50.00 m
-	wantStart := "60.00 50.00 m"
-	if !strings.Contains(got, wantStart) {
-		t.Errorf("Arc() output should start at %q, got %q", wantStart, got)
-	}
-
-	// Verify end point
 	// 90 degrees: x=50, y=50+10=60 (flipped y=100-60=40)
-	// PDF coords: 50.00 40.00 l S
-	wantEnd := "50.00 40.00 l S"
-	if !strings.Contains(got, wantEnd) {
-		t.Errorf("Arc() output should end at %q, got %q", wantEnd, got)
+	// The control points are 0.5523*r from the ends, along the tangents
+	want := "60 50 m\n60 44.48 55.52 40 50 40 c\nS\n"
+	if got != want {
+		t.Errorf("Arc() got = %q, want %q", got, want)
 	}
 }
 
@@ -76,8 +58,8 @@ func TestPDF_Text(t *testing.T) {
 
 	got := p.currentBuf.String()
 	// x=10, y=20 -> flipped y=80
-	// BT /F1 12.00 Tf 10.00 80.00 Td (Hello World) Tj ET
-	want := "BT /F1 12.00 Tf 10.00 80.00 Td (Hello World) Tj ET\n"
+	// BT /F1 12 Tf 10 80 Td (Hello World) Tj ET
+	want := "BT /F1 12 Tf 10 80 Td (Hello World) Tj ET\n"
 
 	if got != want {
 		t.Errorf("Text() got = %q, want %q", got, want)
@@ -104,7 +86,7 @@ func TestPDF_Output(t *testing.T) {
 		"2 0 obj", // Pages
 		"/Type /Pages",
 		"3 0 obj", // Page
-		"/MediaBox [0 0 100.00 200.00]",
+		"/MediaBox [0 0 100 200]",
 		"4 0 obj", // Content stream
 		"stream",
 		"endstream",
@@ -135,3 +117,54 @@ func TestPDF_AddPage(t *testing.T) {
 		t.Error("Buffer should be empty after AddPage")
 	}
 }
+
+func TestPDF_NewWithUnit(t *testing.T) {
+	p := NewWithUnit(100, 100, 2)
+	p.Line(10, 10, 90, 90)
+
+	got := p.currentBuf.String()
+	// Coordinates are doubled and Y is flipped on the 200pt page
+	want := "20 180 m 180 20 l S\n"
+	if got != want {
+		t.Errorf("Line() got = %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Output(&buf); err != nil {
+		t.Fatalf("Output() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "/MediaBox [0 0 200 200]") {
+		t.Error("Output() MediaBox should be expressed in points")
+	}
+}
+
+func TestPDF_ClipRect(t *testing.T) {
+	p := New(100, 100)
+	p.ClipRect(10, 20, 30, 40)
+
+	got := p.currentBuf.String()
+	// Bottom-left corner: y = 100 - 20 - 40 = 40
+	want := "10 40 30 40 re W n\n"
+	if got != want {
+		t.Errorf("ClipRect() got = %q, want %q", got, want)
+	}
+}
+
+func TestPDF_Style(t *testing.T) {
+	p := New(100, 100)
+	p.SetStrokeColor(255, 0, 0)
+	p.SetFillColor(0, 0, 255)
+	p.SetLineWidth(0.5)
+	p.SetDash([]float64{3, 1.5})
+	p.SetDash(nil)
+
+	got := p.currentBuf.String()
+	want := "1 0 0 RG\n" +
+		"0 0 1 rg\n" +
+		"0.5 w\n" +
+		"[3 1.5] 0 d\n" +
+		"[] 0 d\n"
+	if got != want {
+		t.Errorf("style operators got = %q, want %q", got, want)
+	}
+}
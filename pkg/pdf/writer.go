@@ -0,0 +1,50 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+)
+
+// countingWriter counts the bytes written to w, which are the offsets of
+// the cross-reference table. It keeps the first error and writes nothing
+// after it.
+type countingWriter struct {
+	w   io.Writer
+	n   int
+	err error
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(b)
+	c.n += n
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+	c.err = err
+	return n, err
+}
+
+func (c *countingWriter) WriteString(s string) (int, error) {
+	return c.Write([]byte(s))
+}
+
+func (c *countingWriter) printf(format string, args ...any) {
+	if c.err == nil {
+		fmt.Fprintf(c, format, args...)
+	}
+}
+
+// writeObject writes obj as the indirect object id
+func (c *countingWriter) writeObject(id int, obj object) {
+	c.printf("%d 0 obj\n", id)
+	if obj.stream == nil {
+		c.printf("%s\nendobj\n", obj.dict)
+		return
+	}
+	c.printf("%s\nstream\n", obj.streamDict())
+	c.Write(obj.stream)
+	c.WriteString("\nendstream\nendobj\n")
+}
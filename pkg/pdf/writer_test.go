@@ -0,0 +1,325 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// validate parses the cross-reference table or stream of a generated PDF
+// and checks that it locates every object, and that the objects and their
+// streams are well formed. It returns the dictionaries by object number.
+func validate(data []byte) (map[int]string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-1.")) {
+		return nil, fmt.Errorf("header = %.10q", data)
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		return nil, errors.New("no startxref at the end")
+	}
+	xrefOffset, _ := strconv.Atoi(string(m[1]))
+	if xrefOffset >= len(data) {
+		return nil, fmt.Errorf("startxref %d beyond the end", xrefOffset)
+	}
+
+	dicts := make(map[int]string)
+	streams := make(map[int][]byte)
+	// object reads the object id at offset
+	object := func(id, offset int) error {
+		if offset <= 0 || offset >= len(data) {
+			return fmt.Errorf("object %d: offset %d out of range", id, offset)
+		}
+		rest := data[offset:]
+		head := fmt.Sprintf("%d 0 obj\n", id)
+		if !bytes.HasPrefix(rest, []byte(head)) {
+			return fmt.Errorf("object %d: offset %d points to %.20q", id, offset, rest)
+		}
+		rest = rest[len(head):]
+		end := bytes.IndexByte(rest, '\n')
+		if end < 0 {
+			return fmt.Errorf("object %d: unterminated", id)
+		}
+		dict := string(rest[:end])
+		if !strings.HasPrefix(dict, "<<") || !strings.HasSuffix(dict, ">>") {
+			return fmt.Errorf("object %d: dictionary %q", id, dict)
+		}
+		dicts[id] = dict
+		rest = rest[end+1:]
+		if bytes.HasPrefix(rest, []byte("endobj\n")) {
+			return nil
+		}
+		if !bytes.HasPrefix(rest, []byte("stream\n")) {
+			return fmt.Errorf("object %d: %.20q after the dictionary", id, rest)
+		}
+		rest = rest[len("stream\n"):]
+		l := regexp.MustCompile(`/Length (\d+) >>$`).FindStringSubmatch(dict)
+		if l == nil {
+			return fmt.Errorf("object %d: stream without /Length", id)
+		}
+		length, _ := strconv.Atoi(l[1])
+		if length > len(rest) || !bytes.HasPrefix(rest[length:], []byte("\nendstream\nendobj\n")) {
+			return fmt.Errorf("object %d: stream does not end at /Length %d", id, length)
+		}
+		stream := rest[:length]
+		if strings.Contains(dict, "/FlateDecode") {
+			zr, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				return fmt.Errorf("object %d: %v", id, err)
+			}
+			if stream, err = io.ReadAll(zr); err != nil {
+				return fmt.Errorf("object %d: %v", id, err)
+			}
+		}
+		streams[id] = stream
+		return nil
+	}
+
+	var size int
+	if rest := data[xrefOffset:]; bytes.HasPrefix(rest, []byte("xref\n")) {
+		m := regexp.MustCompile(`^xref\n0 (\d+)\n`).FindSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("xref subsection %.20q", rest)
+		}
+		size, _ = strconv.Atoi(string(m[1]))
+		entries := rest[len(m[0]):]
+		if len(entries) < 20*size {
+			return nil, errors.New("xref table truncated")
+		}
+		if string(entries[:20]) != "0000000000 65535 f \n" {
+			return nil, fmt.Errorf("xref entry 0 = %q", entries[:20])
+		}
+		for id := 1; id < size; id++ {
+			e := entries[20*id : 20*id+20]
+			if !regexp.MustCompile(`^\d{10} 00000 n \n$`).Match(e) {
+				return nil, fmt.Errorf("xref entry %d = %q", id, e)
+			}
+			offset, _ := strconv.Atoi(string(e[:10]))
+			if err := object(id, offset); err != nil {
+				return nil, err
+			}
+		}
+		trailer := regexp.MustCompile(fmt.Sprintf(`^trailer\n<< /Size %d /Root 1 0 R[^\n]* >>\nstartxref\n`, size))
+		if !trailer.Match(entries[20*size:]) {
+			return nil, fmt.Errorf("trailer = %.40q", entries[20*size:])
+		}
+	} else {
+		m := regexp.MustCompile(`^(\d+) 0 obj\n`).FindSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("no xref at startxref %d", xrefOffset)
+		}
+		xrefID, _ := strconv.Atoi(string(m[1]))
+		if err := object(xrefID, xrefOffset); err != nil {
+			return nil, err
+		}
+		dict := dicts[xrefID]
+		f := regexp.MustCompile(`^<< /Type /XRef /Size (\d+) /W \[1 (\d) 2\] /Root 1 0 R`).FindStringSubmatch(dict)
+		if f == nil {
+			return nil, fmt.Errorf("xref dictionary %q", dict)
+		}
+		size, _ = strconv.Atoi(f[1])
+		width, _ := strconv.Atoi(f[2])
+		xref := streams[xrefID]
+		if len(xref) != size*(3+width) {
+			return nil, fmt.Errorf("xref stream has %d bytes for %d entries", len(xref), size)
+		}
+		field := func(b []byte) int {
+			v := 0
+			for _, c := range b {
+				v = v<<8 | int(c)
+			}
+			return v
+		}
+		// Objects in object streams are checked once all offsets are
+		type compressed struct{ id, stm, index int }
+		var inStreams []compressed
+		for id := 0; id < size; id++ {
+			e := xref[id*(3+width) : (id+1)*(3+width)]
+			f2, f3 := field(e[1:1+width]), field(e[1+width:])
+			switch {
+			case id == 0:
+				if e[0] != 0 || f3 != 0xffff {
+					return nil, fmt.Errorf("xref entry 0 = %v", e)
+				}
+			case e[0] == 1:
+				if id == xrefID {
+					if f2 != xrefOffset {
+						return nil, fmt.Errorf("xref entry %d = %d, want startxref %d", id, f2, xrefOffset)
+					}
+					continue
+				}
+				if err := object(id, f2); err != nil {
+					return nil, err
+				}
+			case e[0] == 2:
+				inStreams = append(inStreams, compressed{id, f2, f3})
+			default:
+				return nil, fmt.Errorf("xref entry %d has type %d", id, e[0])
+			}
+		}
+		for _, c := range inStreams {
+			stm, ok := streams[c.stm]
+			n := regexp.MustCompile(`/Type /ObjStm /N (\d+) /First (\d+)`).FindStringSubmatch(dicts[c.stm])
+			if !ok || n == nil {
+				return nil, fmt.Errorf("object %d: %d is not an object stream", c.id, c.stm)
+			}
+			count, _ := strconv.Atoi(n[1])
+			first, _ := strconv.Atoi(n[2])
+			index := strings.Fields(string(stm[:min(first, len(stm))]))
+			if len(index) != 2*count || c.index >= count {
+				return nil, fmt.Errorf("object stream %d: index %q", c.stm, index)
+			}
+			if id, _ := strconv.Atoi(index[2*c.index]); id != c.id {
+				return nil, fmt.Errorf("object stream %d: object %d at index %d, want %d", c.stm, id, c.index, c.id)
+			}
+			start, _ := strconv.Atoi(index[2*c.index+1])
+			body := stm[first+start:]
+			dict, _, _ := bytes.Cut(body, []byte("\n"))
+			if !bytes.HasPrefix(dict, []byte("<<")) || !bytes.HasSuffix(dict, []byte(">>")) {
+				return nil, fmt.Errorf("object %d: dictionary %q", c.id, dict)
+			}
+			dicts[c.id] = string(dict)
+		}
+	}
+
+	for id := 1; id < size; id++ {
+		if _, ok := dicts[id]; !ok {
+			return nil, fmt.Errorf("object %d missing", id)
+		}
+	}
+	if !strings.HasPrefix(dicts[1], "<< /Type /Catalog ") {
+		return nil, fmt.Errorf("root %q is not a catalog", dicts[1])
+	}
+	return dicts, nil
+}
+
+// drawing returns a PDF with some of everything
+func drawing() *PDF {
+	p := New(100, 100)
+	walls := p.AddLayer("WALLS", true)
+	p.AddLayer("Hidden (old)", false)
+	p.BeginLayer(walls)
+	p.Line(10, 10, 90, 90)
+	p.Circle(50, 50, 20)
+	p.EndLayer()
+	p.Text(10, 20, 5, "endstream\nendobj")
+	return p
+}
+
+func TestPDF_Validate(t *testing.T) {
+	for _, mode := range []struct {
+		name                   string
+		compress, objectStream bool
+	}{
+		{"plain", false, false},
+		{"compressed", true, false},
+		{"object streams", true, true},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			p := drawing()
+			p.Compress, p.ObjectStreams = mode.compress, mode.objectStream
+			var buf bytes.Buffer
+			if err := p.Output(&buf); err != nil {
+				t.Fatal(err)
+			}
+			dicts, err := validate(buf.Bytes())
+			if err != nil {
+				t.Fatalf("%v\n%s", err, buf.Bytes())
+			}
+			if len(dicts) != 7+btoi(mode.objectStream)*2 {
+				t.Errorf("got %d objects", len(dicts))
+			}
+		})
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// failingWriter accepts n bytes and then fails
+type failingWriter struct {
+	n       int
+	written int
+	short   bool
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if w.written+len(b) <= w.n {
+		w.written += len(b)
+		return len(b), nil
+	}
+	n := w.n - w.written
+	w.written = w.n
+	if w.short {
+		return n, nil
+	}
+	return n, errDiskFull
+}
+
+func TestPDF_OutputError(t *testing.T) {
+	for _, objectStreams := range []bool{false, true} {
+		p := drawing()
+		p.Compress, p.ObjectStreams = objectStreams, objectStreams
+		var buf bytes.Buffer
+		if err := p.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		// Fail at every byte of the output
+		for n := 0; n < buf.Len(); n++ {
+			w := &failingWriter{n: n}
+			if err := p.Output(w); !errors.Is(err, errDiskFull) {
+				t.Fatalf("object streams %v, failing after %d bytes: Output() error = %v, want %v", objectStreams, n, err, errDiskFull)
+			}
+			w = &failingWriter{n: n, short: true}
+			if err := p.Output(w); !errors.Is(err, io.ErrShortWrite) {
+				t.Fatalf("object streams %v, short write after %d bytes: Output() error = %v, want %v", objectStreams, n, err, io.ErrShortWrite)
+			}
+		}
+		if err := p.Output(&failingWriter{n: buf.Len()}); err != nil {
+			t.Errorf("Output() error = %v with room for the whole file", err)
+		}
+	}
+}
+
+func FuzzOutput(f *testing.F) {
+	f.Add("WALLS", "(text)\\", 10.0, 20.0, 5.0, 0.0, 90.0, false, false)
+	f.Add("Ébauche", "endstream\nendobj\n", 0.5, 99.5, 100.0, 270.0, -45.0, true, false)
+	f.Add("", "\xff\x00", -1e9, 1e9, 0.0, 1e300, -1e300, true, true)
+	f.Fuzz(func(t *testing.T, layer, text string, x, y, r, start, end float64, compress, objectStreams bool) {
+		p := New(100, 100)
+		if layer != "" {
+			p.BeginLayer(p.AddLayer(layer, len(text)%2 == 0))
+		}
+		p.Line(x, y, y, x)
+		p.Arc(x, y, r, start, end)
+		p.MoveTo(x, y)
+		p.ArcTo(y, x, r, start, end)
+		p.ClosePath()
+		p.FillStroke()
+		p.Text(x, y, r, text)
+		if layer != "" {
+			p.EndLayer()
+		}
+		p.Compress, p.ObjectStreams = compress, objectStreams
+		p.Info = Info{Title: text, Keywords: layer}
+
+		var buf bytes.Buffer
+		if err := p.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := validate(buf.Bytes()); err != nil {
+			t.Fatalf("%v\n%q", err, buf.Bytes())
+		}
+	})
+}
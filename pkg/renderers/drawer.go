@@ -1,18 +1,47 @@
 package renderers
 
 import (
+	"math"
+
 	"github.com/daidai-ok/dxfconv/pkg/dxf"
 )
 
+// PointStyle describes how POINT entities are drawn, following $PDMODE.
+type PointStyle struct {
+	// Mode is the $PDMODE value: 0 dot, 1 nothing, 2 plus, 3 cross, 4 tick,
+	// optionally combined with 32 (circle) and 64 (square).
+	Mode int
+	// Size is the symbol size in page units.
+	Size float64
+}
+
+// DrawContext holds the page transform shared by all entities of a drawing.
+type DrawContext struct {
+	Scale   float64
+	OffsetX float64
+	OffsetY float64
+	Height  float64
+	// PointStyle controls how points are drawn. If nil, points are drawn as a small circle.
+	PointStyle *PointStyle
+}
+
 // DrawEntity draws a single DXF entity using the Renderer
 func DrawEntity(r Renderer, e dxf.Entity, scale float64, offsetX, offsetY float64, height float64) {
+	ctx := &DrawContext{Scale: scale, OffsetX: offsetX, OffsetY: offsetY, Height: height}
+	ctx.Draw(r, e)
+}
+
+// Draw draws a single DXF entity using the Renderer
+func (c *DrawContext) Draw(r Renderer, e dxf.Entity) {
+	scale := c.Scale
+
 	transformX := func(x float64) float64 {
-		return (x * scale) + offsetX
+		return (x * scale) + c.OffsetX
 	}
 
 	transformY := func(y float64) float64 {
 		// Flip Y
-		return height - ((y * scale) + offsetY)
+		return c.Height - ((y * scale) + c.OffsetY)
 	}
 
 	switch e := e.(type) {
@@ -29,35 +58,65 @@ func DrawEntity(r Renderer, e dxf.Entity, scale float64, offsetX, offsetY float6
 		if len(e.Vertices) < 2 {
 			return
 		}
-		points := make([][]float64, len(e.Vertices))
-		for i, v := range e.Vertices {
-			points[i] = []float64{transformX(v.X), transformY(v.Y)}
+		var p Path
+		p.MoveTo(transformX(e.Vertices[0].X), transformY(e.Vertices[0].Y))
+		n := len(e.Vertices)
+		if !e.Closed {
+			n--
+		}
+		for i := 0; i < n; i++ {
+			v, w := e.Vertices[i], e.Vertices[(i+1)%len(e.Vertices)]
+			if v.Bulge == 0 || (v.X == w.X && v.Y == w.Y) {
+				if i+1 < len(e.Vertices) {
+					p.LineTo(transformX(w.X), transformY(w.Y))
+				}
+				continue
+			}
+			c, rad, start, sweep := BulgeArc([2]float64{v.X, v.Y}, [2]float64{w.X, w.Y}, v.Bulge)
+			p.ArcTo(transformX(c[0]), transformY(c[1]), rad*scale, start, sweep)
+		}
+		if e.Closed {
+			p.Close()
 		}
-		r.Polyline(points, e.Closed)
+		drawPath(r, &p)
 	case *dxf.Polyline:
 		if len(e.Vertices) < 2 {
 			return
 		}
-		points := make([][]float64, len(e.Vertices))
+		var p Path
 		for i, v := range e.Vertices {
-			points[i] = []float64{transformX(v.X), transformY(v.Y)}
+			if i == 0 {
+				p.MoveTo(transformX(v.X), transformY(v.Y))
+			} else {
+				p.LineTo(transformX(v.X), transformY(v.Y))
+			}
 		}
 		// Closed flag is already handled in parser
-		r.Polyline(points, e.Closed)
+		if e.Closed {
+			p.Close()
+		}
+		drawPath(r, &p)
 	case *dxf.Spline:
 		if len(e.ControlPoints) < 2 {
 			return
 		}
-		// Approximation by control points
-		points := make([][]float64, len(e.ControlPoints))
+		// B-splines are invariant under the affine page transform
+		points := make([][2]float64, len(e.ControlPoints))
 		for i, v := range e.ControlPoints {
-			points[i] = []float64{transformX(v[0]), transformY(v[1])}
+			points[i] = [2]float64{transformX(v[0]), transformY(v[1])}
 		}
-		r.Polyline(points, e.Closed) // Spline can be closed
+		var p Path
+		splinePath(&p, e, points)
+		drawPath(r, &p)
 	case *dxf.Point:
-		// Draw as a small circle, simplistic representation
-		radius := 1.0 * scale // Fixed visual size or scaled
-		r.Circle(transformX(e.Coord[0]), transformY(e.Coord[1]), radius)
+		x, y := transformX(e.Coord[0]), transformY(e.Coord[1])
+		if c.PointStyle == nil {
+			// Draw as a small circle, simplistic representation
+			radius := 1.0 * scale // Fixed visual size or scaled
+			r.Circle(x, y, radius)
+			return
+		}
+		drawPointSymbol(r, x, y, c.PointStyle)
 	case *dxf.Text:
 		r.Text(transformX(e.Point[0]), transformY(e.Point[1]), e.Height*scale, e.Value)
 	case *dxf.MText:
@@ -65,3 +124,42 @@ func DrawEntity(r Renderer, e dxf.Entity, scale float64, offsetX, offsetY float6
 		r.Text(transformX(e.Point[0]), transformY(e.Point[1]), e.Height*scale, e.Value)
 	}
 }
+
+// BulgeArc returns the arc from p to q of a polyline segment with a non-zero
+// bulge, the tangent of a quarter of the included angle: its centre, radius,
+// and start angle and sweep in degrees, counter-clockwise for positive bulges.
+func BulgeArc(p, q [2]float64, bulge float64) (center [2]float64, radius, start, sweep float64) {
+	// The centre lies on the left normal of the chord for positive bulges
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	f := (1 - bulge*bulge) / (4 * bulge)
+	center = [2]float64{(p[0]+q[0])/2 - dy*f, (p[1]+q[1])/2 + dx*f}
+	radius = math.Hypot(p[0]-center[0], p[1]-center[1])
+	start = math.Atan2(p[1]-center[1], p[0]-center[0]) * 180 / math.Pi
+	sweep = 4 * math.Atan(bulge) * 180 / math.Pi
+	return center, radius, start, sweep
+}
+
+// drawPointSymbol draws the $PDMODE symbol centered at x, y (page coordinates).
+func drawPointSymbol(r Renderer, x, y float64, ps *PointStyle) {
+	h := ps.Size / 2
+	switch ps.Mode & 7 {
+	case 0:
+		// A dot has no size of its own, so draw the smallest visible mark.
+		r.Circle(x, y, 0.1)
+	case 2:
+		r.Line(x-h, y, x+h, y)
+		r.Line(x, y-h, x, y+h)
+	case 3:
+		r.Line(x-h, y-h, x+h, y+h)
+		r.Line(x-h, y+h, x+h, y-h)
+	case 4:
+		// Page Y grows downwards, so the tick goes towards smaller Y.
+		r.Line(x, y, x, y-h)
+	}
+	if ps.Mode&32 != 0 {
+		r.Circle(x, y, h)
+	}
+	if ps.Mode&64 != 0 {
+		r.Polyline([][]float64{{x - h, y - h}, {x + h, y - h}, {x + h, y + h}, {x - h, y + h}}, true)
+	}
+}
@@ -0,0 +1,257 @@
+package renderers
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Points per millimeter
+const epsUnit = 72 / 25.4
+
+// Line width used when the style has none, matching PDF's default of one point
+const epsDefaultLineWidth = 1.0
+
+// epsProlog defines the procedures used by the page description. The font is
+// re-encoded to ISO Latin-1 so that accented characters can be shown.
+const epsProlog = `%%BeginProlog
+/L { newpath moveto lineto stroke } bind def
+/C { newpath 0 360 arc closepath stroke } bind def
+/A { newpath arc stroke } bind def
+/F { /DXFConvFont findfont exch scalefont setfont } bind def
+/T { moveto show } bind def
+/Helvetica findfont dup length dict begin
+  { 1 index /FID ne { def } { pop pop } ifelse } forall
+  /Encoding ISOLatin1Encoding def
+  currentdict
+end
+/DXFConvFont exch definefont pop
+%%EndProlog
+`
+
+// EPSRenderer implements the Renderer interface for Encapsulated PostScript
+// (Level 2) output. The page description is buffered so that the
+// %%BoundingBox comment can enclose exactly what was drawn.
+type EPSRenderer struct {
+	writer io.Writer
+	buf    bytes.Buffer
+	width  float64 // page size in points
+	height float64
+	style  Style
+	font   float64 // current font size in points
+
+	// Extent of the drawing in points
+	bounds [4]float64
+	drawn  bool
+	clip   [4]float64
+}
+
+// NewEPSRenderer creates a new EPSRenderer for a page of the given size in
+// millimeters, which Init may change
+func NewEPSRenderer(w io.Writer, width, height float64) *EPSRenderer {
+	r := &EPSRenderer{writer: w}
+	r.Init(width, height)
+	return r
+}
+
+func (r *EPSRenderer) Init(width, height float64) {
+	r.width, r.height = width*epsUnit, height*epsUnit
+	r.clip = [4]float64{0, 0, r.width, r.height}
+	r.bounds = [4]float64{math.MaxFloat64, math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+}
+
+// pt converts a page position in millimeters to PostScript coordinates,
+// whose origin is the bottom-left corner
+func (r *EPSRenderer) pt(x, y float64) (float64, float64) {
+	return x * epsUnit, r.height - y*epsUnit
+}
+
+// psNum formats a PostScript number
+func psNum(v float64) string {
+	return FormatNumber(v, 3)
+}
+
+func (r *EPSRenderer) Clip(x, y, width, height float64) {
+	px, py := r.pt(x, y+height)
+	w, h := width*epsUnit, height*epsUnit
+	fmt.Fprintf(&r.buf, "%s %s %s %s rectclip\n", psNum(px), psNum(py), psNum(w), psNum(h))
+	r.clip = [4]float64{
+		math.Max(r.clip[0], px), math.Max(r.clip[1], py),
+		math.Min(r.clip[2], px+w), math.Min(r.clip[3], py+h),
+	}
+}
+
+func (r *EPSRenderer) SetStyle(style Style) {
+	// Only emit the operators that change the graphics state
+	if style.Color != r.style.Color {
+		fmt.Fprintf(&r.buf, "%s setrgbcolor\n", rgb(style.Color))
+	}
+	if style.LineWidth != r.style.LineWidth {
+		w := epsDefaultLineWidth
+		if style.LineWidth != 0 {
+			w = style.LineWidth * epsUnit
+		}
+		fmt.Fprintf(&r.buf, "%s setlinewidth\n", psNum(w))
+	}
+	if !slices.Equal(style.Dash, r.style.Dash) {
+		dash := make([]string, len(style.Dash))
+		for i, v := range style.Dash {
+			dash[i] = psNum(v * epsUnit)
+		}
+		fmt.Fprintf(&r.buf, "[%s] 0 setdash\n", strings.Join(dash, " "))
+	}
+	r.style = style
+}
+
+func rgb(c color.RGBA) string {
+	return fmt.Sprintf("%s %s %s", psNum(float64(c.R)/255), psNum(float64(c.G)/255), psNum(float64(c.B)/255))
+}
+
+// lineWidth returns the width of the current pen in points
+func (r *EPSRenderer) lineWidth() float64 {
+	if r.style.LineWidth == 0 {
+		return epsDefaultLineWidth
+	}
+	return r.style.LineWidth * epsUnit
+}
+
+// extend grows the drawing extent to include the rectangle x0, y0 - x1, y1
+func (r *EPSRenderer) extend(x0, y0, x1, y1 float64) {
+	r.bounds[0] = math.Min(r.bounds[0], x0)
+	r.bounds[1] = math.Min(r.bounds[1], y0)
+	r.bounds[2] = math.Max(r.bounds[2], x1)
+	r.bounds[3] = math.Max(r.bounds[3], y1)
+	r.drawn = true
+}
+
+// extendStroke grows the drawing extent by a stroked point
+func (r *EPSRenderer) extendStroke(x, y float64) {
+	half := r.lineWidth() / 2
+	r.extend(x-half, y-half, x+half, y+half)
+}
+
+func (r *EPSRenderer) Line(x1, y1, x2, y2 float64) {
+	px1, py1 := r.pt(x1, y1)
+	px2, py2 := r.pt(x2, y2)
+	fmt.Fprintf(&r.buf, "%s %s %s %s L\n", psNum(px2), psNum(py2), psNum(px1), psNum(py1))
+	r.extendStroke(px1, py1)
+	r.extendStroke(px2, py2)
+}
+
+func (r *EPSRenderer) Circle(x, y, radius float64) {
+	px, py := r.pt(x, y)
+	rad := radius * epsUnit
+	fmt.Fprintf(&r.buf, "%s %s %s C\n", psNum(px), psNum(py), psNum(rad))
+	r.extendStroke(px-rad, py-rad)
+	r.extendStroke(px+rad, py+rad)
+}
+
+// Arc draws an arc counter-clockwise from startAngle to endAngle (degrees) as seen on the page
+func (r *EPSRenderer) Arc(x, y, radius, startAngle, endAngle float64) {
+	px, py := r.pt(x, y)
+	rad := radius * epsUnit
+	// PostScript angles are counter-clockwise with Y up, like DXF
+	fmt.Fprintf(&r.buf, "%s %s %s %s %s A\n", psNum(px), psNum(py), psNum(rad), psNum(startAngle), psNum(endAngle))
+	r.extendStroke(px-rad, py-rad)
+	r.extendStroke(px+rad, py+rad)
+}
+
+func (r *EPSRenderer) Polyline(points [][]float64, closed bool) {
+	if len(points) < 2 {
+		return
+	}
+	r.buf.WriteString("newpath")
+	for i, p := range points {
+		px, py := r.pt(p[0], p[1])
+		op := "lineto"
+		if i == 0 {
+			op = "moveto"
+		}
+		fmt.Fprintf(&r.buf, " %s %s %s", psNum(px), psNum(py), op)
+		r.extendStroke(px, py)
+	}
+	if closed {
+		r.buf.WriteString(" closepath")
+	}
+	r.buf.WriteString(" stroke\n")
+}
+
+func (r *EPSRenderer) Text(x, y, height float64, text string) {
+	px, py := r.pt(x, y)
+	size := height * epsUnit
+	if size != r.font {
+		fmt.Fprintf(&r.buf, "%s F\n", psNum(size))
+		r.font = size
+	}
+	fmt.Fprintf(&r.buf, "%s %s %s T\n", psString(text), psNum(px), psNum(py))
+	// Approximate the extent from Helvetica's average glyph width and descender
+	n := float64(len([]rune(text)))
+	r.extend(px, py-0.25*size, px+0.6*size*n, py+size)
+}
+
+// psString encodes s as a PostScript string in ISO Latin-1.
+// Characters outside Latin-1 are replaced by a question mark.
+func psString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range s {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		case c >= 0x20 && c < 0x7f:
+			b.WriteRune(c)
+		case c >= 0xa0 && c <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// BoundingBox returns the extent of the drawing in points, clipped to the page.
+// It is the whole page when nothing was drawn.
+func (r *EPSRenderer) BoundingBox() (llx, lly, urx, ury float64) {
+	if !r.drawn {
+		return 0, 0, r.width, r.height
+	}
+	llx = math.Max(r.bounds[0], r.clip[0])
+	lly = math.Max(r.bounds[1], r.clip[1])
+	urx = math.Min(r.bounds[2], r.clip[2])
+	ury = math.Min(r.bounds[3], r.clip[3])
+	if urx < llx || ury < lly {
+		return 0, 0, 0, 0
+	}
+	return llx, lly, urx, ury
+}
+
+func (r *EPSRenderer) Finish() error {
+	llx, lly, urx, ury := r.BoundingBox()
+	var header bytes.Buffer
+	header.WriteString("%!PS-Adobe-3.0 EPSF-3.0\n")
+	fmt.Fprintf(&header, "%%%%BoundingBox: %d %d %d %d\n",
+		int(math.Floor(llx)), int(math.Floor(lly)), int(math.Ceil(urx)), int(math.Ceil(ury)))
+	fmt.Fprintf(&header, "%%%%HiResBoundingBox: %s %s %s %s\n", psNum(llx), psNum(lly), psNum(urx), psNum(ury))
+	header.WriteString("%%Creator: dxfconv\n")
+	header.WriteString("%%LanguageLevel: 2\n")
+	header.WriteString("%%Pages: 1\n")
+	header.WriteString("%%EndComments\n")
+	header.WriteString(epsProlog)
+	header.WriteString("%%Page: 1 1\n")
+	header.WriteString("gsave\n")
+
+	if _, err := r.writer.Write(header.Bytes()); err != nil {
+		return err
+	}
+	if _, err := r.writer.Write(r.buf.Bytes()); err != nil {
+		return err
+	}
+	_, err := io.WriteString(r.writer, "grestore\nshowpage\n%%Trailer\n%%EOF\n")
+	return err
+}
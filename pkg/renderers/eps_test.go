@@ -0,0 +1,105 @@
+package renderers
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func renderEPS(t *testing.T, draw func(r *EPSRenderer)) string {
+	t.Helper()
+	var buf bytes.Buffer
+	// 1 inch square page
+	r := NewEPSRenderer(&buf, 25.4, 25.4)
+	r.Init(25.4, 25.4)
+	draw(r)
+	if err := r.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestEPSRenderer_Document(t *testing.T) {
+	out := renderEPS(t, func(r *EPSRenderer) {})
+	if !strings.HasPrefix(out, "%!PS-Adobe-3.0 EPSF-3.0\n") {
+		t.Errorf("missing EPSF header:\n%s", out)
+	}
+	// Nothing drawn: the whole page
+	if !strings.Contains(out, "%%BoundingBox: 0 0 72 72\n") {
+		t.Errorf("unexpected bounding box:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "%%EOF\n") {
+		t.Errorf("missing %%%%EOF:\n%s", out)
+	}
+}
+
+func TestNewEPSRenderer_PageSize(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewEPSRenderer(&buf, 25.4, 50.8)
+	if err := r.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "%%BoundingBox: 0 0 72 144\n") {
+		t.Errorf("page size not kept before Init:\n%s", out)
+	}
+}
+
+func TestEPSRenderer_BoundingBox(t *testing.T) {
+	out := renderEPS(t, func(r *EPSRenderer) {
+		// From 1/4 to 3/4 inch, Y measured from the top
+		r.Line(6.35, 6.35, 19.05, 19.05)
+	})
+	// Extended by half the default line width of one point
+	if !strings.Contains(out, "%%BoundingBox: 17 17 55 55\n") {
+		t.Errorf("unexpected bounding box:\n%s", out)
+	}
+	if !strings.Contains(out, "%%HiResBoundingBox: 17.5 17.5 54.5 54.5\n") {
+		t.Errorf("unexpected high resolution bounding box:\n%s", out)
+	}
+	if !strings.Contains(out, "54 18 18 54 L\n") {
+		t.Errorf("unexpected line:\n%s", out)
+	}
+}
+
+func TestEPSRenderer_Clip(t *testing.T) {
+	out := renderEPS(t, func(r *EPSRenderer) {
+		r.Clip(0, 0, 12.7, 25.4)
+		r.Line(0, 12.7, 25.4, 12.7)
+	})
+	if !strings.Contains(out, "0 0 36 72 rectclip\n") {
+		t.Errorf("missing rectclip:\n%s", out)
+	}
+	if !strings.Contains(out, "%%BoundingBox: 0 35 36 37\n") {
+		t.Errorf("bounding box not clipped:\n%s", out)
+	}
+}
+
+func TestEPSRenderer_Operators(t *testing.T) {
+	out := renderEPS(t, func(r *EPSRenderer) {
+		r.SetStyle(Style{Color: color.RGBA{R: 0xff, A: 0xff}, LineWidth: 0.5, Dash: []float64{2.54, 1.27}})
+		r.Circle(12.7, 12.7, 2.54)
+		r.Arc(12.7, 12.7, 2.54, 0, 90)
+		r.Text(0, 25.4, 3.175, `a(b)\c é`)
+	})
+	wants := []string{
+		"1 0 0 setrgbcolor\n",
+		"1.417 setlinewidth\n",
+		"[7.2 3.6] 0 setdash\n",
+		"36 36 7.2 C\n",
+		"36 36 7.2 0 90 A\n",
+		"9 F\n",
+		`(a\(b\)\\c \351) 0 0 T` + "\n",
+	}
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPSString(t *testing.T) {
+	if got, want := psString("日本"), "(??)"; got != want {
+		t.Errorf("psString() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,359 @@
+package renderers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/daidai-ok/dxfconv/pkg/pathorder"
+)
+
+// HPGL plotter units per millimeter
+const hpglUnit = 40
+
+// DefaultPenTable maps the standard ACI colours to the pens of an eight pen
+// plotter: black on pen 1, followed by red, yellow, green, cyan, blue and magenta.
+var DefaultPenTable = map[int]int{
+	7: 1,
+	1: 2,
+	2: 3,
+	3: 4,
+	4: 5,
+	5: 6,
+	6: 7,
+}
+
+// HPGLRenderer implements the Renderer interface for HPGL/2 plotter output.
+// Paths are collected and written by Finish, grouped by pen and ordered to
+// keep pen-up travel short.
+type HPGLRenderer struct {
+	writer io.Writer
+	height float64 // page height in plotter units
+	style  Style
+	paths  []*hpglPath
+	clip   *[4]int
+
+	// PenTable maps ACI colour numbers to pen numbers. Nil selects DefaultPenTable.
+	PenTable map[int]int
+	// DefaultPen is used for colours missing from PenTable. Zero selects pen 1.
+	DefaultPen int
+}
+
+type hpglPathKind int
+
+const (
+	hpglPolyline hpglPathKind = iota
+	hpglArc
+	hpglCircle
+	hpglLabel
+)
+
+// hpglPath is a drawing primitive in plotter units
+type hpglPath struct {
+	kind  hpglPathKind
+	pen   int
+	width float64 // mm
+	dash  []float64
+
+	// points of a polyline; the start point of an arc; the centre of a circle
+	// or the origin of a label
+	points [][2]int
+	center [2]int
+	sweep  float64 // degrees, counter-clockwise
+	radius int
+	text   string
+	size   float64 // label height in mm
+}
+
+// start returns the point where the pen is put down
+func (p *hpglPath) start() [2]int {
+	return p.points[0]
+}
+
+// end returns the point where the pen is lifted
+func (p *hpglPath) end() [2]int {
+	switch p.kind {
+	case hpglPolyline:
+		return p.points[len(p.points)-1]
+	case hpglArc:
+		return rotate(p.points[0], p.center, p.sweep)
+	}
+	return p.points[0]
+}
+
+// reverse draws the path from its end, if possible
+func (p *hpglPath) reverse() {
+	switch p.kind {
+	case hpglPolyline:
+		slices.Reverse(p.points)
+	case hpglArc:
+		p.points[0] = p.end()
+		p.sweep = -p.sweep
+	}
+}
+
+// rotate turns p counter-clockwise around c
+func rotate(p, c [2]int, degrees float64) [2]int {
+	a := degrees * math.Pi / 180
+	dx, dy := float64(p[0]-c[0]), float64(p[1]-c[1])
+	return [2]int{
+		c[0] + int(math.Round(dx*math.Cos(a)-dy*math.Sin(a))),
+		c[1] + int(math.Round(dx*math.Sin(a)+dy*math.Cos(a))),
+	}
+}
+
+// NewHPGLRenderer creates a new HPGLRenderer for a page of the given size in
+// millimeters, which Init may change
+func NewHPGLRenderer(w io.Writer, width, height float64) *HPGLRenderer {
+	r := &HPGLRenderer{writer: w}
+	r.Init(width, height)
+	return r
+}
+
+func (r *HPGLRenderer) Init(width, height float64) {
+	r.height = height * hpglUnit
+}
+
+// plu converts a page position in millimeters to plotter units, whose origin
+// is the bottom-left corner
+func (r *HPGLRenderer) plu(x, y float64) [2]int {
+	return [2]int{int(math.Round(x * hpglUnit)), int(math.Round(r.height - y*hpglUnit))}
+}
+
+func (r *HPGLRenderer) Clip(x, y, width, height float64) {
+	ll := r.plu(x, y+height)
+	ur := r.plu(x+width, y)
+	c := [4]int{ll[0], ll[1], ur[0], ur[1]}
+	if r.clip != nil {
+		c = [4]int{max(c[0], r.clip[0]), max(c[1], r.clip[1]), min(c[2], r.clip[2]), min(c[3], r.clip[3])}
+	}
+	r.clip = &c
+}
+
+func (r *HPGLRenderer) SetStyle(style Style) {
+	r.style = style
+}
+
+// pen returns the pen selected by the current colour
+func (r *HPGLRenderer) pen() int {
+	table := r.PenTable
+	if table == nil {
+		table = DefaultPenTable
+	}
+	if pen, ok := table[r.style.ColorIndex]; ok {
+		return pen
+	}
+	if r.DefaultPen > 0 {
+		return r.DefaultPen
+	}
+	return 1
+}
+
+func (r *HPGLRenderer) add(p *hpglPath) {
+	p.pen = r.pen()
+	p.width = r.style.LineWidth
+	p.dash = r.style.Dash
+	r.paths = append(r.paths, p)
+}
+
+func (r *HPGLRenderer) Line(x1, y1, x2, y2 float64) {
+	r.add(&hpglPath{kind: hpglPolyline, points: [][2]int{r.plu(x1, y1), r.plu(x2, y2)}})
+}
+
+func (r *HPGLRenderer) Circle(x, y, radius float64) {
+	r.add(&hpglPath{kind: hpglCircle, points: [][2]int{r.plu(x, y)}, radius: int(math.Round(radius * hpglUnit))})
+}
+
+// Arc draws an arc counter-clockwise from startAngle to endAngle (degrees) as seen on the page
+func (r *HPGLRenderer) Arc(x, y, radius, startAngle, endAngle float64) {
+	sweep := math.Mod(endAngle-startAngle, 360)
+	if sweep <= 0 {
+		sweep += 360
+	}
+	a := startAngle * math.Pi / 180
+	// Plotter Y grows upwards like DXF, so angles keep their direction
+	start := r.plu(x+radius*math.Cos(a), y-radius*math.Sin(a))
+	r.add(&hpglPath{kind: hpglArc, points: [][2]int{start}, center: r.plu(x, y), sweep: sweep})
+}
+
+func (r *HPGLRenderer) Polyline(points [][]float64, closed bool) {
+	if len(points) < 2 {
+		return
+	}
+	pts := make([][2]int, 0, len(points)+1)
+	for _, p := range points {
+		pts = append(pts, r.plu(p[0], p[1]))
+	}
+	if closed {
+		pts = append(pts, pts[0])
+	}
+	r.add(&hpglPath{kind: hpglPolyline, points: pts})
+}
+
+func (r *HPGLRenderer) Text(x, y, height float64, text string) {
+	r.add(&hpglPath{kind: hpglLabel, points: [][2]int{r.plu(x, y)}, text: text, size: height})
+}
+
+// order groups the paths by pen and chains each group from nearest neighbour
+// to nearest neighbour, reversing paths when that shortens the pen-up move.
+func (r *HPGLRenderer) order() []*hpglPath {
+	byPen := make(map[int][]*hpglPath)
+	var pens []int
+	for _, p := range r.paths {
+		if _, ok := byPen[p.pen]; !ok {
+			pens = append(pens, p.pen)
+		}
+		byPen[p.pen] = append(byPen[p.pen], p)
+	}
+	sort.Ints(pens)
+
+	ordered := make([]*hpglPath, 0, len(r.paths))
+	var pos [2]int
+	for _, pen := range pens {
+		paths := byPen[pen]
+		ends := make([]pathorder.Path, len(paths))
+		for i, p := range paths {
+			ends[i] = pathorder.Path{
+				Start:      float2(p.start()),
+				End:        float2(p.end()),
+				Reversible: p.kind == hpglPolyline || p.kind == hpglArc,
+			}
+		}
+		for _, s := range pathorder.Nearest(ends, float2(pos)) {
+			p := paths[s.Index]
+			if s.Reversed {
+				p.reverse()
+			}
+			ordered = append(ordered, p)
+			pos = p.end()
+		}
+	}
+	return ordered
+}
+
+func float2(p [2]int) [2]float64 {
+	return [2]float64{float64(p[0]), float64(p[1])}
+}
+
+func (r *HPGLRenderer) Finish() error {
+	w := bufio.NewWriter(r.writer)
+	w.WriteString("IN;PA;")
+	if r.clip != nil {
+		fmt.Fprintf(w, "IW%d,%d,%d,%d;", r.clip[0], r.clip[1], r.clip[2], r.clip[3])
+	}
+	w.WriteString("\n")
+
+	pen := 0
+	width := math.NaN()
+	var dash []float64
+	// Pen position and state; the position is unknown after initialization
+	// and after a label moved the pen
+	var pos [2]int
+	down, known := false, false
+	moveTo := func(p [2]int) {
+		if down || !known || p != pos {
+			fmt.Fprintf(w, "PU%d,%d;", p[0], p[1])
+			pos, down, known = p, false, true
+		}
+	}
+
+	for _, p := range r.order() {
+		if p.pen != pen {
+			if down {
+				w.WriteString("PU;")
+				down = false
+			}
+			fmt.Fprintf(w, "SP%d;", p.pen)
+			pen = p.pen
+		}
+		if p.width != width {
+			if p.width > 0 {
+				fmt.Fprintf(w, "PW%s;", FormatNumber(p.width, 3))
+			} else {
+				// Default pen width
+				w.WriteString("PW;")
+			}
+			width = p.width
+		}
+		if !slices.Equal(p.dash, dash) {
+			w.WriteString(hpglLineType(p.dash))
+			dash = p.dash
+		}
+
+		switch p.kind {
+		case hpglPolyline:
+			if !known || p.start() != pos {
+				moveTo(p.start())
+			}
+			w.WriteString("PD")
+			for i, pt := range p.points[1:] {
+				if i > 0 {
+					w.WriteString(",")
+				}
+				fmt.Fprintf(w, "%d,%d", pt[0], pt[1])
+			}
+			w.WriteString(";")
+			down = true
+		case hpglArc:
+			if !known || p.start() != pos {
+				moveTo(p.start())
+			}
+			if !down {
+				w.WriteString("PD;")
+				down = true
+			}
+			fmt.Fprintf(w, "AA%d,%d,%s;", p.center[0], p.center[1], FormatNumber(p.sweep, 3))
+		case hpglCircle:
+			moveTo(p.start())
+			// CI lowers the pen for the circle and lifts it again
+			fmt.Fprintf(w, "CI%d;", p.radius)
+		case hpglLabel:
+			moveTo(p.start())
+			// Character width and cap height in centimeters
+			fmt.Fprintf(w, "SI%s,%s;LB%s\x03", FormatNumber(p.size*0.06, 3), FormatNumber(p.size*0.1, 3), hpglLabelText(p.text))
+			// The label leaves the pen after its last character
+			known = false
+		}
+		pos = p.end()
+		w.WriteString("\n")
+	}
+
+	w.WriteString("PU;SP0;\n")
+	return w.Flush()
+}
+
+// hpglLineType returns the commands selecting a dash pattern given as
+// alternating dash and gap lengths in millimeters, or a solid line.
+func hpglLineType(dash []float64) string {
+	total := 0.0
+	for _, v := range dash {
+		total += v
+	}
+	if total <= 0 {
+		return "LT;"
+	}
+	// User line type 1, with elements in percent of the pattern length
+	var b strings.Builder
+	b.WriteString("UL1")
+	for _, v := range dash {
+		b.WriteString("," + FormatNumber(v/total*100, 3))
+	}
+	// Pattern length in millimeters (mode 1)
+	fmt.Fprintf(&b, ";LT1,%s,1;", FormatNumber(total, 3))
+	return b.String()
+}
+
+// hpglLabelText replaces the characters that cannot appear in a label:
+// control characters, including the ETX terminator, and non-ASCII characters.
+func hpglLabelText(s string) string {
+	return strings.Map(func(c rune) rune {
+		if c < 0x20 || c >= 0x7f {
+			return '?'
+		}
+		return c
+	}, s)
+}
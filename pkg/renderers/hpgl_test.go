@@ -0,0 +1,127 @@
+package renderers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func renderHPGL(t *testing.T, setup func(r *HPGLRenderer), draw func(r *HPGLRenderer)) string {
+	t.Helper()
+	var buf bytes.Buffer
+	r := NewHPGLRenderer(&buf, 100, 100)
+	if setup != nil {
+		setup(r)
+	}
+	r.Init(100, 100)
+	draw(r)
+	if err := r.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestNewHPGLRenderer_PageSize(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewHPGLRenderer(&buf, 100, 100)
+	// The top-left corner of the page is at the top of the plotter
+	r.Line(0, 0, 10, 0)
+	if err := r.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "PU0,4000;PD400,4000;") {
+		t.Errorf("page size not kept before Init:\n%s", out)
+	}
+}
+
+func TestHPGLRenderer_Commands(t *testing.T) {
+	out := renderHPGL(t, nil, func(r *HPGLRenderer) {
+		r.SetStyle(Style{ColorIndex: 7})
+		r.Polyline([][]float64{{0, 100}, {10, 100}, {10, 90}}, false)
+		r.Arc(50, 50, 10, 0, 90)
+		r.Circle(50, 50, 5)
+		r.Text(10, 10, 5, "A;B\x03")
+	})
+
+	if !strings.HasPrefix(out, "IN;PA;\n") {
+		t.Errorf("missing initialization:\n%s", out)
+	}
+	wants := []string{
+		"SP1;",
+		// Plotter units are 0.025 mm with Y up
+		"PU0,0;PD400,0,400,400;",
+		"PU2400,2000;PD;AA2000,2000,90;",
+		"PU2000,2000;CI200;",
+		"PU400,3600;SI0.3,0.5;LBA;B?\x03",
+	}
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "PU;SP0;\n") {
+		t.Errorf("pen not put away:\n%s", out)
+	}
+}
+
+func TestHPGLRenderer_PenTable(t *testing.T) {
+	draw := func(r *HPGLRenderer) {
+		r.SetStyle(Style{ColorIndex: 5})
+		r.Line(0, 0, 10, 0)
+		r.SetStyle(Style{ColorIndex: 42})
+		r.Line(0, 10, 10, 10)
+	}
+
+	out := renderHPGL(t, nil, draw)
+	// Blue on pen 6, unknown colours on pen 1, pens in ascending order
+	if i, j := strings.Index(out, "SP1;"), strings.Index(out, "SP6;"); i < 0 || j < i {
+		t.Errorf("unexpected pens:\n%s", out)
+	}
+
+	out = renderHPGL(t, func(r *HPGLRenderer) {
+		r.PenTable = map[int]int{5: 3}
+		r.DefaultPen = 2
+	}, draw)
+	if strings.Contains(out, "SP1;") || !strings.Contains(out, "SP2;") || !strings.Contains(out, "SP3;") {
+		t.Errorf("pen table not applied:\n%s", out)
+	}
+}
+
+func TestHPGLRenderer_Order(t *testing.T) {
+	out := renderHPGL(t, nil, func(r *HPGLRenderer) {
+		r.Line(0, 100, 10, 100)
+		r.Line(90, 10, 100, 10)
+		// Drawn backwards, but continues the first line
+		r.Line(20, 100, 10, 100)
+	})
+	// The second line is reversed and drawn without lifting the pen
+	want := "PU0,0;PD400,0;\nPD800,0;\nPU3600,3600;PD4000,3600;"
+	if !strings.Contains(out, want) {
+		t.Errorf("output missing %q:\n%s", want, out)
+	}
+}
+
+func TestHPGLRenderer_Style(t *testing.T) {
+	out := renderHPGL(t, nil, func(r *HPGLRenderer) {
+		r.SetStyle(Style{LineWidth: 0.5, Dash: []float64{2, 1}})
+		r.Line(0, 100, 10, 100)
+		r.SetStyle(Style{})
+		r.Line(10, 90, 0, 90)
+	})
+	wants := []string{"PW0.5;UL1,66.667,33.333;LT1,3,1;", "PW;LT;"}
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHPGLRenderer_Clip(t *testing.T) {
+	out := renderHPGL(t, nil, func(r *HPGLRenderer) {
+		r.Clip(10, 10, 50, 50)
+		r.Line(0, 0, 100, 100)
+	})
+	if !strings.Contains(out, "IW400,1600,2400,3600;") {
+		t.Errorf("missing input window:\n%s", out)
+	}
+}
@@ -1,14 +1,33 @@
 package renderers
 
+import "image/color"
+
+// Style describes the pen used by subsequent drawing operations
+type Style struct {
+	Color color.RGBA
+	// ColorIndex is the ACI colour number Color was resolved from, zero if unknown.
+	// Pen plotters use it to select a pen.
+	ColorIndex int
+	// LineWidth in page units. Zero selects the renderer default.
+	LineWidth float64
+	// Dash holds alternating dash and gap lengths in page units. Empty draws solid lines.
+	Dash []float64
+}
+
 // Renderer defines the interface for drawing backend
 type Renderer interface {
 	// Init initializes the renderer with page dimensions
 	Init(width, height float64)
+	// Clip restricts all subsequent drawing to the rectangle with top-left corner x, y
+	Clip(x, y, width, height float64)
+	// SetStyle sets the colour, line width and dash pattern of subsequent drawing
+	SetStyle(style Style)
 	// Line draws a line segment
 	Line(x1, y1, x2, y2 float64)
 	// Circle draws a circle
 	Circle(x, y, r float64)
-	// Arc draws an arc
+	// Arc draws an arc counter-clockwise as seen on the page, from startAngle to
+	// endAngle in degrees measured from the positive X axis
 	Arc(x, y, r, startAngle, endAngle float64)
 	// Polyline draws a polyline
 	Polyline(points [][]float64, closed bool)
@@ -17,3 +36,29 @@ type Renderer interface {
 	// Finish finalizes the rendering and writes to output
 	Finish() error
 }
+
+// EntityInfo describes the DXF entity whose primitives are about to be drawn
+type EntityInfo struct {
+	Type   string
+	Handle string
+	Layer  string
+	// LayerVisible is false for layers that are off or frozen in the layer table
+	LayerVisible bool
+}
+
+// EntityRenderer is implemented by renderers that group primitives by entity or layer.
+// BeginEntity and EndEntity enclose the drawing calls of each entity.
+type EntityRenderer interface {
+	BeginEntity(info EntityInfo)
+	EndEntity()
+}
+
+// ChunkRenderer is implemented by renderers that can draw runs of entities
+// concurrently. NewChunk returns a renderer for a run whose drawing starts
+// with the style prev, or with the current style if prev is nil; it reports
+// false if the renderer cannot draw in chunks. AppendChunk adds the output of
+// a chunk after what was drawn so far. Chunks are appended in drawing order.
+type ChunkRenderer interface {
+	NewChunk(prev *Style) (Renderer, bool)
+	AppendChunk(chunk Renderer)
+}
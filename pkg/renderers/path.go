@@ -0,0 +1,154 @@
+package renderers
+
+import "math"
+
+// SegmentKind identifies the operation of a path segment
+type SegmentKind int
+
+const (
+	// MoveTo starts a subpath at Points[0]
+	MoveTo SegmentKind = iota
+	// LineTo draws a line to Points[0]
+	LineTo
+	// CurveTo draws a cubic Bezier curve to Points[2] with the control
+	// points Points[0] and Points[1]
+	CurveTo
+	// ArcTo draws an arc of the circle around Points[0] with Radius, from
+	// StartAngle sweeping by Sweep degrees, counter-clockwise as seen on the
+	// page for positive Sweep. A line connects the current point to the
+	// start of the arc.
+	ArcTo
+	// Close closes the subpath with a line to its start
+	Close
+)
+
+// Segment is an operation of a Path
+type Segment struct {
+	Kind   SegmentKind
+	Points [3][2]float64
+	// Radius, StartAngle and Sweep describe ArcTo segments
+	Radius, StartAngle, Sweep float64
+}
+
+// Path is a sequence of subpaths in page coordinates
+type Path struct {
+	Segments []Segment
+}
+
+func (p *Path) MoveTo(x, y float64) {
+	p.Segments = append(p.Segments, Segment{Kind: MoveTo, Points: [3][2]float64{{x, y}}})
+}
+
+func (p *Path) LineTo(x, y float64) {
+	p.Segments = append(p.Segments, Segment{Kind: LineTo, Points: [3][2]float64{{x, y}}})
+}
+
+func (p *Path) CurveTo(x1, y1, x2, y2, x3, y3 float64) {
+	p.Segments = append(p.Segments, Segment{Kind: CurveTo, Points: [3][2]float64{{x1, y1}, {x2, y2}, {x3, y3}}})
+}
+
+func (p *Path) ArcTo(cx, cy, r, startAngle, sweep float64) {
+	p.Segments = append(p.Segments, Segment{Kind: ArcTo, Points: [3][2]float64{{cx, cy}}, Radius: r, StartAngle: startAngle, Sweep: sweep})
+}
+
+func (p *Path) Close() {
+	p.Segments = append(p.Segments, Segment{Kind: Close})
+}
+
+// PathRenderer is implemented by renderers that draw curves natively.
+// Other renderers are given the flattened path as polylines.
+type PathRenderer interface {
+	// Path strokes the path
+	Path(p *Path)
+}
+
+// Maximum distance in page units between a flattened path and the true curve
+const pathTolerance = 0.01
+
+// Polylines flattens the path into the points of its subpaths and whether
+// each is closed
+func (p *Path) Polylines() (polylines [][][]float64, closed []bool) {
+	var pts [][]float64
+	end := func(close bool) {
+		if len(pts) > 1 {
+			polylines = append(polylines, pts)
+			closed = append(closed, close)
+		}
+		pts = nil
+	}
+	for _, s := range p.Segments {
+		switch s.Kind {
+		case MoveTo:
+			end(false)
+			pts = append(pts, []float64{s.Points[0][0], s.Points[0][1]})
+		case LineTo:
+			pts = append(pts, []float64{s.Points[0][0], s.Points[0][1]})
+		case CurveTo:
+			if len(pts) == 0 {
+				continue
+			}
+			last := pts[len(pts)-1]
+			pts = append(pts, bezierPoints([2]float64{last[0], last[1]}, s.Points)...)
+		case ArcTo:
+			pts = append(pts, arcPoints(s.Points[0], s.Radius, s.StartAngle, s.Sweep)...)
+		case Close:
+			end(true)
+		}
+	}
+	end(false)
+	return polylines, closed
+}
+
+// bezierPoints returns the points of a cubic Bezier curve from p0, without p0
+func bezierPoints(p0 [2]float64, c [3][2]float64) [][]float64 {
+	// The curve deviates from its chords by at most 3/4 of the largest
+	// second difference of its control points over the square of the number
+	// of chords
+	dd := 0.0
+	for i, p := range [][2]float64{p0, c[0]} {
+		q, r := c[i], c[i+1]
+		dd = max(dd, math.Hypot(p[0]-2*q[0]+r[0], p[1]-2*q[1]+r[1]))
+	}
+	n := int(min(max(math.Ceil(math.Sqrt(0.75*dd/pathTolerance)), 1), 100))
+	pts := make([][]float64, n)
+	for i := range pts {
+		t := float64(i+1) / float64(n)
+		u := 1 - t
+		a, b, cc, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		pts[i] = []float64{
+			a*p0[0] + b*c[0][0] + cc*c[1][0] + d*c[2][0],
+			a*p0[1] + b*c[0][1] + cc*c[1][1] + d*c[2][1],
+		}
+	}
+	return pts
+}
+
+// arcPoints returns the points of an arc as described by ArcTo segments
+func arcPoints(c [2]float64, r, startAngle, sweep float64) [][]float64 {
+	a0 := startAngle * math.Pi / 180
+	sw := sweep * math.Pi / 180
+	n := 1
+	if r > pathTolerance {
+		n = int(math.Ceil(math.Abs(sw) / (2 * math.Acos(1-pathTolerance/r))))
+	}
+	n = min(max(n, 1), 360)
+	pts := make([][]float64, n+1)
+	for i := range pts {
+		a := a0 + sw*float64(i)/float64(n)
+		// Page Y grows downwards
+		pts[i] = []float64{c[0] + r*math.Cos(a), c[1] - r*math.Sin(a)}
+	}
+	return pts
+}
+
+// drawPath strokes p on r
+func drawPath(r Renderer, p *Path) {
+	if pr, ok := r.(PathRenderer); ok {
+		pr.Path(p)
+		return
+	}
+	polylines, closed := p.Polylines()
+	for i, pts := range polylines {
+		r.Polyline(pts, closed[i])
+	}
+}
@@ -0,0 +1,203 @@
+package renderers
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+	"github.com/daidai-ok/dxfconv/pkg/pdf"
+)
+
+// recorder records the polylines drawn on it
+type recorder struct {
+	polylines [][][]float64
+	closed    []bool
+}
+
+func (r *recorder) Init(width, height float64)             {}
+func (r *recorder) Clip(x, y, width, height float64)       {}
+func (r *recorder) SetStyle(style Style)                   {}
+func (r *recorder) Line(x1, y1, x2, y2 float64)            {}
+func (r *recorder) Circle(x, y, radius float64)            {}
+func (r *recorder) Arc(x, y, radius, start, end float64)   {}
+func (r *recorder) Text(x, y, height float64, text string) {}
+func (r *recorder) Finish() error                          { return nil }
+func (r *recorder) Polyline(points [][]float64, closed bool) {
+	r.polylines = append(r.polylines, points)
+	r.closed = append(r.closed, closed)
+}
+
+// pathRecorder also records the paths drawn on it
+type pathRecorder struct {
+	recorder
+	paths []*Path
+}
+
+func (r *pathRecorder) Path(p *Path) { r.paths = append(r.paths, p) }
+
+func TestDraw_PolylineWithoutBulges(t *testing.T) {
+	r := &recorder{}
+	DrawEntity(r, &dxf.LwPolyline{
+		Vertices: []dxf.LwPolylineVertex{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}},
+		Closed:   true,
+	}, 1, 0, 0, 100)
+
+	if len(r.polylines) != 1 || !r.closed[0] {
+		t.Fatalf("got %d polylines, closed %v; want one closed polyline", len(r.polylines), r.closed)
+	}
+	want := [][]float64{{0, 100}, {10, 100}, {10, 90}}
+	for i, p := range want {
+		if r.polylines[0][i][0] != p[0] || r.polylines[0][i][1] != p[1] {
+			t.Errorf("point %d = %v, want %v", i, r.polylines[0][i], p)
+		}
+	}
+}
+
+func TestDraw_Bulge(t *testing.T) {
+	// A bulge of one is a half circle, counter-clockwise from (0,0) to (10,0)
+	// through (5,-5)
+	e := &dxf.LwPolyline{Vertices: []dxf.LwPolylineVertex{{X: 0, Y: 0, Bulge: 1}, {X: 10, Y: 0}}}
+
+	pr := &pathRecorder{}
+	DrawEntity(pr, e, 2, 0, 0, 100)
+	if len(pr.paths) != 1 {
+		t.Fatalf("got %d paths, want 1", len(pr.paths))
+	}
+	arc := pr.paths[0].Segments[1]
+	if arc.Kind != ArcTo || arc.Points[0] != [2]float64{10, 100} || arc.Radius != 10 ||
+		math.Abs(arc.StartAngle-180) > 1e-9 || math.Abs(arc.Sweep-180) > 1e-9 {
+		t.Errorf("arc = %+v, want centre (10,100) radius 10 from 180 sweeping 180", arc)
+	}
+
+	r := &recorder{}
+	DrawEntity(r, e, 2, 0, 0, 100)
+	if len(r.polylines) != 1 {
+		t.Fatalf("got %d polylines, want 1", len(r.polylines))
+	}
+	pts := r.polylines[0]
+	for _, p := range pts {
+		if d := math.Hypot(p[0]-10, p[1]-100); math.Abs(d-10) > 1e-9 {
+			t.Fatalf("point %v is %v from the centre, want 10", p, d)
+		}
+		// The arc passes below the chord in DXF, so above it on the page
+		if p[1] < 100-1e-9 {
+			t.Fatalf("point %v is on the wrong side of the chord", p)
+		}
+	}
+	if last := pts[len(pts)-1]; math.Abs(last[0]-20) > 1e-9 || math.Abs(last[1]-100) > 1e-9 {
+		t.Errorf("last point = %v, want (20,100)", last)
+	}
+}
+
+func TestBulgeArc(t *testing.T) {
+	// A negative bulge of one is a clockwise half circle
+	c, r, start, sweep := BulgeArc([2]float64{0, 0}, [2]float64{10, 0}, -1)
+	if c != [2]float64{5, 0} || r != 5 || math.Abs(start-180) > 1e-9 || math.Abs(sweep+180) > 1e-9 {
+		t.Errorf("BulgeArc() = %v, %v, %v, %v; want centre (5,0) radius 5 from 180 sweeping -180", c, r, start, sweep)
+	}
+}
+
+func TestSplinePath(t *testing.T) {
+	pts := [][2]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}}
+	t.Run("bezier", func(t *testing.T) {
+		// A clamped cubic spline with one span is its Bezier curve
+		var p Path
+		splinePath(&p, &dxf.Spline{Degree: 3, Knots: []float64{0, 0, 0, 0, 1, 1, 1, 1}}, pts)
+		if len(p.Segments) != 2 || p.Segments[1].Kind != CurveTo {
+			t.Fatalf("segments = %+v, want a move and a curve", p.Segments)
+		}
+		if got := p.Segments[1].Points; got != [3][2]float64{{0, 10}, {10, 10}, {10, 0}} {
+			t.Errorf("curve = %v, want the control points", got)
+		}
+	})
+	t.Run("spans", func(t *testing.T) {
+		// A uniform quadratic spline has a span per control point past the
+		// degree, each starting at the midpoint of a control polygon leg
+		var p Path
+		splinePath(&p, &dxf.Spline{Degree: 2, Knots: []float64{0, 1, 2, 3, 4, 5, 6}}, pts)
+		if len(p.Segments) != 3 {
+			t.Fatalf("got %d segments, want 3", len(p.Segments))
+		}
+		want := [][2]float64{{0, 5}, {5, 10}, {10, 5}}
+		if got := p.Segments[0].Points[0]; got != want[0] {
+			t.Errorf("start = %v, want %v", got, want[0])
+		}
+		for i, s := range p.Segments[1:] {
+			if got := s.Points[2]; got != want[i+1] {
+				t.Errorf("span %d ends at %v, want %v", i, got, want[i+1])
+			}
+		}
+	})
+	t.Run("points", func(t *testing.T) {
+		// The same spans evaluated at their ends and at the end of the domain
+		u := []float64{0, 1, 2, 3, 4, 5, 6}
+		for _, tt := range []struct {
+			t    float64
+			want [2]float64
+		}{{2, [2]float64{0, 5}}, {3, [2]float64{5, 10}}, {4, [2]float64{10, 5}}} {
+			if got := SplinePoint(pts, u, 2, tt.t); got != tt.want {
+				t.Errorf("SplinePoint(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		}
+	})
+	t.Run("invalid knots", func(t *testing.T) {
+		var p Path
+		splinePath(&p, &dxf.Spline{Degree: 3, Closed: true}, pts)
+		polylines, closed := p.Polylines()
+		if len(polylines) != 1 || len(polylines[0]) != 4 || !closed[0] {
+			t.Errorf("got %v, closed %v; want the closed control polygon", polylines, closed)
+		}
+	})
+}
+
+func TestPDFRenderer_Path(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewPDFRenderer(&buf, "", 100, 100)
+	r.LineJoin = pdf.RoundJoin
+	r.Init(100, 100)
+	r.Polyline([][]float64{{0, 0}, {10, 0}, {10, 10}}, true)
+	var p Path
+	p.MoveTo(60, 50)
+	p.ArcTo(50, 50, 10, 0, 90)
+	r.Path(&p)
+	if err := r.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"1 j\n",
+		// The polyline is a single path with all its points
+		"0 283.46 m\n28.35 283.46 l\n28.35 255.12 l\nh\nS\n",
+		// Counter-clockwise on the page, from 3 to 12 o'clock
+		"170.08 141.73 m\n170.08 157.39 157.39 170.08 141.73 170.08 c\nS\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPDFRenderer_LayerCase(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewPDFRenderer(&buf, "", 100, 100)
+	r.OptionalContent = true
+	r.Init(100, 100)
+	for _, layer := range []string{"Walls", "DIM", "WALLS", "walls"} {
+		r.BeginEntity(EntityInfo{Layer: layer, LayerVisible: true})
+		r.Line(0, 0, 10, 10)
+		r.EndEntity()
+	}
+	if err := r.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "/Type /OCG"); n != 2 {
+		t.Errorf("got %d optional content groups, want 2", n)
+	}
+	if !strings.Contains(out, "/Name (Walls)") {
+		t.Errorf("group should be named after the first spelling:\n%s", out)
+	}
+}
@@ -1,7 +1,10 @@
 package renderers
 
 import (
+	"image/color"
 	"io"
+	"slices"
+	"strings"
 
 	"github.com/daidai-ok/dxfconv/pkg/pdf"
 )
@@ -10,22 +13,79 @@ import (
 type PDFRenderer struct {
 	pdf    *pdf.PDF
 	writer io.Writer
+	style  Style
+
+	// OptionalContent places each DXF layer in a PDF optional content group
+	// that can be toggled in the viewer.
+	OptionalContent bool
+	// Compress compresses the page content and writes the other objects to
+	// object and cross-reference streams, which requires PDF 1.5.
+	Compress bool
+	// LineJoin and LineCap shape the corners and ends of strokes. The zero
+	// values are PDF's defaults of mitered joins and butt caps.
+	LineJoin pdf.LineJoin
+	LineCap  pdf.LineCap
+	// Info is the document information and XMP metadata
+	Info pdf.Info
+	// PDFA writes a PDF/A-2b archival document; text makes Finish fail with
+	// pdf.ErrFontNotEmbedded
+	PDFA bool
+	// ViewerPreferences control how viewers open the document
+	ViewerPreferences pdf.ViewerPreferences
+
+	layerIDs  map[string]int // optional content groups by lowercase layer name
+	openLayer string
+	layerOpen bool
 }
 
+// PDF's default line width of one point, in millimeters
+const pdfDefaultLineWidth = 1 / pdf.UnitMM
+
 // NewPDFRenderer creates a new PDFRenderer
 func NewPDFRenderer(w io.Writer, orientation string, width, height float64) *PDFRenderer {
 	// Simple validation of orientation - our simple PDF assumes dimensions are enough.
 	// We ignore orientation flag if width/height are correct, or swap them if needed?
 	// The caller (Convert) calculates width/height.
 
-	p := pdf.New(width, height)
+	// The converter works in millimeters
+	p := pdf.NewWithUnit(width, height, pdf.UnitMM)
 	p.AddPage()
 
-	return &PDFRenderer{pdf: p, writer: w}
+	return &PDFRenderer{pdf: p, writer: w, style: Style{Color: color.RGBA{A: 0xff}}}
 }
 
 func (r *PDFRenderer) Init(width, height float64) {
-	// Already initialized
+	// The page is already set up, only the stroke shape is left
+	if r.LineJoin != pdf.MiterJoin {
+		r.pdf.SetLineJoin(r.LineJoin)
+	}
+	if r.LineCap != pdf.ButtCap {
+		r.pdf.SetLineCap(r.LineCap)
+	}
+}
+
+func (r *PDFRenderer) Clip(x, y, width, height float64) {
+	r.pdf.ClipRect(x, y, width, height)
+}
+
+func (r *PDFRenderer) SetStyle(style Style) {
+	// Only emit the operators that change the graphics state
+	if style.Color != r.style.Color {
+		c := style.Color
+		r.pdf.SetStrokeColor(c.R, c.G, c.B)
+		r.pdf.SetFillColor(c.R, c.G, c.B)
+	}
+	if style.LineWidth != r.style.LineWidth {
+		w := style.LineWidth
+		if w == 0 {
+			w = pdfDefaultLineWidth
+		}
+		r.pdf.SetLineWidth(w)
+	}
+	if !slices.Equal(style.Dash, r.style.Dash) {
+		r.pdf.SetDash(style.Dash)
+	}
+	r.style = style
 }
 
 func (r *PDFRenderer) Line(x1, y1, x2, y2 float64) {
@@ -37,7 +97,8 @@ func (r *PDFRenderer) Circle(x, y, radius float64) {
 }
 
 func (r *PDFRenderer) Arc(x, y, radius, startAngle, endAngle float64) {
-	r.pdf.Arc(x, y, radius, startAngle, endAngle)
+	// pdf.Arc sweeps clockwise on the page, so mirror the angles
+	r.pdf.Arc(x, y, radius, -endAngle, -startAngle)
 }
 
 func (r *PDFRenderer) Polyline(points [][]float64, closed bool) {
@@ -45,12 +106,39 @@ func (r *PDFRenderer) Polyline(points [][]float64, closed bool) {
 		return
 	}
 
-	for i := 0; i < len(points)-1; i++ {
-		r.pdf.Line(points[i][0], points[i][1], points[i+1][0], points[i+1][1])
+	// A single path lets the viewer join the segments
+	r.pdf.MoveTo(points[0][0], points[0][1])
+	for _, p := range points[1:] {
+		r.pdf.LineTo(p[0], p[1])
 	}
 	if closed {
-		r.pdf.Line(points[len(points)-1][0], points[len(points)-1][1], points[0][0], points[0][1])
+		r.pdf.ClosePath()
 	}
+	r.pdf.Stroke()
+}
+
+// Path strokes p with native lines, Bezier curves and arcs
+func (r *PDFRenderer) Path(p *Path) {
+	if len(p.Segments) == 0 {
+		return
+	}
+	for _, s := range p.Segments {
+		pt := s.Points
+		switch s.Kind {
+		case MoveTo:
+			r.pdf.MoveTo(pt[0][0], pt[0][1])
+		case LineTo:
+			r.pdf.LineTo(pt[0][0], pt[0][1])
+		case CurveTo:
+			r.pdf.CurveTo(pt[0][0], pt[0][1], pt[1][0], pt[1][1], pt[2][0], pt[2][1])
+		case ArcTo:
+			// pdf.ArcTo measures angles clockwise on the page
+			r.pdf.ArcTo(pt[0][0], pt[0][1], s.Radius, -s.StartAngle, -(s.StartAngle + s.Sweep))
+		case Close:
+			r.pdf.ClosePath()
+		}
+	}
+	r.pdf.Stroke()
 }
 
 // Text draws text at the specified location
@@ -58,6 +146,67 @@ func (r *PDFRenderer) Text(x, y, height float64, text string) {
 	r.pdf.Text(x, y, height, text)
 }
 
+// BeginEntity places the entity in the optional content group of its layer.
+// Consecutive entities on the same layer share one marked-content section.
+func (r *PDFRenderer) BeginEntity(info EntityInfo) {
+	if !r.OptionalContent {
+		return
+	}
+	// Layer names are case-insensitive, the group takes the first spelling seen
+	if r.layerOpen && strings.EqualFold(info.Layer, r.openLayer) {
+		return
+	}
+	if r.layerOpen {
+		r.pdf.EndLayer()
+	}
+	if r.layerIDs == nil {
+		r.layerIDs = make(map[string]int)
+	}
+	key := strings.ToLower(info.Layer)
+	id, ok := r.layerIDs[key]
+	if !ok {
+		id = r.pdf.AddLayer(info.Layer, info.LayerVisible)
+		r.layerIDs[key] = id
+	}
+	r.pdf.BeginLayer(id)
+	r.openLayer = info.Layer
+	r.layerOpen = true
+}
+
+func (r *PDFRenderer) EndEntity() {
+	// The section stays open until an entity on another layer begins
+}
+
+// NewChunk returns a renderer drawing into a separate content buffer. Chunks
+// are not supported with OptionalContent, whose groups are numbered in
+// drawing order.
+func (r *PDFRenderer) NewChunk(prev *Style) (Renderer, bool) {
+	if r.OptionalContent {
+		return nil, false
+	}
+	style := r.style
+	if prev != nil {
+		style = *prev
+	}
+	return &PDFRenderer{pdf: r.pdf.Chunk(), style: style}, true
+}
+
+// AppendChunk adds the content of a renderer returned by NewChunk
+func (r *PDFRenderer) AppendChunk(chunk Renderer) {
+	c := chunk.(*PDFRenderer)
+	r.pdf.Append(c.pdf)
+	r.style = c.style
+}
+
 func (r *PDFRenderer) Finish() error {
+	if r.layerOpen {
+		r.pdf.EndLayer()
+		r.layerOpen = false
+	}
+	r.pdf.Compress = r.Compress
+	r.pdf.ObjectStreams = r.Compress
+	r.pdf.Info = r.Info
+	r.pdf.PDFA = r.PDFA
+	r.pdf.ViewerPreferences = r.ViewerPreferences
 	return r.pdf.Output(r.writer)
 }
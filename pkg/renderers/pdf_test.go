@@ -0,0 +1,42 @@
+package renderers
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/daidai-ok/dxfconv/pkg/pdf"
+)
+
+func TestPDFRenderer_Arc(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewPDFRenderer(&buf, "portrait", 100, 100)
+	r.Init(100, 100)
+	// A quarter circle counter-clockwise on the page from the right of the
+	// centre to above it
+	r.Arc(50, 50, 10, 0, 90)
+	if err := r.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`([\d.]+) ([\d.]+) m\n(?:[\d.]+ [\d.]+ [\d.]+ [\d.]+ ([\d.]+) ([\d.]+) c\n)+`).FindSubmatch(buf.Bytes())
+	if m == nil {
+		t.Fatalf("no arc in\n%s", buf.Bytes())
+	}
+	var pt [4]float64
+	for i := range pt {
+		v, _ := strconv.ParseFloat(string(m[i+1]), 64)
+		pt[i] = v / pdf.UnitMM
+	}
+	// PDF coordinates grow upwards: the centre is at 50, 50
+	start, end := [2]float64{pt[0], pt[1]}, [2]float64{pt[2], pt[3]}
+	near := func(p, q [2]float64) bool {
+		return math.Abs(p[0]-q[0]) < 0.01 && math.Abs(p[1]-q[1]) < 0.01
+	}
+	// pdf.Arc sweeps clockwise, so the arc may be traced from its end
+	right, top := [2]float64{60, 50}, [2]float64{50, 60}
+	if !(near(start, right) && near(end, top)) && !(near(start, top) && near(end, right)) {
+		t.Errorf("arc from %v to %v, want between [60 50] and [50 60]", start, end)
+	}
+}
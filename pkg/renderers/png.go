@@ -0,0 +1,381 @@
+package renderers
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// DefaultPNGDPI is the resolution of PNG output when none is given
+const DefaultPNGDPI = 96
+
+// DefaultPNGMaxPixels bounds the image size when no MaxPixels is given:
+// 1 GiB of RGBA, an A0 sheet at 300 DPI
+const DefaultPNGMaxPixels = 1 << 28
+
+// ErrImageTooLarge is returned by Finish when the page at the requested
+// resolution exceeds MaxPixels
+var ErrImageTooLarge = errors.New("image too large")
+
+// Line width used when the style has none, matching PDF's default of one point
+const pngDefaultLineWidth = 25.4 / 72
+
+// Maximum distance in pixels between a flattened curve and the true curve
+const flattenTolerance = 0.25
+
+// PNGRenderer implements the Renderer interface for PNG output.
+// Page millimeters are mapped to pixels according to DPI.
+type PNGRenderer struct {
+	writer io.Writer
+	img    *image.RGBA
+	clip   image.Rectangle
+	style  Style
+	scale  float64 // pixels per millimeter
+	ras    vector.Rasterizer
+	faces  map[float64]font.Face
+	err    error
+
+	// DPI is the resolution in dots per inch. Zero selects DefaultPNGDPI.
+	DPI float64
+	// Background fills the image before drawing. Nil leaves it transparent.
+	Background color.Color
+	// Antialias smooths the edges of lines and curves.
+	Antialias bool
+	// MinLineWidth in pixels keeps thin lines visible at low resolutions.
+	MinLineWidth float64
+	// MaxPixels is the largest image drawn, in pixels. Zero selects
+	// DefaultPNGMaxPixels.
+	MaxPixels int64
+}
+
+// NewPNGRenderer creates a new PNGRenderer with a white background and anti-aliasing
+func NewPNGRenderer(w io.Writer, width, height float64) *PNGRenderer {
+	return &PNGRenderer{
+		writer:       w,
+		style:        Style{Color: color.RGBA{A: 0xff}},
+		DPI:          DefaultPNGDPI,
+		Background:   color.White,
+		Antialias:    true,
+		MinLineWidth: 1,
+	}
+}
+
+func (r *PNGRenderer) Init(width, height float64) {
+	dpi := r.DPI
+	if dpi <= 0 {
+		dpi = DefaultPNGDPI
+	}
+	r.scale = dpi / 25.4
+	w, h := math.Ceil(width*r.scale), math.Ceil(height*r.scale)
+	maxPixels := r.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = DefaultPNGMaxPixels
+	}
+	// Written so that NaN sizes fail too
+	if m := float64(maxPixels); !(w*h <= m && w <= m && h <= m) {
+		r.err = fmt.Errorf("%w: %.0f x %.0f pixels, the limit is %d", ErrImageTooLarge, w, h, maxPixels)
+		// Nothing is drawn on an empty image
+		w, h = 0, 0
+	}
+	r.img = image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
+	if r.Background != nil {
+		draw.Draw(r.img, r.img.Bounds(), image.NewUniform(r.Background), image.Point{}, draw.Src)
+	}
+	r.clip = r.img.Bounds()
+}
+
+// Image returns the image drawn so far
+func (r *PNGRenderer) Image() *image.RGBA {
+	return r.img
+}
+
+func (r *PNGRenderer) Clip(x, y, width, height float64) {
+	s := r.scale
+	rect := image.Rect(
+		int(math.Floor(x*s)), int(math.Floor(y*s)),
+		int(math.Ceil((x+width)*s)), int(math.Ceil((y+height)*s)),
+	)
+	r.clip = r.clip.Intersect(rect)
+}
+
+func (r *PNGRenderer) SetStyle(style Style) {
+	r.style = style
+}
+
+func (r *PNGRenderer) Line(x1, y1, x2, y2 float64) {
+	r.stroke([][2]float64{r.px(x1, y1), r.px(x2, y2)}, false)
+}
+
+func (r *PNGRenderer) Circle(x, y, radius float64) {
+	r.stroke(r.arcPoints(x, y, radius, 0, 360), true)
+}
+
+// Arc draws an arc counter-clockwise from startAngle to endAngle (degrees) as seen on the page
+func (r *PNGRenderer) Arc(x, y, radius, startAngle, endAngle float64) {
+	for endAngle <= startAngle {
+		endAngle += 360
+	}
+	r.stroke(r.arcPoints(x, y, radius, startAngle, endAngle), false)
+}
+
+func (r *PNGRenderer) Polyline(points [][]float64, closed bool) {
+	if len(points) < 2 {
+		return
+	}
+	pts := make([][2]float64, len(points))
+	for i, p := range points {
+		pts[i] = r.px(p[0], p[1])
+	}
+	r.stroke(pts, closed)
+}
+
+func (r *PNGRenderer) Text(x, y, height float64, text string) {
+	size := height * r.scale
+	if size <= 0 || r.err != nil {
+		return
+	}
+	face, err := r.face(size)
+	if err != nil {
+		r.err = err
+		return
+	}
+	d := font.Drawer{
+		Dst:  r.img.SubImage(r.clip).(*image.RGBA),
+		Src:  image.NewUniform(r.style.Color),
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(x * r.scale * 64), Y: fixed.Int26_6(y * r.scale * 64)},
+	}
+	d.DrawString(text)
+}
+
+func (r *PNGRenderer) Finish() error {
+	if r.err != nil {
+		return r.err
+	}
+	return png.Encode(r.writer, r.img)
+}
+
+// px converts page millimeters to pixels
+func (r *PNGRenderer) px(x, y float64) [2]float64 {
+	return [2]float64{x * r.scale, y * r.scale}
+}
+
+// arcPoints flattens an arc given in page millimeters to pixels
+func (r *PNGRenderer) arcPoints(x, y, radius, startAngle, endAngle float64) [][2]float64 {
+	c := r.px(x, y)
+	rad := radius * r.scale
+	start := startAngle * math.Pi / 180
+	sweep := (endAngle - startAngle) * math.Pi / 180
+	n := segments(rad, sweep)
+	pts := make([][2]float64, n+1)
+	for i := range pts {
+		a := start + sweep*float64(i)/float64(n)
+		// Page Y grows downwards, so counter-clockwise angles subtract from Y
+		pts[i] = [2]float64{c[0] + rad*math.Cos(a), c[1] - rad*math.Sin(a)}
+	}
+	return pts
+}
+
+// segments returns the number of chords approximating an arc of radius rad
+// pixels within flattenTolerance
+func segments(rad, sweep float64) int {
+	step := math.Pi / 2
+	if rad > flattenTolerance {
+		step = 2 * math.Acos(1-flattenTolerance/rad)
+	}
+	n := int(math.Ceil(math.Abs(sweep) / step))
+	return min(max(n, 4), 4096)
+}
+
+// lineWidth returns the width of the current pen in pixels
+func (r *PNGRenderer) lineWidth() float64 {
+	w := r.style.LineWidth
+	if w == 0 {
+		w = pngDefaultLineWidth
+	}
+	return max(w*r.scale, r.MinLineWidth)
+}
+
+// stroke draws a polyline given in pixels with the current style
+func (r *PNGRenderer) stroke(pts [][2]float64, closed bool) {
+	if closed {
+		pts = append(pts, pts[0])
+	}
+	runs := [][][2]float64{pts}
+	if len(r.style.Dash) > 0 {
+		dash := make([]float64, len(r.style.Dash))
+		for i, v := range r.style.Dash {
+			dash[i] = v * r.scale
+		}
+		if dashed, ok := dashes(pts, dash); ok {
+			runs = dashed
+			// The joint of a closed outline is only drawn without a dash pattern
+			closed = false
+		}
+	}
+
+	half := r.lineWidth() / 2
+	bounds := image.Rectangle{}
+	for _, run := range runs {
+		for _, p := range run {
+			pb := image.Rect(
+				int(math.Floor(p[0]-half)), int(math.Floor(p[1]-half)),
+				int(math.Ceil(p[0]+half)), int(math.Ceil(p[1]+half)),
+			)
+			bounds = bounds.Union(pb)
+		}
+	}
+	bounds = bounds.Intersect(r.clip)
+	if bounds.Empty() {
+		return
+	}
+
+	// Rasterize into a coverage mask the size of the stroke bounds
+	r.ras.Reset(bounds.Dx(), bounds.Dy())
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	for _, run := range runs {
+		for i := 1; i < len(run); i++ {
+			r.segment(run[i-1], run[i], half, ox, oy)
+			// Round joins between segments
+			if i < len(run)-1 || closed {
+				r.dot(run[i], half, ox, oy)
+			}
+		}
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	r.ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	if !r.Antialias {
+		for i, a := range mask.Pix {
+			if a >= 0x80 {
+				mask.Pix[i] = 0xff
+			} else {
+				mask.Pix[i] = 0
+			}
+		}
+	}
+	draw.DrawMask(r.img, bounds, image.NewUniform(r.style.Color), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// segment adds the rectangle covering the segment p-q to the rasterizer.
+// All shapes are added clockwise so that overlaps do not cancel out.
+func (r *PNGRenderer) segment(p, q [2]float64, half, ox, oy float64) {
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return
+	}
+	nx, ny := -dy/l*half, dx/l*half
+	r.ras.MoveTo(float32(p[0]+nx-ox), float32(p[1]+ny-oy))
+	r.ras.LineTo(float32(q[0]+nx-ox), float32(q[1]+ny-oy))
+	r.ras.LineTo(float32(q[0]-nx-ox), float32(q[1]-ny-oy))
+	r.ras.LineTo(float32(p[0]-nx-ox), float32(p[1]-ny-oy))
+	r.ras.ClosePath()
+}
+
+// dot adds a disc of radius half centred on p to the rasterizer
+func (r *PNGRenderer) dot(p [2]float64, half, ox, oy float64) {
+	n := segments(half, 2*math.Pi)
+	r.ras.MoveTo(float32(p[0]+half-ox), float32(p[1]-oy))
+	for i := 1; i < n; i++ {
+		a := -2 * math.Pi * float64(i) / float64(n)
+		r.ras.LineTo(float32(p[0]+half*math.Cos(a)-ox), float32(p[1]+half*math.Sin(a)-oy))
+	}
+	r.ras.ClosePath()
+}
+
+// Patterns shorter than this many pixels, or repeating into more runs than
+// maxDashRuns on a polyline, are drawn solid
+const (
+	minDashPattern = 1
+	maxDashRuns    = 1 << 20
+)
+
+// dashes splits a polyline into the runs drawn by the dash pattern,
+// given as alternating dash and gap lengths. It reports false if the
+// polyline is to be drawn solid instead.
+func dashes(pts [][2]float64, pattern []float64) ([][][2]float64, bool) {
+	total := 0.0
+	for _, v := range pattern {
+		total += v
+	}
+	length := 0.0
+	for k := 1; k < len(pts); k++ {
+		length += math.Hypot(pts[k][0]-pts[k-1][0], pts[k][1]-pts[k-1][1])
+	}
+	if !(total >= minDashPattern) || !(length/total*float64(len(pattern)) <= maxDashRuns) {
+		return nil, false
+	}
+
+	var runs [][][2]float64
+	run := [][2]float64{pts[0]}
+	i, left := 0, pattern[0]
+	on := true
+	for k := 1; k < len(pts); k++ {
+		p, q := pts[k-1], pts[k]
+		l := math.Hypot(q[0]-p[0], q[1]-p[1])
+		pos := 0.0
+		for l-pos > left {
+			pos += left
+			t := pos / l
+			m := [2]float64{p[0] + (q[0]-p[0])*t, p[1] + (q[1]-p[1])*t}
+			if on {
+				runs = append(runs, append(run, m))
+				run = nil
+			} else {
+				run = [][2]float64{m}
+			}
+			on = !on
+			i = (i + 1) % len(pattern)
+			left = pattern[i]
+		}
+		left -= l - pos
+		if on {
+			run = append(run, q)
+		}
+	}
+	if on && len(run) > 1 {
+		runs = append(runs, run)
+	}
+	return runs, true
+}
+
+var (
+	fontOnce sync.Once
+	textFont *opentype.Font
+	fontErr  error
+)
+
+// face returns the text face of the given pixel size
+func (r *PNGRenderer) face(size float64) (font.Face, error) {
+	if f, ok := r.faces[size]; ok {
+		return f, nil
+	}
+	fontOnce.Do(func() {
+		textFont, fontErr = opentype.Parse(goregular.TTF)
+	})
+	if fontErr != nil {
+		return nil, fontErr
+	}
+	// At 72 DPI the size in points equals the size in pixels
+	f, err := opentype.NewFace(textFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, err
+	}
+	if r.faces == nil {
+		r.faces = make(map[float64]font.Face)
+	}
+	r.faces[size] = f
+	return f, nil
+}
@@ -0,0 +1,152 @@
+package renderers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// renderPNG draws on a 10 x 10 mm page at 2 pixels per millimeter and decodes the result
+func renderPNG(t *testing.T, setup func(r *PNGRenderer), draw func(r *PNGRenderer)) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	r := NewPNGRenderer(&buf, 10, 10)
+	r.DPI = 50.8
+	if setup != nil {
+		setup(r)
+	}
+	r.Init(10, 10)
+	draw(r)
+	if err := r.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	return img
+}
+
+func gray(img image.Image, x, y int) uint8 {
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
+
+func TestPNGRenderer_Size(t *testing.T) {
+	img := renderPNG(t, nil, func(r *PNGRenderer) {})
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 20 {
+		t.Errorf("size = %dx%d, want 20x20", b.Dx(), b.Dy())
+	}
+	if g := gray(img, 10, 10); g != 0xff {
+		t.Errorf("background = %d, want white", g)
+	}
+}
+
+func TestPNGRenderer_TooLarge(t *testing.T) {
+	for _, dpi := range []float64{1e9, math.Inf(1), math.NaN()} {
+		var buf bytes.Buffer
+		r := NewPNGRenderer(&buf, 10, 10)
+		r.DPI = dpi
+		r.Init(10, 10)
+		r.Line(1, 1, 9, 9)
+		r.Text(1, 1, 2, "text")
+		if err := r.Finish(); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("DPI %v: Finish() error = %v, want %v", dpi, err, ErrImageTooLarge)
+		}
+	}
+
+	var buf bytes.Buffer
+	r := NewPNGRenderer(&buf, 10, 10)
+	r.DPI = 50.8
+	r.MaxPixels = 399
+	r.Init(10, 10)
+	if err := r.Finish(); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("20 x 20 pixels with MaxPixels 399: Finish() error = %v, want %v", err, ErrImageTooLarge)
+	}
+}
+
+func TestPNGRenderer_Line(t *testing.T) {
+	img := renderPNG(t, nil, func(r *PNGRenderer) {
+		r.SetStyle(Style{Color: color.RGBA{A: 0xff}, LineWidth: 1})
+		r.Line(1, 5, 9, 5)
+	})
+	// The line covers pixel rows 9 and 10
+	if g := gray(img, 10, 9); g != 0 {
+		t.Errorf("pixel on line = %d, want black", g)
+	}
+	if g := gray(img, 10, 3); g != 0xff {
+		t.Errorf("pixel off line = %d, want white", g)
+	}
+}
+
+func TestPNGRenderer_Antialias(t *testing.T) {
+	diagonal := func(r *PNGRenderer) {
+		r.Line(1, 1, 9, 7)
+	}
+	shades := func(img image.Image) int {
+		n := 0
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				if g := gray(img, x, y); g != 0 && g != 0xff {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	if n := shades(renderPNG(t, nil, diagonal)); n == 0 {
+		t.Error("anti-aliased line has no intermediate shades")
+	}
+	aliased := renderPNG(t, func(r *PNGRenderer) { r.Antialias = false }, diagonal)
+	if n := shades(aliased); n != 0 {
+		t.Errorf("aliased line has %d intermediate shades", n)
+	}
+}
+
+func TestPNGRenderer_Background(t *testing.T) {
+	img := renderPNG(t, func(r *PNGRenderer) { r.Background = nil }, func(r *PNGRenderer) {})
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("alpha = %d, want transparent", a)
+	}
+}
+
+func TestPNGRenderer_Clip(t *testing.T) {
+	img := renderPNG(t, nil, func(r *PNGRenderer) {
+		r.Clip(0, 0, 5, 10)
+		r.SetStyle(Style{Color: color.RGBA{A: 0xff}, LineWidth: 1})
+		r.Line(1, 5, 9, 5)
+	})
+	if g := gray(img, 6, 9); g != 0 {
+		t.Errorf("pixel inside clip = %d, want black", g)
+	}
+	if g := gray(img, 14, 9); g != 0xff {
+		t.Errorf("pixel outside clip = %d, want white", g)
+	}
+}
+
+func TestDashes(t *testing.T) {
+	pts := [][2]float64{{0, 0}, {10, 0}}
+	runs, ok := dashes(pts, []float64{3, 1})
+	// Dashes 0-3, 4-7 and 8-10
+	if !ok || len(runs) != 3 {
+		t.Fatalf("got %d dashes, want 3: %v", len(runs), runs)
+	}
+	if runs[1][0] != [2]float64{4, 0} || runs[1][1] != [2]float64{7, 0} {
+		t.Errorf("second dash = %v, want [[4 0] [7 0]]", runs[1])
+	}
+
+	// Patterns shorter than a pixel, or repeating too often, are drawn solid
+	for _, pattern := range [][]float64{{0.5, 0.25}, {1e-9, 1e-9}, {0, 0}} {
+		if runs, ok := dashes(pts, pattern); ok {
+			t.Errorf("dashes(%v) = %d runs, want solid", pattern, len(runs))
+		}
+	}
+	long := [][2]float64{{0, 0}, {1e9, 0}}
+	if runs, ok := dashes(long, []float64{3, 1}); ok {
+		t.Errorf("dashes() on a long line = %d runs, want solid", len(runs))
+	}
+}
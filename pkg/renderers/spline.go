@@ -0,0 +1,124 @@
+package renderers
+
+import "github.com/daidai-ok/dxfconv/pkg/dxf"
+
+// Number of chords per knot span for splines of degree above three, which
+// cannot be drawn as cubic Bezier curves
+const splineSamples = 16
+
+// splinePath adds the spline with the control points pts, already in page
+// coordinates, to p. Splines of up to degree three become Bezier curves, one
+// per knot span. Without a valid knot vector the control polygon is drawn.
+func splinePath(p *Path, s *dxf.Spline, pts [][2]float64) {
+	n, deg, u := len(pts), s.Degree, s.Knots
+	if !knotsFit(n, deg, u) {
+		p.MoveTo(pts[0][0], pts[0][1])
+		for _, q := range pts[1:] {
+			p.LineTo(q[0], q[1])
+		}
+		if s.Closed {
+			p.Close()
+		}
+		return
+	}
+
+	started := false
+	for k := deg; k < n; k++ {
+		if u[k+1] <= u[k] {
+			continue
+		}
+		a, b := u[k], u[k+1]
+		if !started {
+			q := blossom(pts, u, deg, k, repeat(a, deg)...)
+			p.MoveTo(q[0], q[1])
+			started = true
+		}
+		switch deg {
+		case 1:
+			q := blossom(pts, u, deg, k, b)
+			p.LineTo(q[0], q[1])
+		case 2:
+			// Elevate the quadratic Bezier curve to a cubic one
+			q0, q1, q2 := blossom(pts, u, deg, k, a, a), blossom(pts, u, deg, k, a, b), blossom(pts, u, deg, k, b, b)
+			p.CurveTo(
+				q0[0]+2*(q1[0]-q0[0])/3, q0[1]+2*(q1[1]-q0[1])/3,
+				q2[0]+2*(q1[0]-q2[0])/3, q2[1]+2*(q1[1]-q2[1])/3,
+				q2[0], q2[1])
+		case 3:
+			c1, c2, c3 := blossom(pts, u, deg, k, a, a, b), blossom(pts, u, deg, k, a, b, b), blossom(pts, u, deg, k, b, b, b)
+			p.CurveTo(c1[0], c1[1], c2[0], c2[1], c3[0], c3[1])
+		default:
+			for i := 1; i <= splineSamples; i++ {
+				t := a + (b-a)*float64(i)/splineSamples
+				q := blossom(pts, u, deg, k, repeat(t, deg)...)
+				p.LineTo(q[0], q[1])
+			}
+		}
+	}
+	if !started {
+		return
+	}
+	if s.Closed {
+		p.Close()
+	}
+}
+
+// ValidKnots reports whether the knot vector of s fits its degree and number
+// of control points, so that the spline can be evaluated
+func ValidKnots(s *dxf.Spline) bool {
+	return knotsFit(len(s.ControlPoints), s.Degree, s.Knots)
+}
+
+// knotsFit reports whether the knots u fit n control points of degree deg
+func knotsFit(n, deg int, u []float64) bool {
+	return deg >= 1 && n > deg && len(u) == n+deg+1
+}
+
+// SplinePoint returns the point at t of the B-spline of degree deg with the
+// control points pts and the knots u, which ValidKnots accepts. The end of
+// the domain belongs to the last knot span.
+func SplinePoint(pts [][2]float64, u []float64, deg int, t float64) [2]float64 {
+	k := deg
+	for k < len(pts)-1 && t >= u[k+1] {
+		k++
+	}
+	return blossom(pts, u, deg, k, repeat(t, deg)...)
+}
+
+// blossom evaluates the polar form of the spline of degree deg on the knot
+// span k at ts, which holds deg parameters. With all of them equal to t this
+// is the point at t; mixing the span ends gives its Bezier control points.
+func blossom(pts [][2]float64, u []float64, deg, k int, ts ...float64) [2]float64 {
+	var d [4][2]float64
+	var buf [][2]float64
+	if deg < len(d) {
+		buf = d[:deg+1]
+	} else {
+		buf = make([][2]float64, deg+1)
+	}
+	copy(buf, pts[k-deg:k+1])
+	for r := 1; r <= deg; r++ {
+		t := ts[r-1]
+		for j := deg; j >= r; j-- {
+			i := k - deg + j
+			alpha := 0.0
+			if den := u[i+deg-r+1] - u[i]; den != 0 {
+				alpha = (t - u[i]) / den
+			}
+			buf[j] = [2]float64{
+				(1-alpha)*buf[j-1][0] + alpha*buf[j][0],
+				(1-alpha)*buf[j-1][1] + alpha*buf[j][1],
+			}
+		}
+	}
+	return buf[deg]
+}
+
+// repeat returns n copies of t
+func repeat(t float64, n int) []float64 {
+	ts := make([]float64, n)
+	for i := range ts {
+		ts[i] = t
+	}
+	return ts
+}
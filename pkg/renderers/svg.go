@@ -1,80 +1,332 @@
 package renderers
 
 import (
+	"bytes"
 	"fmt"
+	"image/color"
 	"io"
 	"math"
-
-	svg "github.com/ajstarks/svgo"
+	"strings"
 )
 
+// DefaultSVGPrecision is the number of decimals used for SVG coordinates
+const DefaultSVGPrecision = 3
+
+// Line width used when the style has none, one point as in PDF
+const svgDefaultLineWidth = 25.4 / 72
+
 // SVGRenderer implements the Renderer interface for SVG output
 type SVGRenderer struct {
-	canvas *svg.SVG
-	width  float64
-	height float64
+	w       *svgWriter
+	writer  io.Writer
+	width   float64
+	height  float64
+	clipped int // number of open clip groups
+	style   Style
+
+	// Precision is the number of decimals of coordinates and lengths, zero
+	// for integers. Negative values select DefaultSVGPrecision. Stroke
+	// widths and dashes keep at least DefaultSVGPrecision decimals.
+	Precision int
+	// Minify omits indentation and line breaks.
+	Minify bool
+	// UseClasses replaces inline styles by CSS classes defined in an embedded
+	// stylesheet, so that an external stylesheet can restyle the drawing.
+	UseClasses bool
+	classes    map[string]string // style -> class name
+	styles     []string          // styles in class order
+
+	// Entities drawn between BeginEntity and EndEntity are collected per layer
+	// and written as one group per layer by Finish.
+	layers  []*svgLayer
+	byName  map[string]*svgLayer // by lowercase name
+	current *svgLayer
+	entity  EntityInfo
+}
+
+// svgLayer buffers the elements of one DXF layer
+type svgLayer struct {
+	name    string
+	id      string
+	visible bool
+	buf     bytes.Buffer
+	w       *svgWriter
 }
 
 // NewSVGRenderer creates a new SVGRenderer
 func NewSVGRenderer(w io.Writer, width, height float64) *SVGRenderer {
-	canvas := svg.New(w)
-	return &SVGRenderer{canvas: canvas, width: width, height: height}
+	return &SVGRenderer{writer: w, width: width, height: height, Precision: DefaultSVGPrecision}
+}
+
+func (r *SVGRenderer) newWriter(w io.Writer) *svgWriter {
+	precision := r.Precision
+	if precision < 0 {
+		precision = DefaultSVGPrecision
+	}
+	return newSVGWriter(w, precision, r.Minify)
 }
 
 func (r *SVGRenderer) Init(width, height float64) {
-	r.canvas.Start(int(width), int(height))
-	r.canvas.Rect(0, 0, int(width), int(height), "fill:none;stroke:none") // Optional background
+	r.w = r.newWriter(r.writer)
+	r.w.write(`<?xml version="1.0" encoding="UTF-8"?>`)
+	r.w.newline()
+	// Size the document in millimeters so that plots keep their true scale
+	w, h := r.w.num(width), r.w.num(height)
+	r.w.start("svg",
+		attr{"xmlns", "http://www.w3.org/2000/svg"},
+		attr{"width", w + "mm"},
+		attr{"height", h + "mm"},
+		attr{"viewBox", "0 0 " + w + " " + h},
+	)
+}
+
+func (r *SVGRenderer) Clip(x, y, width, height float64) {
+	id := fmt.Sprintf("clip%d", r.clipped+1)
+	r.w.start("defs")
+	r.w.start("clipPath", attr{"id", id})
+	r.w.empty("rect",
+		attr{"x", r.w.num(x)},
+		attr{"y", r.w.num(y)},
+		attr{"width", r.w.num(width)},
+		attr{"height", r.w.num(height)},
+	)
+	r.w.end("clipPath")
+	r.w.end("defs")
+	r.w.start("g", attr{"clip-path", "url(#" + id + ")"})
+	r.clipped++
+}
+
+func (r *SVGRenderer) SetStyle(style Style) {
+	r.style = style
+}
+
+// BeginEntity directs the following elements to the group of the entity's layer
+// and tags them with the entity type, handle and layer.
+func (r *SVGRenderer) BeginEntity(info EntityInfo) {
+	if r.byName == nil {
+		r.byName = make(map[string]*svgLayer)
+	}
+	key := strings.ToLower(info.Layer)
+	l, ok := r.byName[key]
+	if !ok {
+		l = &svgLayer{name: info.Layer, id: r.layerID(info.Layer), visible: info.LayerVisible}
+		l.w = r.newWriter(&l.buf)
+		l.w.depth = r.w.depth + 1
+		r.byName[key] = l
+		r.layers = append(r.layers, l)
+	}
+	r.current = l
+	r.entity = info
+}
+
+func (r *SVGRenderer) EndEntity() {
+	r.current = nil
+	r.entity = EntityInfo{}
+}
+
+// layerID returns a unique XML id for the layer group
+func (r *SVGRenderer) layerID(name string) string {
+	id := "layer-" + sanitizeID(name)
+	unique := id
+	for i := 2; r.idTaken(unique); i++ {
+		unique = fmt.Sprintf("%s-%d", id, i)
+	}
+	return unique
+}
+
+func (r *SVGRenderer) idTaken(id string) bool {
+	for _, l := range r.layers {
+		if l.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeID replaces the characters that are not allowed in XML ids
+func sanitizeID(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+			b.WriteRune(c)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// out returns the writer the next element is written to
+func (r *SVGRenderer) out() *svgWriter {
+	if r.current != nil {
+		return r.current.w
+	}
+	return r.w
+}
+
+// attrs returns the geometry attributes followed by the style and metadata attributes
+func (r *SVGRenderer) attrs(style string, geometry ...attr) []attr {
+	a := geometry
+	if r.UseClasses {
+		a = append(a, attr{"class", r.class(style)})
+	} else {
+		a = append(a, attr{"style", style})
+	}
+	if r.current != nil {
+		a = append(a,
+			attr{"data-type", r.entity.Type},
+			attr{"data-layer", r.entity.Layer},
+		)
+		if r.entity.Handle != "" {
+			a = append(a, attr{"data-handle", r.entity.Handle})
+		}
+	}
+	return a
+}
+
+// class returns the CSS class for an inline style, registering it on first use
+func (r *SVGRenderer) class(style string) string {
+	if r.classes == nil {
+		r.classes = make(map[string]string)
+	}
+	name, ok := r.classes[style]
+	if !ok {
+		name = fmt.Sprintf("s%d", len(r.styles))
+		r.classes[style] = name
+		r.styles = append(r.styles, style)
+	}
+	return name
+}
+
+// strokeStyle returns the inline style for stroked elements
+func (r *SVGRenderer) strokeStyle() string {
+	width := r.style.LineWidth
+	if width == 0 {
+		width = svgDefaultLineWidth
+	}
+	// Pens are far thinner than the drawing, so low precisions would lose them
+	precision := max(r.w.precision, DefaultSVGPrecision)
+	style := fmt.Sprintf("fill:none;stroke:%s;stroke-width:%s", hexColor(r.style.Color), FormatNumber(width, precision))
+	if len(r.style.Dash) > 0 {
+		dash := make([]string, len(r.style.Dash))
+		for i, v := range r.style.Dash {
+			dash[i] = FormatNumber(v, precision)
+		}
+		style += ";stroke-dasharray:" + strings.Join(dash, ",")
+	}
+	return style
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
 }
 
 func (r *SVGRenderer) Line(x1, y1, x2, y2 float64) {
-	r.canvas.Line(int(x1), int(y1), int(x2), int(y2), "stroke:black;stroke-width:1")
+	w := r.out()
+	w.empty("line", r.attrs(r.strokeStyle(),
+		attr{"x1", w.num(x1)},
+		attr{"y1", w.num(y1)},
+		attr{"x2", w.num(x2)},
+		attr{"y2", w.num(y2)},
+	)...)
 }
 
 func (r *SVGRenderer) Circle(x, y, radius float64) {
-	r.canvas.Circle(int(x), int(y), int(radius), "fill:none;stroke:black;stroke-width:1")
+	w := r.out()
+	w.empty("circle", r.attrs(r.strokeStyle(),
+		attr{"cx", w.num(x)},
+		attr{"cy", w.num(y)},
+		attr{"r", w.num(radius)},
+	)...)
 }
 
+// Arc draws an arc counter-clockwise from startAngle to endAngle (degrees) as seen on the page
 func (r *SVGRenderer) Arc(x, y, radius, startAngle, endAngle float64) {
 	startRad := startAngle * math.Pi / 180
 	endRad := endAngle * math.Pi / 180
 
-	sx := int(x + radius*math.Cos(startRad))
-	sy := int(y + radius*math.Sin(startRad))
-	ex := int(x + radius*math.Cos(endRad))
-	ey := int(y + radius*math.Sin(endRad))
+	// Page Y grows downwards, so counter-clockwise angles subtract from Y
+	sx := x + radius*math.Cos(startRad)
+	sy := y - radius*math.Sin(startRad)
+	ex := x + radius*math.Cos(endRad)
+	ey := y - radius*math.Sin(endRad)
 
-	large := false
-	diff := endAngle - startAngle
+	large := "0"
+	diff := math.Mod(endAngle-startAngle, 360)
 	if diff < 0 {
 		diff += 360
 	}
 	if diff > 180 {
-		large = true
+		large = "1"
 	}
 
-	r.canvas.Arc(sx, sy, int(radius), int(radius), 0, large, true, ex, ey, "fill:none;stroke:black")
+	w := r.out()
+	rad := w.num(radius)
+	// Sweep flag 0 draws counter-clockwise on screen
+	d := "M" + w.num(sx) + " " + w.num(sy) +
+		" A" + rad + " " + rad + " 0 " + large + " 0 " + w.num(ex) + " " + w.num(ey)
+	w.empty("path", r.attrs(r.strokeStyle(), attr{"d", d})...)
 }
 
 func (r *SVGRenderer) Polyline(points [][]float64, closed bool) {
-	x := make([]int, len(points))
-	y := make([]int, len(points))
+	if len(points) == 0 {
+		return
+	}
+	w := r.out()
+	var d strings.Builder
 	for i, p := range points {
-		x[i] = int(p[0])
-		y[i] = int(p[1])
+		switch i {
+		case 0:
+			d.WriteString("M")
+		case 1:
+			d.WriteString(" L")
+		default:
+			d.WriteString(" ")
+		}
+		d.WriteString(w.num(p[0]) + " " + w.num(p[1]))
 	}
-	style := "fill:none;stroke:black;stroke-width:1"
 	if closed {
-		r.canvas.Polygon(x, y, style)
-	} else {
-		r.canvas.Polyline(x, y, style)
+		d.WriteString(" Z")
 	}
+	w.empty("path", r.attrs(r.strokeStyle(), attr{"d", d.String()})...)
 }
 
 func (r *SVGRenderer) Text(x, y, height float64, text string) {
-	r.canvas.Text(int(x), int(y), text, "font-size:"+fmt.Sprintf("%d", int(height)))
+	w := r.out()
+	style := fmt.Sprintf("fill:%s;font-family:Helvetica,Arial,sans-serif;font-size:%spx", hexColor(r.style.Color), w.num(height))
+	w.text("text", text, r.attrs(style,
+		attr{"x", w.num(x)},
+		attr{"y", w.num(y)},
+	)...)
 }
 
 func (r *SVGRenderer) Finish() error {
-	r.canvas.End()
-	return nil
+	if len(r.styles) > 0 {
+		rules := make([]string, len(r.styles))
+		for i, style := range r.styles {
+			rules[i] = fmt.Sprintf(".s%d { %s }", i, style)
+		}
+		r.w.text("style", strings.Join(rules, " "), attr{"type", "text/css"})
+	}
+	for _, l := range r.layers {
+		attrs := []attr{
+			{"id", l.id},
+			{"data-layer", l.name},
+		}
+		if !l.visible {
+			attrs = append(attrs, attr{"style", "display:none"})
+		}
+		r.w.start("g", attrs...)
+		r.w.raw(l.buf.Bytes())
+		if l.w.err != nil {
+			return l.w.err
+		}
+		r.w.end("g")
+	}
+	for ; r.clipped > 0; r.clipped-- {
+		r.w.end("g")
+	}
+	r.w.end("svg")
+	return r.w.err
 }
@@ -0,0 +1,163 @@
+package renderers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSVGWriter_Num(t *testing.T) {
+	tests := []struct {
+		precision int
+		v         float64
+		want      string
+	}{
+		{3, 1, "1"},
+		{3, 1.5, "1.5"},
+		{3, 1.23456, "1.235"},
+		{3, -0.0001, "0"},
+		{3, 100, "100"},
+		{1, 12.34, "12.3"},
+	}
+	for _, tt := range tests {
+		sw := newSVGWriter(nil, tt.precision, false)
+		if got := sw.num(tt.v); got != tt.want {
+			t.Errorf("num(%v) with precision %d = %q, want %q", tt.v, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestSVGRenderer_Document(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewSVGRenderer(&buf, 210, 297)
+	r.Init(210, 297)
+	r.Line(10.25, 20.5, 30.125, 40.0626)
+	r.Text(5, 5, 2.5, `<A & "B">`)
+	if err := r.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	out := buf.String()
+
+	wants := []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="210mm" height="297mm" viewBox="0 0 210 297">`,
+		`<line x1="10.25" y1="20.5" x2="30.125" y2="40.063"`,
+		// One point wide by default
+		`stroke-width:0.353"`,
+		`>&lt;A &amp; "B"&gt;</text>`,
+		"</svg>\n",
+	}
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSVGRenderer_Precision(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewSVGRenderer(&buf, 100, 100)
+	r.Precision = 1
+	r.Init(100, 100)
+	r.Circle(1.26, 2, 0.04)
+	r.Finish()
+
+	if want := `<circle cx="1.3" cy="2" r="0"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+
+	for _, tt := range []struct {
+		precision int
+		want      string
+	}{
+		{0, `<circle cx="1" cy="3" r="1" style="fill:none;stroke:#000000;stroke-width:0.353"`},
+		{-1, `<circle cx="1.26" cy="2.6" r="0.6"`},
+	} {
+		buf.Reset()
+		r := NewSVGRenderer(&buf, 100, 100)
+		r.Precision = tt.precision
+		r.Init(100, 100)
+		r.Circle(1.26, 2.6, 0.6)
+		r.Finish()
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("precision %d: output missing %q:\n%s", tt.precision, tt.want, buf.String())
+		}
+	}
+}
+
+func TestSVGRenderer_Minify(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewSVGRenderer(&buf, 100, 100)
+	r.Minify = true
+	r.Init(100, 100)
+	r.BeginEntity(EntityInfo{Type: "LINE", Layer: "0", LayerVisible: true})
+	r.Line(0, 0, 10, 10)
+	r.EndEntity()
+	r.Finish()
+
+	out := buf.String()
+	if strings.Contains(out, "\n") || strings.Contains(out, "  ") {
+		t.Errorf("minified output contains whitespace:\n%s", out)
+	}
+	if !strings.Contains(out, `<g id="layer-0" data-layer="0"><line `) {
+		t.Errorf("unexpected minified output:\n%s", out)
+	}
+}
+
+func TestSVGRenderer_LayerCase(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewSVGRenderer(&buf, 100, 100)
+	r.Init(100, 100)
+	for _, layer := range []string{"Walls", "DIM", "WALLS"} {
+		r.BeginEntity(EntityInfo{Type: "LINE", Layer: layer, LayerVisible: true})
+		r.Line(0, 0, 10, 10)
+		r.EndEntity()
+	}
+	r.Finish()
+
+	out := buf.String()
+	if n := strings.Count(out, "<g id="); n != 2 {
+		t.Errorf("got %d layer groups, want 2:\n%s", n, out)
+	}
+	if !strings.Contains(out, `<g id="layer-Walls" data-layer="Walls">`) {
+		t.Errorf("group should be named after the first spelling:\n%s", out)
+	}
+}
+
+func TestSVGRenderer_ArcPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end float64
+		want       string
+	}{
+		// Quarter arc from 3 o'clock to 12 o'clock: up on the page
+		{"quarter", 0, 90, `d="M60 50 A10 10 0 0 0 50 40"`},
+		// Three quarters from 12 o'clock through 9 and 6 to 3 o'clock
+		{"large", 90, 360, `d="M50 40 A10 10 0 1 0 60 50"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := NewSVGRenderer(&buf, 100, 100)
+			r.Init(100, 100)
+			r.Arc(50, 50, 10, tt.start, tt.end)
+			r.Finish()
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output missing %q:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestSVGRenderer_PolylinePath(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewSVGRenderer(&buf, 100, 100)
+	r.Init(100, 100)
+	r.Polyline([][]float64{{0, 0}, {10, 0}, {10, 10}}, true)
+	r.Finish()
+
+	if want := `d="M0 0 L10 0 10 10 Z"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package renderers
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// attr is an XML attribute
+type attr struct {
+	name  string
+	value string
+}
+
+// svgWriter writes indented or minified SVG markup.
+// The first write error is kept in err and all later writes are skipped.
+type svgWriter struct {
+	w         io.Writer
+	precision int
+	minify    bool
+	depth     int
+	err       error
+}
+
+func newSVGWriter(w io.Writer, precision int, minify bool) *svgWriter {
+	return &svgWriter{w: w, precision: precision, minify: minify}
+}
+
+var (
+	textEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;")
+	attrEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;", "\n", "&#10;", "\t", "&#9;")
+)
+
+func (sw *svgWriter) write(s string) {
+	if sw.err != nil {
+		return
+	}
+	_, sw.err = io.WriteString(sw.w, s)
+}
+
+// num formats a number with the configured precision, without trailing zeros
+func (sw *svgWriter) num(v float64) string {
+	return FormatNumber(v, sw.precision)
+}
+
+// FormatNumber formats v with at most precision decimals, without trailing
+// zeros, for text output formats
+func FormatNumber(v float64, precision int) string {
+	s := strconv.FormatFloat(v, 'f', precision, 64)
+	if strings.IndexByte(s, '.') >= 0 {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// indent starts a new line at the current depth
+func (sw *svgWriter) indent() {
+	if sw.minify {
+		return
+	}
+	sw.write(strings.Repeat("  ", sw.depth))
+}
+
+func (sw *svgWriter) newline() {
+	if !sw.minify {
+		sw.write("\n")
+	}
+}
+
+func (sw *svgWriter) tag(name string, attrs []attr) {
+	sw.write("<" + name)
+	for _, a := range attrs {
+		sw.write(" " + a.name + `="` + attrEscaper.Replace(a.value) + `"`)
+	}
+}
+
+// start opens an element that has children
+func (sw *svgWriter) start(name string, attrs ...attr) {
+	sw.indent()
+	sw.tag(name, attrs)
+	sw.write(">")
+	sw.newline()
+	sw.depth++
+}
+
+// end closes the element opened by start
+func (sw *svgWriter) end(name string) {
+	sw.depth--
+	sw.indent()
+	sw.write("</" + name + ">")
+	sw.newline()
+}
+
+// empty writes an element without children
+func (sw *svgWriter) empty(name string, attrs ...attr) {
+	sw.indent()
+	sw.tag(name, attrs)
+	sw.write("/>")
+	sw.newline()
+}
+
+// text writes an element containing escaped character data
+func (sw *svgWriter) text(name, content string, attrs ...attr) {
+	sw.indent()
+	sw.tag(name, attrs)
+	sw.write(">" + textEscaper.Replace(content) + "</" + name + ">")
+	sw.newline()
+}
+
+// raw writes already formatted markup, such as a buffered group
+func (sw *svgWriter) raw(s []byte) {
+	if sw.err != nil {
+		return
+	}
+	_, sw.err = sw.w.Write(s)
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxf"
+)
+
+// Params are the conversion options of a request. They are given as query
+// parameters of the same names or, for multipart uploads, as a JSON object
+// in the "options" field. Query parameters take precedence.
+type Params struct {
+	// Format is pdf, svg, png, eps or hpgl
+	Format string `json:"format,omitempty"`
+	// Page is a paper name such as "A3" or WIDTHxHEIGHT in mm
+	Page string `json:"page,omitempty"`
+	// Orientation is portrait or landscape
+	Orientation string   `json:"orientation,omitempty"`
+	Scale       *float64 `json:"scale,omitempty"`
+	PlotScale   string   `json:"plotScale,omitempty"`
+	// Units overrides $INSUNITS for PlotScale, e.g. "mm" or "in"
+	Units         string   `json:"units,omitempty"`
+	Margin        *float64 `json:"margin,omitempty"`
+	View          string   `json:"view,omitempty"`
+	Layers        []string `json:"layers,omitempty"`
+	ExcludeLayers []string `json:"excludeLayers,omitempty"`
+	ShowHidden    *bool    `json:"showHidden,omitempty"`
+	DPI           *float64 `json:"dpi,omitempty"`
+	// Background is the PNG background colour, e.g. "#ffffff" or "transparent"
+	Background string `json:"background,omitempty"`
+	// Lenient skips malformed entities instead of failing
+	Lenient *bool `json:"lenient,omitempty"`
+	// Compress compresses PDF output
+	Compress *bool `json:"compress,omitempty"`
+	// PDFA writes PDF/A-2b archival output
+	PDFA *bool `json:"pdfa,omitempty"`
+	// Title and Author are the PDF document information. The title defaults
+	// to the header or the uploaded file name.
+	Title  string `json:"title,omitempty"`
+	Author string `json:"author,omitempty"`
+}
+
+// Bounds of the numeric parameters, beyond which a request only serves to
+// exhaust the server
+const (
+	// maxPageSide is the largest page width or height in mm: 200 inches,
+	// the limit of PDF pages
+	maxPageSide = 5080
+	maxScale    = 1e6
+	maxDPI      = 2400
+)
+
+// paramError is a malformed request parameter
+type paramError struct {
+	param string
+	err   error
+}
+
+func (e *paramError) Error() string {
+	return fmt.Sprintf("invalid parameter %s: %v", e.param, e.err)
+}
+
+func (e *paramError) Unwrap() error {
+	return e.err
+}
+
+// parseJSONParams decodes the JSON form of the parameters
+func parseJSONParams(s string) (*Params, error) {
+	p := &Params{}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return nil, &paramError{"options", err}
+	}
+	return p, nil
+}
+
+// merge overrides p with the query parameters
+func (p *Params) merge(q url.Values) error {
+	floatParam := func(name string, dst **float64) error {
+		if !q.Has(name) {
+			return nil
+		}
+		v, err := strconv.ParseFloat(q.Get(name), 64)
+		if err != nil {
+			return &paramError{name, err}
+		}
+		*dst = &v
+		return nil
+	}
+	for name, dst := range map[string]*string{
+		"format":      &p.Format,
+		"page":        &p.Page,
+		"orientation": &p.Orientation,
+		"plotScale":   &p.PlotScale,
+		"units":       &p.Units,
+		"view":        &p.View,
+		"background":  &p.Background,
+		"title":       &p.Title,
+		"author":      &p.Author,
+	} {
+		if q.Has(name) {
+			*dst = q.Get(name)
+		}
+	}
+	for name, dst := range map[string]**float64{"scale": &p.Scale, "margin": &p.Margin, "dpi": &p.DPI} {
+		if err := floatParam(name, dst); err != nil {
+			return err
+		}
+	}
+	for name, dst := range map[string]**bool{"showHidden": &p.ShowHidden, "lenient": &p.Lenient, "compress": &p.Compress, "pdfa": &p.PDFA} {
+		if !q.Has(name) {
+			continue
+		}
+		v, err := strconv.ParseBool(q.Get(name))
+		if err != nil {
+			return &paramError{name, err}
+		}
+		*dst = &v
+	}
+	// Layer lists may be repeated or comma-separated
+	if q.Has("layers") {
+		p.Layers = splitList(q["layers"])
+	}
+	if q.Has("excludeLayers") {
+		p.ExcludeLayers = splitList(q["excludeLayers"])
+	}
+	return nil
+}
+
+func splitList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// options applies the parameters to a copy of defaults
+func (p *Params) options(defaults *converter.Options) (*converter.Options, error) {
+	opts := *defaults
+	var err error
+	if p.Format != "" {
+		if opts.Format, err = converter.ParseFormat(p.Format); err != nil {
+			return nil, &paramError{"format", err}
+		}
+	}
+	if p.Page != "" {
+		if opts.PageSize, err = converter.ParsePageSize(p.Page); err != nil {
+			return nil, &paramError{"page", err}
+		}
+		if opts.PageSize.Width > maxPageSide || opts.PageSize.Height > maxPageSide {
+			return nil, &paramError{"page", fmt.Errorf("sides must not exceed %d mm", maxPageSide)}
+		}
+	}
+	if p.Orientation != "" {
+		if opts.Orientation, err = converter.ParseOrientation(p.Orientation); err != nil {
+			return nil, &paramError{"orientation", err}
+		}
+	}
+	if p.Scale != nil {
+		// Zero fits the drawing to the page
+		if !(*p.Scale >= 0 && *p.Scale <= maxScale) {
+			return nil, &paramError{"scale", fmt.Errorf("must be between 0 and %g", float64(maxScale))}
+		}
+		opts.Scale = *p.Scale
+	}
+	if p.PlotScale != "" {
+		opts.PlotScale = p.PlotScale
+	}
+	if p.Units != "" {
+		u, ok := dxf.ParseUnits(p.Units)
+		if !ok {
+			return nil, &paramError{"units", fmt.Errorf("unknown units %q", p.Units)}
+		}
+		opts.Units = u
+	}
+	if p.Margin != nil {
+		if !(*p.Margin >= 0 && *p.Margin <= maxPageSide) {
+			return nil, &paramError{"margin", fmt.Errorf("must be between 0 and %d mm", maxPageSide)}
+		}
+		opts.Margin = *p.Margin
+	}
+	if p.View != "" {
+		opts.View = p.View
+	}
+	if p.Layers != nil {
+		opts.Layers = p.Layers
+	}
+	if p.ExcludeLayers != nil {
+		opts.ExcludeLayers = p.ExcludeLayers
+	}
+	if p.ShowHidden != nil {
+		opts.ShowHiddenLayers = *p.ShowHidden
+	}
+	if p.Lenient != nil {
+		opts.Lenient = *p.Lenient
+	}
+	if p.Compress != nil {
+		opts.Compress = *p.Compress
+	}
+	if p.PDFA != nil {
+		opts.PDFA = *p.PDFA
+	}
+	if p.Title != "" {
+		opts.Info.Title = p.Title
+	}
+	if p.Author != "" {
+		opts.Info.Author = p.Author
+	}
+	if p.DPI != nil {
+		if !(*p.DPI > 0 && *p.DPI <= maxDPI) {
+			return nil, &paramError{"dpi", fmt.Errorf("must be positive and at most %d", maxDPI)}
+		}
+		opts.DPI = *p.DPI
+	}
+	if p.Background != "" {
+		if opts.Background, err = converter.ParseColor(p.Background); err != nil {
+			return nil, &paramError{"background", err}
+		}
+	}
+	return &opts, nil
+}
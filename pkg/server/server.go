@@ -0,0 +1,219 @@
+// Package server provides an HTTP handler that converts uploaded DXF drawings.
+//
+// POST /convert accepts the DXF data either as the raw request body or as the
+// "file" field of a multipart form, whose "options" field may hold the
+// conversion Params as JSON. Params can also be given as query parameters.
+// The response holds the converted drawing with the content type of the
+// output format. With lenient parsing, the X-Dxfconv-Warnings header counts
+// the malformed input that was skipped. Errors are reported as JSON objects
+// {"error": "..."} with a status code matching the dxfconverror type. Parse
+// errors add the "line" and an "excerpt" of the offending input.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+	"github.com/daidai-ok/dxfconv/pkg/dxfconverror"
+	"github.com/daidai-ok/dxfconv/pkg/pdf"
+)
+
+// Default limits of a Handler
+const (
+	DefaultMaxUploadSize = 32 << 20
+	DefaultTimeout       = 60 * time.Second
+)
+
+// Config configures a Handler
+type Config struct {
+	// MaxUploadSize is the maximum size of a request body in bytes.
+	// Zero selects DefaultMaxUploadSize.
+	MaxUploadSize int64
+	// Timeout is the maximum duration of a conversion. Zero selects DefaultTimeout.
+	Timeout time.Duration
+	// Defaults are the options that request parameters are applied to.
+	// Nil selects converter.DefaultOptions.
+	Defaults *converter.Options
+}
+
+// Handler serves the conversion endpoint
+type Handler struct {
+	config Config
+	mux    *http.ServeMux
+}
+
+// NewHandler creates a Handler
+func NewHandler(config Config) *Handler {
+	if config.MaxUploadSize <= 0 {
+		config.MaxUploadSize = DefaultMaxUploadSize
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.Defaults == nil {
+		config.Defaults = converter.DefaultOptions()
+	}
+	h := &Handler{config: config, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/convert", h.convert)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// errTimeout is reported when a conversion exceeds Config.Timeout
+var errTimeout = errors.New("conversion timed out")
+
+func (h *Handler) convert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxUploadSize)
+
+	input, name, params, err := readRequest(r)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if err := params.merge(r.URL.Query()); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts, err := params.options(h.config.Defaults)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if opts.Filename == "" && name != "" {
+		opts.Filename = path.Base(name)
+	}
+
+	// The output is buffered so that a failed conversion still gets an error status
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeout)
+	defer cancel()
+	var (
+		out bytes.Buffer
+		res *converter.Result
+	)
+	done := make(chan error, 1)
+	go func() {
+		// A panic would take down the whole server
+		defer func() {
+			if p := recover(); p != nil {
+				done <- &dxfconverror.InternalError{Err: fmt.Errorf("conversion panicked: %v", p)}
+			}
+		}()
+		var err error
+		res, err = converter.ConvertContext(ctx, input, &out, opts)
+		done <- err
+	}()
+	// A conversion blocked on reading the upload only notices the deadline
+	// once the read returns, so the handler does not wait for it
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errTimeout
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errTimeout
+	}
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", opts.Format.MediaType())
+	w.Header().Set("Content-Length", strconv.Itoa(out.Len()))
+	if len(res.Warnings) > 0 {
+		w.Header().Set("X-Dxfconv-Warnings", strconv.Itoa(len(res.Warnings)))
+	}
+	if name != "" {
+		filename := strings.TrimSuffix(path.Base(name), path.Ext(name)) + opts.Format.Extension()
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	}
+	out.WriteTo(w)
+}
+
+// readRequest returns the DXF data, the uploaded file name and the JSON
+// parameters of a request
+func readRequest(r *http.Request) (io.Reader, string, *Params, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, "", &Params{}, nil
+	}
+
+	// Small fields are kept in memory, the file may be spooled to disk
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			return nil, "", nil, err
+		}
+		return nil, "", nil, &paramError{"body", err}
+	}
+	params := &Params{}
+	if s := r.FormValue("options"); s != "" {
+		p, err := parseJSONParams(s)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		params = p
+	}
+	f, fh, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", nil, &paramError{"file", err}
+	}
+	// The file is released with the multipart form when the request ends
+	return f, fh.Filename, params, nil
+}
+
+// statusOf maps an error to an HTTP status code
+func statusOf(err error) int {
+	var (
+		maxBytes  *http.MaxBytesError
+		limitErr  *dxfconverror.LimitError
+		paramErr  *paramError
+		optionErr *dxfconverror.OptionError
+		parseErr  *dxfconverror.ParseError
+		fitErr    *dxfconverror.FitError
+	)
+	switch {
+	case errors.As(err, &maxBytes), errors.As(err, &limitErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errTimeout):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &paramErr), errors.As(err, &optionErr):
+		return http.StatusBadRequest
+	case errors.As(err, &parseErr), errors.As(err, &fitErr), errors.Is(err, pdf.ErrFontNotEmbedded):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	body := map[string]any{"error": err.Error()}
+	if status == http.StatusInternalServerError {
+		// Internal details are not leaked to clients
+		body["error"] = http.StatusText(status)
+	} else if pe, ok := dxfconverror.AsParseError(err); ok && pe.HasPosition() {
+		body["line"] = pe.Line
+		body["excerpt"] = pe.Excerpt
+	}
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daidai-ok/dxfconv/pkg/converter"
+)
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../../fixtures/" + name)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	return data
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func multipartRequest(t *testing.T, target string, data []byte, options string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if options != "" {
+		mw.WriteField("options", options)
+	}
+	fw, err := mw.CreateFormFile("file", "plan.dxf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestConvert_RawBody(t *testing.T) {
+	h := NewHandler(Config{})
+	tests := []struct {
+		query       string
+		contentType string
+		prefix      string
+	}{
+		{"", "application/pdf", "%PDF-"},
+		{"?format=svg&page=A3&orientation=landscape", "image/svg+xml", "<?xml"},
+		{"?format=png&dpi=30&background=transparent", "image/png", "\x89PNG"},
+	}
+	for _, tt := range tests {
+		rec := serve(h, httptest.NewRequest(http.MethodPost, "/convert"+tt.query, bytes.NewReader(fixture(t, "overall.dxf"))))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: status %d: %s", tt.query, rec.Code, rec.Body.String())
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%q: Content-Type = %q, want %q", tt.query, got, tt.contentType)
+		}
+		if !strings.HasPrefix(rec.Body.String(), tt.prefix) {
+			t.Errorf("%q: body starts with %.10q", tt.query, rec.Body.String())
+		}
+	}
+}
+
+func TestConvert_Multipart(t *testing.T) {
+	h := NewHandler(Config{})
+	// The query overrides the format of the JSON options
+	req := multipartRequest(t, "/convert?format=svg", fixture(t, "layers.dxf"), `{"format": "pdf", "layers": ["DIM"], "margin": 5}`)
+	rec := serve(h, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/svg+xml" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `inline; filename=plan.svg` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+}
+
+func TestConvert_PDFA(t *testing.T) {
+	h := NewHandler(Config{})
+	rec := serve(h, multipartRequest(t, "/convert?author=Jane+Doe", fixture(t, "layers.dxf"), `{"pdfa": true}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	// The title defaults to the name of the upload
+	for _, want := range []string{"/Title (plan) /Author (Jane Doe)", "/OutputIntents"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	p, err := parseJSONParams(`{"page": "letter", "layers": ["A"], "showHidden": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/convert?layers=B,C&layers=D&scale=2", nil)
+	if err := p.merge(req.URL.Query()); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := p.options(converter.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.PageSize.Width != 215.9 || !opts.ShowHiddenLayers || opts.Scale != 2 || opts.Margin != 10 {
+		t.Errorf("options = %+v", opts)
+	}
+	if strings.Join(opts.Layers, "|") != "B|C|D" {
+		t.Errorf("layers = %v", opts.Layers)
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	h := NewHandler(Config{MaxUploadSize: 64 << 10})
+	big := bytes.Repeat([]byte("999\ncomment\n"), 10000)
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"method", httptest.NewRequest(http.MethodGet, "/convert", nil), http.StatusMethodNotAllowed},
+		{"unknown format", httptest.NewRequest(http.MethodPost, "/convert?format=dwg", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"bad number", httptest.NewRequest(http.MethodPost, "/convert?margin=wide", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"huge DPI", httptest.NewRequest(http.MethodPost, "/convert?format=png&dpi=1e9", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"NaN DPI", httptest.NewRequest(http.MethodPost, "/convert?format=png&dpi=NaN", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"huge page", httptest.NewRequest(http.MethodPost, "/convert?page=1e6x1e6", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"infinite page", httptest.NewRequest(http.MethodPost, "/convert?page=infx10", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"negative scale", httptest.NewRequest(http.MethodPost, "/convert?scale=-1", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"NaN margin", httptest.NewRequest(http.MethodPost, "/convert?margin=NaN", strings.NewReader("0\nEOF\n")), http.StatusBadRequest},
+		{"too many pixels", httptest.NewRequest(http.MethodPost, "/convert?format=png&page=A0&dpi=2400", bytes.NewReader(fixture(t, "overall.dxf"))), http.StatusRequestEntityTooLarge},
+		{"option error", httptest.NewRequest(http.MethodPost, "/convert?plotScale=big", bytes.NewReader(fixture(t, "overall.dxf"))), http.StatusBadRequest},
+		{"bad JSON", multipartRequest(t, "/convert", fixture(t, "overall.dxf"), `{"colour": "red"}`), http.StatusBadRequest},
+		{"parse error", httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(fixture(t, "broken.dxf"))), http.StatusUnprocessableEntity},
+		{"PDF/A text", httptest.NewRequest(http.MethodPost, "/convert?pdfa=true", bytes.NewReader(fixture(t, "text.dxf"))), http.StatusUnprocessableEntity},
+		{"fit error", httptest.NewRequest(http.MethodPost, "/convert?plotScale=10:1&page=A5", bytes.NewReader(fixture(t, "overall.dxf"))), http.StatusUnprocessableEntity},
+		{"too large", httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(big)), http.StatusRequestEntityTooLarge},
+		{"too large multipart", multipartRequest(t, "/convert", big, ""), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		rec := serve(h, tt.req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body.String())
+			continue
+		}
+		var body struct{ Error string }
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+			t.Errorf("%s: body %q is not a JSON error", tt.name, rec.Body.String())
+		}
+	}
+}
+
+func TestConvert_LimitError(t *testing.T) {
+	defaults := converter.DefaultOptions()
+	defaults.MaxVertices = 1
+	h := NewHandler(Config{Defaults: defaults})
+	rec := serve(h, httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(fixture(t, "overall.dxf"))))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want %d: %s", rec.Code, http.StatusRequestEntityTooLarge, rec.Body.String())
+	}
+}
+
+// panicReader panics when the conversion reads the upload
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) { panic("boom") }
+
+func TestConvert_Panic(t *testing.T) {
+	rec := serve(NewHandler(Config{}), httptest.NewRequest(http.MethodPost, "/convert", panicReader{}))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want %d: %s", rec.Code, http.StatusInternalServerError, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "boom") {
+		t.Errorf("panic leaked to the client: %s", rec.Body.String())
+	}
+}
+
+func TestConvert_ParseErrorPosition(t *testing.T) {
+	rec := serve(NewHandler(Config{}), httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(fixture(t, "broken.dxf"))))
+	var body struct {
+		Line    int
+		Excerpt string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Line != 29 || !strings.Contains(body.Excerpt, "invalid_float_here") {
+		t.Errorf("body = %s", rec.Body.String())
+	}
+}
+
+func TestConvert_Lenient(t *testing.T) {
+	h := NewHandler(Config{})
+	rec := serve(h, httptest.NewRequest(http.MethodPost, "/convert?lenient=true", bytes.NewReader(fixture(t, "broken.dxf"))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Dxfconv-Warnings"); got != "1" {
+		t.Errorf("X-Dxfconv-Warnings = %q, want 1", got)
+	}
+}
+
+// slowReader delivers its data only after a delay
+type slowReader struct {
+	r     *bytes.Reader
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(p)
+}
+
+func TestConvert_Timeout(t *testing.T) {
+	h := NewHandler(Config{Timeout: 10 * time.Millisecond})
+	body := &slowReader{bytes.NewReader(fixture(t, "overall.dxf")), 50 * time.Millisecond}
+	rec := serve(h, httptest.NewRequest(http.MethodPost, "/convert", body))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body.String())
+	}
+}